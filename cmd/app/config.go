@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+const configUsage = `usage: main [-config file] [-profile name] config <command>
+
+commands:
+  print [-redact]      print the configuration in effect, with -redact without secrets`
+
+// runConfig выполняет подкоманду config.
+func runConfig(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	fs := flag.NewFlagSet("print", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	redact := fs.Bool("redact", false, "hide secrets")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w\n%s", err, configUsage)
+	}
+
+	return cfg.Print(out, *redact)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yervsil/toDo-microservice/pkg/health"
+)
+
+// migrationsCurrent возвращает ошибку, пока в базе нет миграций, известных
+// этой сборке, например когда migrations.auto выключен и `migrate up` не запускали.
+func migrationsCurrent(m migrator) health.Check {
+	return func(ctx context.Context) error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), first %d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+
+		return nil
+	}
+}
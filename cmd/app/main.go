@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
-	//"time"
+	"os"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/yervsil/toDo-microservice/config"
 	handler "github.com/yervsil/toDo-microservice/internal/delivery/http"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
 	"github.com/yervsil/toDo-microservice/internal/repository"
 	"github.com/yervsil/toDo-microservice/internal/server"
 	"github.com/yervsil/toDo-microservice/internal/service"
-	"github.com/yervsil/toDo-microservice/pkg/database/mongodb"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/lifecycle"
 	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/ratelimit"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
 )
 
 // @title Todo App API
@@ -21,27 +32,185 @@ import (
 // @BasePath /
 
 func main() {
-	cfg, err := config.InitConfig()
+	configPath := flag.String("config", config.DefaultPath, "config file")
+	profile := flag.String("profile", "", "config profile: local, dev or prod (default TODO_ENV or env in the config file)")
+	flag.Parse()
+	args := flag.Args()
+
+	store, err := config.NewStore(config.Options{Path: *configPath, Profile: *profile})
 	//time.Sleep(5 * time.Minute)
 	if err != nil {
 		log.Fatalf("Config error: %s", err)
 	}
+	cfg := store.Config()
 
-	l := logger.New(cfg.Env)
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(cfg, args[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	client, err := mongodb.NewClient(cfg.Mongo.URI, cfg.Mongo.User, cfg.Mongo.Password)
+	l, err := logger.NewFromConfig(cfg.Log)
+	if err != nil {
+		log.Fatalf("Logger error: %s", err)
+	}
+	logger.SetDefault(l)
+
+	if len(args) > 0 && args[0] == "migrate" {
+		exit(l, migrateCommand(cfg, l, args[1:]))
+		return
+	}
+
+	ids, err := entity.NewTaskIDGenerator(cfg.Mongo.TaskIDs)
+	if err != nil {
+		l.Fatal(fmt.Errorf("db.taskIds: %w", err))
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
 	if err != nil {
 		l.Fatal(err)
 	}
 
-	db := client.Database(cfg.Mongo.Name)
+	// Компоненты останавливаются в обратном порядке: сначала HTTP, затем воркеры,
+	// затем используемая ими база и последним трейсер, чтобы выгрузить их спаны.
+	app := lifecycle.New(l.Package("lifecycle"))
+	app.Add(lifecycle.Hook{Name: "tracing", OnStop: shutdownTracing})
+	checker := health.New(health.DefaultTimeout)
 
-	repository := repository.NewRepository(db)
-	service := service.NewService(repository)
-	handler := handler.NewHandler(service, l)
+	var (
+		repo       *repository.Repository
+		dispatcher service.Dispatcher
+	)
 
-	srv := server.NewServer(cfg, handler.InitRoutes())
-	if err = srv.Run(); err != nil {
+	switch cfg.Mongo.Driver {
+	case config.DriverMongo:
+		repo, dispatcher = startMongo(cfg, l, ids, app, checker)
+	case config.DriverPostgres:
+		l.Warn("db.driver is postgres: webhooks, reminders and digests are disabled")
+		repo = startPostgres(cfg, l, app, checker)
+	case config.DriverSQLite:
+		l.Warn("db.driver is sqlite: webhooks, reminders and digests are disabled")
+		repo = startSQLite(cfg, l, app, checker)
+	case config.DriverMemory:
+		l.Warn("db.driver is memory: tasks are not persisted; webhooks, reminders and digests are disabled")
+		repo = repository.NewMemoryRepository(ids, taskDecorators(cfg, l, nil)...)
+	default:
+		l.Fatal(fmt.Errorf("unknown db.driver %q", cfg.Mongo.Driver))
+	}
+
+	if (cfg.Mongo.Driver == config.DriverPostgres || cfg.Mongo.Driver == config.DriverSQLite) && cfg.Mongo.TaskIDs != entity.TaskIDObjectID {
+		l.Warn("db.taskIds is ignored by %s: task IDs are assigned by the database", cfg.Mongo.Driver)
+	}
+
+	gauges := metrics.NewTaskGauges(repo, cfg.Metrics, l.Package("metrics"))
+	app.Add(lifecycle.Worker("task gauges", gauges.Run))
+
+	service := service.NewService(repo, dispatcher)
+	// Ограничитель создается и выключенным, чтобы перезагрузка могла его включить.
+	limiter, err := newLimiter(cfg.RateLimit)
+	if err != nil {
 		l.Fatal(err)
 	}
-}
\ No newline at end of file
+
+	handler := handler.NewHandler(service, checker, l, cfg, limiter)
+
+	srv := server.NewServer(cfg, handler.InitRoutes(), l.Package("http"))
+	app.Add(lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(context.Context) error {
+			return srv.Start(app.Fail)
+		},
+		OnStop:  srv.Stop,
+		Timeout: cfg.HTTP.ShutdownTimeout,
+	})
+	// Добавляется последним, чтобы остановиться первым: /readyz отвечает
+	// ошибкой в течение http.shutdownDelay, прежде чем сервер перестанет
+	// принимать соединения.
+	app.Add(lifecycle.Hook{
+		Name: "readiness",
+		OnStop: func(context.Context) error {
+			checker.Shutdown()
+			time.Sleep(cfg.HTTP.ShutdownDelay)
+			return nil
+		},
+		Timeout: cfg.HTTP.ShutdownDelay + lifecycle.DefaultStopTimeout,
+	})
+
+	watchConfig(store, l, limiter)
+
+	exit(l, app.Run(context.Background()))
+}
+
+// watchConfig применяет изменения файлов конфигурации к настройкам, которые
+// могут меняться во время работы: уровням логов и ограничениям частоты
+// запросов. Остальные изменения вступают в силу при следующем запуске.
+func watchConfig(store *config.Store, l *logger.Logger, limiter *ratelimit.Limiter) {
+	config.Subscribe(store, func(c *config.Config) config.LogConfig { return c.Log }, func(logCfg config.LogConfig) {
+		if err := l.Levels().Replace(logCfg.Level, logCfg.Packages); err != nil {
+			l.Error(fmt.Errorf("config reload: log: %w", err))
+		}
+	})
+	config.Subscribe(store, func(c *config.Config) config.RateLimitConfig { return c.RateLimit }, func(rl config.RateLimitConfig) {
+		if err := limiter.Update(enabledGroups(rl)); err != nil {
+			l.Error(fmt.Errorf("config reload: %w", err))
+		}
+	})
+
+	store.Watch(func(err error) {
+		if err != nil {
+			l.Error(fmt.Errorf("config reload failed, keeping the previous config: %w", err))
+			return
+		}
+		l.Info("config reloaded")
+	})
+}
+
+// newLimiter возвращает ограничитель частоты с хранилищем rateLimit.store.
+func newLimiter(cfg config.RateLimitConfig) (*ratelimit.Limiter, error) {
+	var store ratelimit.Store
+	switch cfg.Store {
+	case ratelimit.StoreMemory:
+		store = ratelimit.NewMemoryStore(clock.New())
+	default:
+		return nil, fmt.Errorf("unknown rateLimit.store %q", cfg.Store)
+	}
+
+	return ratelimit.New(enabledGroups(cfg), store)
+}
+
+// taskDecorators возвращает декораторы репозитория задач, включенные в
+// cfg.Repository и cfg.TaskCache, и guard, если он не nil, сразу под кэшем.
+func taskDecorators(cfg *config.Config, l *logger.Logger, guard repository.TaskDecorator) []repository.TaskDecorator {
+	decorators, err := repository.TaskDecorators(cfg.Repository, cfg.TaskCache, guard)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	return decorators
+}
+
+// enabledGroups возвращает cfg без групп, когда ограничение частоты выключено.
+func enabledGroups(cfg config.RateLimitConfig) config.RateLimitConfig {
+	if !cfg.Enabled {
+		cfg.Groups = nil
+	}
+
+	return cfg
+}
+
+// exit пишет в лог err, если она есть, закрывает выводы лога и завершает
+// процесс с кодом, которого требует err.
+func exit(l *logger.Logger, err error) {
+	if err != nil {
+		l.Error(err)
+	}
+
+	if closeErr := l.Close(); closeErr != nil {
+		log.Printf("close logger: %s", closeErr)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
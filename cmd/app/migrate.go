@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up                   apply all pending migrations
+  down [-steps n]      roll back the last n applied migrations (default 1)
+  status               list migrations and whether they are applied
+  dry-run [up|down]    show what up (default) or down would do, without changing anything`
+
+// migrator реализуют и migrations.Migrator, и migrations.SQLMigrator.
+type migrator interface {
+	Up(ctx context.Context) ([]migrations.Migration, error)
+	Down(ctx context.Context, steps int) ([]migrations.Migration, error)
+	Status(ctx context.Context) ([]migrations.Status, error)
+	Pending(ctx context.Context) ([]migrations.Migration, error)
+	Rollback(ctx context.Context, steps int) ([]migrations.Migration, error)
+}
+
+// runMigrate выполняет подкоманду migrate.
+func runMigrate(ctx context.Context, m migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Fprintf(out, "applied %04d %s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "nothing to apply")
+		}
+		return err
+
+	case "down":
+		steps, err := parseSteps(args[1:])
+		if err != nil {
+			return err
+		}
+
+		rolledBack, err := m.Down(ctx, steps)
+		for _, mig := range rolledBack {
+			fmt.Fprintf(out, "rolled back %04d %s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(rolledBack) == 0 {
+			fmt.Fprintln(out, "nothing to roll back")
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			if s.Unknown {
+				applied += " (unknown to this binary)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	case "dry-run":
+		direction := "up"
+		if len(args) > 1 {
+			direction = args[1]
+		}
+
+		var (
+			plan []migrations.Migration
+			verb string
+			err  error
+		)
+		switch direction {
+		case "up":
+			plan, err = m.Pending(ctx)
+			verb = "would apply"
+		case "down":
+			var steps int
+			if steps, err = parseSteps(args[2:]); err == nil {
+				plan, err = m.Rollback(ctx, steps)
+			}
+			verb = "would roll back"
+		default:
+			return errors.New(migrateUsage)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range plan {
+			fmt.Fprintf(out, "%s %04d %s\n", verb, mig.Version, mig.Name)
+		}
+		if len(plan) == 0 {
+			fmt.Fprintln(out, "nothing to do")
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+func parseSteps(args []string) (int, error) {
+	fs := flag.NewFlagSet("down", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+
+	if err := fs.Parse(args); err != nil {
+		return 0, fmt.Errorf("%w\n%s", err, migrateUsage)
+	}
+	if *steps < 1 {
+		return 0, errors.New("steps must be at least 1")
+	}
+
+	return *steps, nil
+}
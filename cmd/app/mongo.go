@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/digest"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
+	"github.com/yervsil/toDo-microservice/internal/outbox"
+	"github.com/yervsil/toDo-microservice/internal/reminder"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+	"github.com/yervsil/toDo-microservice/internal/service"
+	"github.com/yervsil/toDo-microservice/internal/webhook"
+	"github.com/yervsil/toDo-microservice/pkg/breaker"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/database/mongodb"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/lifecycle"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// startMongo подключается к MongoDB, применяет миграции и регистрирует
+// воркеры, которым она нужна: доставку вебхуков, ретранслятор outbox,
+// напоминания и сводки. Они останавливаются до отключения клиента. Клиент,
+// миграции и воркеры проверяет /readyz.
+func startMongo(cfg *config.Config, l *logger.Logger, ids entity.TaskIDGenerator, app *lifecycle.Manager, checker *health.Checker) (*repository.Repository, service.Dispatcher) {
+	db, migrator, err := connectMongo(cfg, l)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	app.Add(lifecycle.Hook{
+		Name:   "mongo",
+		OnStop: db.Client().Disconnect,
+	})
+
+	checker.Add("mongo", func(ctx context.Context) error {
+		return db.Client().Ping(ctx, readpref.Primary())
+	})
+	checker.Add("migrations", migrationsCurrent(migrator))
+	checker.Add("workers", app.Check)
+
+	if cfg.Migrations.Auto {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			l.Fatal(err)
+		}
+	}
+
+	// Предохранитель видит вызов задач один раз, после его повторов; попадания в
+	// кэш до него не доходят.
+	b := newBreaker(cfg.Mongo.CircuitBreaker, l)
+	repo := repository.WithBreaker(repository.NewRepository(db, ids, taskDecorators(cfg, l, repository.Guarded(b))...), b)
+
+	dispatcher := webhook.NewDispatcher(repo, clock.New(), cfg.Webhook, l.Package("webhook"))
+	app.Add(lifecycle.Worker("webhook dispatcher", dispatcher.Run))
+
+	relay := outbox.NewRelay(repo, clock.New(), cfg.Outbox, l.Package("outbox"), dispatcher, reminder.NewSync(repo))
+	app.Add(lifecycle.Worker("outbox relay", relay.Run))
+
+	mailer := mail.NewSMTPClient(cfg.SMTP)
+
+	notifier, err := reminder.NewNotifier(cfg.Reminder, mailer, l.Package("reminder"))
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	scheduler := reminder.NewScheduler(repo, notifier, clock.New(), cfg.Reminder, l.Package("reminder"))
+	app.Add(lifecycle.Worker("reminder scheduler", scheduler.Run))
+
+	if cfg.Digest.Enabled {
+		digestJob := digest.NewJob(repo, mailer, clock.New(), cfg.Digest, l.Package("digest"))
+		app.Add(lifecycle.Worker("digest job", digestJob.Run))
+	}
+
+	return repo, dispatcher
+}
+
+// migrateCommand выполняет `main migrate ...` для настроенной базы.
+func migrateCommand(cfg *config.Config, l *logger.Logger, args []string) error {
+	var (
+		m   migrator
+		err error
+	)
+
+	ctx := context.Background()
+	switch cfg.Mongo.Driver {
+	case config.DriverMongo:
+		var db *mongo.Database
+		if db, m, err = connectMongo(cfg, l); err == nil {
+			defer db.Client().Disconnect(ctx)
+		}
+	case config.DriverPostgres:
+		var db *sql.DB
+		if db, m, err = connectPostgres(cfg, l); err == nil {
+			defer db.Close()
+		}
+	case config.DriverSQLite:
+		var db *sql.DB
+		if db, m, err = connectSQLite(cfg, l); err == nil {
+			defer db.Close()
+		}
+	default:
+		return fmt.Errorf("migrate: db.driver %s has no migrations", cfg.Mongo.Driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	return runMigrate(ctx, m, args, os.Stdout)
+}
+
+// newBreaker возвращает предохранитель репозитория MongoDB или nil, когда
+// db.circuitBreaker выключен. Его состояние пишется в лог и экспортируется
+// метрикой circuit_breaker_state.
+func newBreaker(cfg config.BreakerConfig, l *logger.Logger) *breaker.Breaker {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	state := metrics.CircuitBreakerState.WithLabelValues("mongo")
+	state.Set(float64(breaker.Closed))
+
+	return breaker.New(cfg.Failures, cfg.OpenTimeout, breaker.OnChange(func(from, to breaker.State) {
+		state.Set(float64(to))
+		if to == breaker.Open {
+			l.Warn("mongo circuit breaker is open: requests fail with 503 for %s", cfg.OpenTimeout)
+			return
+		}
+		l.Info("mongo circuit breaker is %s", to)
+	}))
+}
+
+// connectMongo ждет MongoDB, сколько позволяет db.connectRetry; SIGINT или
+// SIGTERM прекращают ожидание.
+func connectMongo(cfg *config.Config, l *logger.Logger) (*mongo.Database, *migrations.Migrator, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := mongodb.NewClient(ctx, cfg.Mongo, l.Package("mongo"), metrics.MongoMonitor(), tracing.MongoMonitor())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db := client.Database(cfg.Mongo.Name)
+
+	migrator, err := migrations.New(db, migrations.All(), cfg.Migrations, l.Package("migrations"))
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+	"github.com/yervsil/toDo-microservice/pkg/database/postgres"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/lifecycle"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// startPostgres подключается к PostgreSQL и применяет миграции. Там хранятся
+// только задачи, поэтому фоновые воркеры не запускаются.
+func startPostgres(cfg *config.Config, l *logger.Logger, app *lifecycle.Manager, checker *health.Checker) *repository.Repository {
+	db, migrator, err := connectPostgres(cfg, l)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	app.Add(lifecycle.Hook{
+		Name: "postgres",
+		OnStop: func(context.Context) error {
+			return db.Close()
+		},
+	})
+
+	checker.Add("postgres", db.PingContext)
+	checker.Add("migrations", migrationsCurrent(migrator))
+
+	if cfg.Migrations.Auto {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			l.Fatal(err)
+		}
+	}
+
+	return repository.NewPostgresRepository(db, taskDecorators(cfg, l, nil)...)
+}
+
+func connectPostgres(cfg *config.Config, l *logger.Logger) (*sql.DB, *migrations.SQLMigrator, error) {
+	db, err := postgres.NewDB(cfg.Postgres.DSN, cfg.Postgres.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrations.NewSQL(db, migrations.Postgres, migrations.PostgresMigrations(), cfg.Migrations, l.Package("migrations"))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+	"github.com/yervsil/toDo-microservice/pkg/database/sqlite"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/lifecycle"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// startSQLite открывает файл SQLite и приводит его схему к актуальной. Схема
+// мигрирует всегда, что бы ни говорил migrations.auto: файл принадлежит этому
+// процессу, и новая установка должна работать без настройки.
+func startSQLite(cfg *config.Config, l *logger.Logger, app *lifecycle.Manager, checker *health.Checker) *repository.Repository {
+	db, migrator, err := connectSQLite(cfg, l)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	app.Add(lifecycle.Hook{
+		Name: "sqlite",
+		OnStop: func(context.Context) error {
+			return db.Close()
+		},
+	})
+
+	checker.Add("sqlite", db.PingContext)
+	checker.Add("migrations", migrationsCurrent(migrator))
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		l.Fatal(err)
+	}
+
+	return repository.NewSQLiteRepository(db, taskDecorators(cfg, l, nil)...)
+}
+
+func connectSQLite(cfg *config.Config, l *logger.Logger) (*sql.DB, *migrations.SQLMigrator, error) {
+	db, err := sqlite.NewDB(cfg.SQLite.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrations.NewSQL(db, migrations.SQLite, migrations.SQLiteMigrations(), cfg.Migrations, l.Package("migrations"))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
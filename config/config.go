@@ -1,26 +1,192 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
+// DefaultPath — файл конфигурации, который читается, если другой не задан.
+const DefaultPath = "config/main.yaml"
+
+// EnvPrefix начинает имена переменных окружения, переопределяющих ключи
+// конфигурации, см. EnvName.
+const EnvPrefix = "TODO_"
+
+// Профили для env. Профиль накладывает на файл конфигурации соседний файл,
+// названный по профилю, см. OverlayPath.
+const (
+	ProfileLocal = "local"
+	ProfileDev   = "dev"
+	ProfileProd  = "prod"
+)
+
+var Profiles = []string{ProfileLocal, ProfileDev, ProfileProd}
+
+// Options сообщают Load, откуда берется конфигурация.
+type Options struct {
+	// Path — файл конфигурации; пустой значит DefaultPath.
+	Path string
+	// Profile выбирает накладываемый файл; пустой значит env из TODO_ENV или файла.
+	Profile string
+	// EnvFile загружается в окружение, если существует; пустой значит .env.
+	EnvFile string
+}
+
+// legacyEnv — переменные, которые читались до переменных TODO_. Они еще
+// принимаются, если соответствующая TODO_ не задана.
+var legacyEnv = map[string]string{
+	"db.uri":            "MONGO_URI",
+	"db.password":       "MONGO_PASS",
+	"postgres.password": "POSTGRES_PASS",
+	"smtp.password":     "SMTP_PASS",
+	"log.adminToken":    "LOG_ADMIN_TOKEN",
+}
+
+// Драйверы базы данных для db.driver.
+const (
+	DriverMongo    = "mongo"
+	DriverMemory   = "memory"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
 
 type (
+	// Config — конфигурация сервиса. Поля с тегом secret Print с redact скрывает;
+	// у полей с тегом secret:"url" скрывается только пароль в URL.
 	Config struct {
 		Env 		string 		`mapstructure:"env"`
-		HTTP        HTTPConfig
-		Mongo 		MongoConfig
+		Log         LogConfig        `mapstructure:"log"`
+		HTTP        HTTPConfig       `mapstructure:"http"`
+		RateLimit   RateLimitConfig  `mapstructure:"rateLimit"`
+		Mongo 		MongoConfig      `mapstructure:"db"`
+		Postgres    PostgresConfig   `mapstructure:"postgres"`
+		SQLite      SQLiteConfig     `mapstructure:"sqlite"`
+		Repository  RepositoryConfig `mapstructure:"repository"`
+		TaskCache   TaskCacheConfig  `mapstructure:"taskCache"`
+		Outbox      OutboxConfig     `mapstructure:"outbox"`
+		Webhook     WebhookConfig    `mapstructure:"webhook"`
+		SMTP        SMTPConfig       `mapstructure:"smtp"`
+		Reminder    ReminderConfig   `mapstructure:"reminder"`
+		Digest      DigestConfig     `mapstructure:"digest"`
+		Migrations  MigrationsConfig `mapstructure:"migrations"`
+		Metrics     MetricsConfig    `mapstructure:"metrics"`
+		Tracing     TracingConfig    `mapstructure:"tracing"`
 	}
 
 	MongoConfig struct {
-		URI      string `mapstructure:"MONGO_URI"`
-		User     string `mapstructure:"MONGO_USER"`
-		Password string
+		Driver   string `mapstructure:"driver"`
+		TaskIDs  string `mapstructure:"taskIds"`
+		URI      string `mapstructure:"uri" secret:"url"`
+		User     string `mapstructure:"user"`
+		Password string `mapstructure:"password" secret:"true"`
 		Name     string `mapstructure:"databaseName"`
+		// Размеры пула и таймауты драйвера; ноль оставляет значение драйвера по
+		// умолчанию.
+		MinPoolSize            uint64        `mapstructure:"minPoolSize"`
+		MaxPoolSize            uint64        `mapstructure:"maxPoolSize"`
+		MaxConnIdleTime        time.Duration `mapstructure:"maxConnIdleTime"`
+		ConnectTimeout         time.Duration `mapstructure:"connectTimeout"`
+		ServerSelectionTimeout time.Duration `mapstructure:"serverSelectionTimeout"`
+		SocketTimeout          time.Duration `mapstructure:"socketTimeout"`
+		// ReadConcern — уровень вроде local или majority; пустой оставляет значение
+		// сервера по умолчанию.
+		ReadConcern  string            `mapstructure:"readConcern"`
+		WriteConcern MongoWriteConcern `mapstructure:"writeConcern"`
+		// ReadPreference — primary, primaryPreferred, secondary, secondaryPreferred
+		// или nearest. Транзакции всегда читают с primary.
+		ReadPreference string           `mapstructure:"readPreference"`
+		ConnectRetry   MongoRetryConfig `mapstructure:"connectRetry"`
+		CircuitBreaker BreakerConfig    `mapstructure:"circuitBreaker"`
+	}
+
+	MongoWriteConcern struct {
+		// W — majority или число узлов; пустой оставляет значение сервера по
+		// умолчанию.
+		W        string        `mapstructure:"w"`
+		Journal  bool          `mapstructure:"journal"`
+		WTimeout time.Duration `mapstructure:"wtimeout"`
+	}
+
+	// MongoRetryConfig повторяет первое подключение с экспоненциальной паузой.
+	MongoRetryConfig struct {
+		// MaxAttempts, равный 0, повторяет, пока запуск не отменен.
+		MaxAttempts int           `mapstructure:"maxAttempts"`
+		BackoffBase time.Duration `mapstructure:"backoffBase"`
+		BackoffMax  time.Duration `mapstructure:"backoffMax"`
+	}
+
+	// BreakerConfig открывает предохранитель после Failures вызовов подряд,
+	// не удавшихся из-за недоступности базы. Пока он открыт, вызовы сразу
+	// завершаются ошибкой; через OpenTimeout один вызов проверяет базу.
+	BreakerConfig struct {
+		Enabled     bool          `mapstructure:"enabled"`
+		Failures    int           `mapstructure:"failures"`
+		OpenTimeout time.Duration `mapstructure:"openTimeout"`
+	}
+
+	PostgresConfig struct {
+		DSN      string `mapstructure:"dsn" secret:"url"`
+		Password string `mapstructure:"password" secret:"true"`
+	}
+
+	SQLiteConfig struct {
+		Path string `mapstructure:"path"`
+	}
+
+	// RepositoryConfig включает декораторы репозитория задач, начиная с
+	// внешнего: логирование, таймаут и повторы.
+	RepositoryConfig struct {
+		// Log пишет каждый вызов с его длительностью на уровне debug, а вызовы
+		// дольше SlowThreshold — на уровне warn.
+		Log           bool          `mapstructure:"log"`
+		SlowThreshold time.Duration `mapstructure:"slowThreshold"`
+		// Timeout ограничивает каждый вызов вместе с повторами; 0 оставляет это
+		// запросу. Timeouts переопределяет его для отдельных операций.
+		Timeout  time.Duration            `mapstructure:"timeout"`
+		Timeouts RepositoryTimeoutsConfig `mapstructure:"timeouts"`
+		Retry    RepositoryRetryConfig    `mapstructure:"retry"`
+	}
+
+	// RepositoryTimeoutsConfig хранит таймауты операций репозитория задач;
+	// ноль значит RepositoryConfig.Timeout.
+	RepositoryTimeoutsConfig struct {
+		CreateTask   time.Duration `mapstructure:"createTask"`
+		UpdateTask   time.Duration `mapstructure:"updateTask"`
+		DeleteTask   time.Duration `mapstructure:"deleteTask"`
+		StatusUpdate time.Duration `mapstructure:"statusUpdate"`
+		GetTasks     time.Duration `mapstructure:"getTasks"`
+		CountTasks   time.Duration `mapstructure:"countTasks"`
+		SearchTasks  time.Duration `mapstructure:"searchTasks"`
+	}
+
+	// RepositoryRetryConfig повторяет чтения, не удавшиеся из-за разорванного
+	// соединения. Записи здесь не повторяются: они пишут и события задач.
+	RepositoryRetryConfig struct {
+		// MaxAttempts учитывает первый вызов; меньше 2 — повторов нет.
+		MaxAttempts int `mapstructure:"maxAttempts"`
+		// Backoff — пауза перед первым повтором, перед каждым следующим она
+		// удваивается.
+		Backoff time.Duration `mapstructure:"backoff"`
+	}
+
+	// TaskCacheConfig кэширует списки задач. Записи этой реплики очищают кэш;
+	// TTL ограничивает, насколько список может устареть после записей других
+	// реплик и по мере того, как задачи становятся активными.
+	TaskCacheConfig struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Store   string `mapstructure:"store"`
+		// Size — число списков, которые хранит хранилище memory.
+		Size int           `mapstructure:"size"`
+		TTL  time.Duration `mapstructure:"ttl"`
 	}
 
 	HTTPConfig struct {
@@ -28,56 +194,316 @@ type (
 		Port               string        `mapstructure:"port"`
 		ReadTimeout        time.Duration `mapstructure:"readTimeout"`
 		WriteTimeout       time.Duration `mapstructure:"writeTimeout"`
+		// ReadHeaderTimeout ограничивает чтение строки запроса и заголовков;
+		// IdleTimeout ограничивает ожидание следующего запроса в keep-alive
+		// соединении.
+		ReadHeaderTimeout  time.Duration `mapstructure:"readHeaderTimeout"`
+		IdleTimeout        time.Duration `mapstructure:"idleTimeout"`
 		MaxHeaderMegabytes int           `mapstructure:"maxHeaderBytes"`
+		// MaxBodyBytes — наибольшее принимаемое тело запроса.
+		MaxBodyBytes       int64         `mapstructure:"maxBodyBytes"`
+		// HandlerTimeout — срок контекста каждого запроса; 0 отключает его.
+		HandlerTimeout     time.Duration `mapstructure:"handlerTimeout"`
+		ShutdownTimeout    time.Duration `mapstructure:"shutdownTimeout"`
+		ShutdownDelay      time.Duration `mapstructure:"shutdownDelay"`
+		// TrustedProxies — адреса, чьему X-Forwarded-For верят при определении IP
+		// клиента.
+		TrustedProxies     []string      `mapstructure:"trustedProxies"`
+		// HTTP2 включает HTTP/2: по TLS через ALPN, по обычному HTTP как h2c.
+		HTTP2              bool          `mapstructure:"http2"`
+		TLS                TLSConfig     `mapstructure:"tls"`
+		CORS               CORSConfig    `mapstructure:"cors"`
+		SecurityHeaders    SecurityHeadersConfig `mapstructure:"securityHeaders"`
 	}
 
-)
+	TLSConfig struct {
+		Enabled  bool   `mapstructure:"enabled"`
+		CertFile string `mapstructure:"certFile"`
+		KeyFile  string `mapstructure:"keyFile"`
+		// ClientCAFile включает взаимный TLS: сертификаты клиентов должны быть
+		// подписаны одним из его CA.
+		ClientCAFile string `mapstructure:"clientCAFile"`
+		// ClientAuth — require (по умолчанию) или optional, когда задан
+		// ClientCAFile.
+		ClientAuth string `mapstructure:"clientAuth"`
+		// MinVersion — 1.2 или 1.3.
+		MinVersion string `mapstructure:"minVersion"`
+	}
 
+	CORSConfig struct {
+		// AllowedOrigins — источники вида https://app.example.com; "*" на месте
+		// первой метки подходит к любому поддомену, "*" сам по себе — к любому
+		// источнику. Пустой список отключает CORS.
+		AllowedOrigins   []string      `mapstructure:"allowedOrigins"`
+		AllowedMethods   []string      `mapstructure:"allowedMethods"`
+		AllowedHeaders   []string      `mapstructure:"allowedHeaders"`
+		ExposedHeaders   []string      `mapstructure:"exposedHeaders"`
+		AllowCredentials bool          `mapstructure:"allowCredentials"`
+		MaxAge           time.Duration `mapstructure:"maxAge"`
+	}
 
+	SecurityHeadersConfig struct {
+		Enabled bool `mapstructure:"enabled"`
+		// HSTSMaxAge отправляется в Strict-Transport-Security на TLS-соединениях;
+		// ноль не отправляет заголовок.
+		HSTSMaxAge            time.Duration `mapstructure:"hstsMaxAge"`
+		ContentSecurityPolicy string        `mapstructure:"contentSecurityPolicy"`
+	}
 
-func InitConfig() (*Config, error) {
-	//viper.AddConfigPath("../../config")
-	// viper.SetConfigName("main")
-    viper.AddConfigPath("./config")
-	viper.SetConfigName("main")
+	RateLimitConfig struct {
+		Enabled bool                   `mapstructure:"enabled"`
+		Store   string                 `mapstructure:"store"`
+		// Groups проверяются по порядку; запрос ограничивает первая подходящая.
+		Groups  []RateLimitGroupConfig `mapstructure:"groups"`
+	}
+
+	RateLimitGroupConfig struct {
+		Name     string        `mapstructure:"name"`
+		// Methods и Paths (префиксы) выбирают запросы; пустые подходят ко всем.
+		Methods  []string      `mapstructure:"methods"`
+		Paths    []string      `mapstructure:"paths"`
+		Requests int           `mapstructure:"requests"`
+		Per      time.Duration `mapstructure:"per"`
+		Burst    int           `mapstructure:"burst"`
+	}
+
+	LogConfig struct {
+		// Level — нижний записываемый уровень: debug, info, warn или error.
+		Level    string            `mapstructure:"level"`
+		// Format — json или console.
+		Format   string            `mapstructure:"format"`
+		Outputs  []LogOutputConfig `mapstructure:"outputs"`
+		// Packages переопределяет Level для логгеров отдельных пакетов.
+		Packages map[string]string `mapstructure:"packages"`
+		Sampling LogSamplingConfig `mapstructure:"sampling"`
+		// AdminToken включает эндпоинт смены уровня во время работы.
+		AdminToken string `mapstructure:"adminToken" secret:"true"`
+	}
+
+	LogOutputConfig struct {
+		// Type — stdout, file или syslog.
+		Type       string `mapstructure:"type"`
+		Path       string `mapstructure:"path"`
+		MaxSizeMB  int    `mapstructure:"maxSizeMB"`
+		MaxAgeDays int    `mapstructure:"maxAgeDays"`
+		MaxBackups int    `mapstructure:"maxBackups"`
+		Compress   bool   `mapstructure:"compress"`
+		// Network и Address указывают демон syslog; пустые значат локальный сокет.
+		Network    string `mapstructure:"network"`
+		Address    string `mapstructure:"address"`
+		Tag        string `mapstructure:"tag"`
+	}
+
+	LogSamplingConfig struct {
+		// Пишутся Burst строк debug за Period, после этого только одна из
+		// Thereafter. Нулевой Burst отключает выборку.
+		Burst      uint32        `mapstructure:"burst"`
+		Period     time.Duration `mapstructure:"period"`
+		Thereafter uint32        `mapstructure:"thereafter"`
+	}
+
+	MetricsConfig struct {
+		RefreshInterval time.Duration `mapstructure:"refreshInterval"`
+	}
 
-	
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, err 
+	TracingConfig struct {
+		Exporter    string  `mapstructure:"exporter"`
+		Endpoint    string  `mapstructure:"endpoint"`
+		Insecure    bool    `mapstructure:"insecure"`
+		SampleRatio float64 `mapstructure:"sampleRatio"`
+		ServiceName string  `mapstructure:"serviceName"`
+	}
+
+	OutboxConfig struct {
+		Interval    time.Duration `mapstructure:"interval"`
+		BatchSize   int           `mapstructure:"batchSize"`
+		Lease       time.Duration `mapstructure:"lease"`
+		MaxAttempts int           `mapstructure:"maxAttempts"`
+		RetryDelay  time.Duration `mapstructure:"retryDelay"`
+	}
+
+	WebhookConfig struct {
+		Workers int `mapstructure:"workers"`
+		// Interval — как часто простаивающие воркеры ищут доставки, которым пора;
+		// новые доставки будят их сразу. Lease — как долго воркер держит доставку,
+		// пока отправляет ее, поэтому он должен быть больше Timeout.
+		Interval    time.Duration `mapstructure:"interval"`
+		Lease       time.Duration `mapstructure:"lease"`
+		MaxAttempts int           `mapstructure:"maxAttempts"`
+		Timeout     time.Duration `mapstructure:"timeout"`
+		BackoffBase time.Duration `mapstructure:"backoffBase"`
+		BackoffMax  time.Duration `mapstructure:"backoffMax"`
+	}
+
+	SMTPConfig struct {
+		Host     string        `mapstructure:"host"`
+		Port     string        `mapstructure:"port"`
+		Username string        `mapstructure:"username"`
+		Password string        `mapstructure:"password" secret:"true"`
+		From     string        `mapstructure:"from"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+	}
+
+	ReminderConfig struct {
+		Interval    time.Duration         `mapstructure:"interval"`
+		Lease       time.Duration         `mapstructure:"lease"`
+		MaxAttempts int                   `mapstructure:"maxAttempts"`
+		RetryDelay  time.Duration         `mapstructure:"retryDelay"`
+		Channels    []string              `mapstructure:"channels"`
+		Email       ReminderEmailConfig   `mapstructure:"email"`
+		Webhook     ReminderWebhookConfig `mapstructure:"webhook"`
+	}
+
+	ReminderEmailConfig struct {
+		To []string `mapstructure:"to"`
+	}
+
+	ReminderWebhookConfig struct {
+		URL    string `mapstructure:"url"`
+		Secret string `mapstructure:"secret" secret:"true"`
+	}
+
+	MigrationsConfig struct {
+		Auto        bool          `mapstructure:"auto"`
+		LockTimeout time.Duration `mapstructure:"lockTimeout"`
+		LockLease   time.Duration `mapstructure:"lockLease"`
+	}
+
+	DigestConfig struct {
+		Enabled  bool          `mapstructure:"enabled"`
+		Interval time.Duration `mapstructure:"interval"`
+		// Lease — как долго реплика держит сводку, пока отправляет ее.
+		Lease time.Duration `mapstructure:"lease"`
+	}
+
+)
+
+
+
+// Load читает файл конфигурации и накладываемый файл профиля, заполняет
+// пропущенное в них значениями по умолчанию, применяет поверх переменные
+// окружения TODO_ и проверяет результат.
+func Load(opts Options) (*Config, error) {
+	v, err := newViper(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	var cfg Config
-	if err := viper.UnmarshalKey("env", &cfg.Env); err != nil {
-		return nil, err 
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	if err := viper.UnmarshalKey("db", &cfg.Mongo); err != nil {
-		return nil, err 
+	return &cfg, nil
+}
+
+// newViper возвращает viper со слоями opts: значения по умолчанию, файл
+// конфигурации, накладываемый файл профиля и окружение.
+func newViper(opts Options) (*viper.Viper, error) {
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	// .env необязателен: в контейнерах переменные приходят из окружения.
+	if err := godotenv.Load(envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("load %s: %w", envFile, err)
 	}
 
-	if err := viper.UnmarshalKey("http", &cfg.HTTP); err != nil {
-		return nil, err 
+	path := opts.Path
+	if path == "" {
+		path = DefaultPath
 	}
 
-	if err := parseEnv(&cfg); err != nil {
-		return nil, err 
+	v := viper.New()
+	setDefaults(v)
+	if err := bindEnv(v, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = v.GetString("env")
+	}
+	if !validProfile(profile) {
+		return nil, fmt.Errorf("env: unknown profile %q, want one of %s", profile, strings.Join(Profiles, ", "))
 	}
+	v.Set("env", profile)
 
-	return &cfg, nil 
+	overlay := OverlayPath(path, profile)
+	if _, err := os.Stat(overlay); err == nil {
+		v.SetConfigFile(overlay)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", overlay, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// OverlayPath возвращает накладываемый файл для path и profile:
+// config/main.prod.yaml для config/main.yaml и prod.
+func OverlayPath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// EnvName возвращает переменную окружения, переопределяющую key:
+// TODO_HTTP_PORT для http.port.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
 }
 
-func parseEnv(cfg *Config) error {
-	//err := godotenv.Load("../../.env")
-	err := godotenv.Load("./.env")
-    if err != nil {
-        log.Fatal("Error loading .env file")
-    }
+// bindEnv привязывает каждый ключ t, структуры конфигурации, к его
+// переменной TODO_ и к устаревшей переменной, если она есть. Списки секций
+// и словари читаются только из файлов.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
 
-	if err := viper.BindEnv("MONGO_PASS"); err != nil {
-		return err 
+		switch kind := field.Type.Kind(); {
+		case kind == reflect.Struct:
+			if err := bindEnv(v, field.Type, key); err != nil {
+				return err
+			}
+		case kind == reflect.Map, kind == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+		default:
+			names := []string{key, EnvName(key)}
+			if legacy, ok := legacyEnv[key]; ok {
+				names = append(names, legacy)
+			}
+			if err := v.BindEnv(names...); err != nil {
+				return err
+			}
+		}
 	}
 
-	cfg.Mongo.Password = viper.GetString("MONGO_PASS")
+	return nil
+}
 
-	return nil 
-}
\ No newline at end of file
+func validProfile(profile string) bool {
+	for _, p := range Profiles {
+		if p == profile {
+			return true
+		}
+	}
+
+	return false
+}
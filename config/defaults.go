@@ -0,0 +1,45 @@
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// setDefaults задает значения ключей, которые файлы конфигурации могут
+// пропустить.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("env", ProfileLocal)
+
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "json")
+	v.SetDefault("log.outputs", []map[string]interface{}{{"type": "stdout"}})
+
+	v.SetDefault("http.port", "8000")
+	v.SetDefault("http.readHeaderTimeout", 5*time.Second)
+	v.SetDefault("http.idleTimeout", time.Minute)
+	v.SetDefault("http.maxHeaderBytes", 1)
+	v.SetDefault("http.maxBodyBytes", 1<<20)
+	v.SetDefault("http.shutdownTimeout", 15*time.Second)
+	v.SetDefault("http.tls.clientAuth", "require")
+	v.SetDefault("http.tls.minVersion", "1.2")
+
+	v.SetDefault("rateLimit.store", "memory")
+
+	v.SetDefault("repository.slowThreshold", 500*time.Millisecond)
+	v.SetDefault("repository.retry.backoff", 50*time.Millisecond)
+
+	v.SetDefault("taskCache.store", "memory")
+	v.SetDefault("taskCache.size", 1000)
+	v.SetDefault("taskCache.ttl", 10*time.Second)
+
+	v.SetDefault("db.driver", DriverMongo)
+	v.SetDefault("db.taskIds", entity.TaskIDObjectID)
+	v.SetDefault("db.serverSelectionTimeout", 5*time.Second)
+	v.SetDefault("db.connectRetry.maxAttempts", 10)
+	v.SetDefault("db.connectRetry.backoffBase", 500*time.Millisecond)
+	v.SetDefault("db.connectRetry.backoffMax", 10*time.Second)
+	v.SetDefault("db.circuitBreaker.failures", 5)
+	v.SetDefault("db.circuitBreaker.openTimeout", 10*time.Second)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted заменяет значения секретов, выведенных с redact.
+const Redacted = "REDACTED"
+
+// Print пишет c в YAML в разметке файла конфигурации. С redact заданные поля
+// с тегом secret заменяются на Redacted, как и пароли в URL полей с тегом
+// secret:"url".
+func (c *Config) Print(w io.Writer, redact bool) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(toNode(reflect.ValueOf(*c), redact)); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func toNode(v reflect.Value, redact bool) *yaml.Node {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: d.String()}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := field.Tag.Get("mapstructure")
+			if key == "" {
+				continue
+			}
+
+			value := toNode(v.Field(i), redact)
+			if redact && !v.Field(i).IsZero() {
+				switch field.Tag.Get("secret") {
+				case "true":
+					value = &yaml.Node{Kind: yaml.ScalarNode, Value: Redacted}
+				case "url":
+					value = toNode(reflect.ValueOf(redactURL(v.Field(i).String())), false)
+				}
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+		}
+		return n
+
+	case reflect.Slice:
+		n := &yaml.Node{Kind: yaml.SequenceNode}
+		if v.Len() == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		for i := 0; i < v.Len(); i++ {
+			n.Content = append(n.Content, toNode(v.Index(i), redact))
+		}
+		return n
+
+	case reflect.Map:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		if v.Len() == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k.String()}, toNode(v.MapIndex(k), redact))
+		}
+		return n
+
+	default:
+		n := &yaml.Node{}
+		// Кодирование простого значения не завершается ошибкой.
+		_ = n.Encode(v.Interface())
+		return n
+	}
+}
+
+// redactURL заменяет пароль в userinfo и в параметре запроса password
+// строки s. Значение, которое не является URL, например DSN вида key=value,
+// может хранить пароль где угодно и заменяется целиком.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		return Redacted
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", Redacted)
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
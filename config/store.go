@@ -0,0 +1,100 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Store хранит действующую конфигурацию. Reload и Watch заменяют ее новой,
+// только если новая корректна, и сообщают подписчикам, что изменилось.
+type Store struct {
+	opts    Options
+	current atomic.Pointer[Config]
+
+	// mu упорядочивает перезагрузки и защищает subs.
+	mu   sync.Mutex
+	subs []func(old, new *Config)
+}
+
+// NewStore загружает конфигурацию opts. Ее профиль сохраняется при перезагрузках.
+func NewStore(opts Options) (*Store, error) {
+	cfg, err := Load(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Profile = cfg.Env
+	s := &Store{opts: opts}
+	s.current.Store(cfg)
+
+	return s, nil
+}
+
+// Config возвращает действующую конфигурацию. Изменять ее нельзя.
+func (s *Store) Config() *Config {
+	return s.current.Load()
+}
+
+// Subscribe вызывает fn с секцией каждой новой конфигурации s, в которой
+// секция отличается от предыдущей конфигурации. fn выполняется в горутине
+// перезагрузки, которая ее ждет.
+func Subscribe[T any](s *Store, section func(*Config) T, fn func(T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subs = append(s.subs, func(old, new *Config) {
+		if value := section(new); !reflect.DeepEqual(section(old), value) {
+			fn(value)
+		}
+	})
+}
+
+// Reload загружает конфигурацию заново и уведомляет подписчиков. Если новую
+// конфигурацию не удалось загрузить или она некорректна, остается текущая, а
+// ошибка возвращается.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := Load(s.opts)
+	if err != nil {
+		return err
+	}
+
+	old := s.current.Swap(cfg)
+	for _, fn := range s.subs {
+		fn(old, cfg)
+	}
+
+	return nil
+}
+
+// Watch перезагружает конфигурацию при каждой записи файла конфигурации или
+// накладываемого файла профиля и вызывает done с результатом каждой перезагрузки.
+func (s *Store) Watch(done func(error)) {
+	path := s.opts.Path
+	if path == "" {
+		path = DefaultPath
+	}
+
+	// У относительного пути в рабочем каталоге не было бы каталога для наблюдения.
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	for _, file := range []string{path, OverlayPath(path, s.opts.Profile)} {
+		// Наблюдающий viper только замечает изменения; Reload заново читает все
+		// слои.
+		w := viper.New()
+		w.SetConfigFile(file)
+		w.OnConfigChange(func(fsnotify.Event) {
+			done(s.Reload())
+		})
+		w.WatchConfig()
+	}
+}
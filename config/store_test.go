@@ -0,0 +1,118 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withLevel возвращает testConfig с log.level, равным level.
+func withLevel(level string) string {
+	return strings.Replace(testConfig, "level: info", "level: "+level, 1)
+}
+
+func newStore(t *testing.T, path string) *Store {
+	t.Helper()
+
+	store, err := NewStore(Options{Path: path, EnvFile: filepath.Join(t.TempDir(), ".env")})
+	require.NoError(t, err)
+	return store
+}
+
+func TestStore_Reload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "main.yaml", testConfig)
+	store := newStore(t, path)
+
+	var levels, ports []string
+	Subscribe(store, func(c *Config) string { return c.Log.Level }, func(level string) {
+		levels = append(levels, level)
+	})
+	Subscribe(store, func(c *Config) HTTPConfig { return c.HTTP }, func(http HTTPConfig) {
+		ports = append(ports, http.Port)
+	})
+
+	writeConfig(t, dir, "main.yaml", testConfig+"rateLimit:\n  enabled: true\n")
+	require.NoError(t, store.Reload())
+	assert.True(t, store.Config().RateLimit.Enabled)
+	assert.Empty(t, levels, "unchanged sections are not published")
+	assert.Empty(t, ports)
+
+	writeConfig(t, dir, "main.yaml", withLevel("debug"))
+	require.NoError(t, store.Reload())
+	assert.Equal(t, []string{"debug"}, levels)
+
+	// Некорректная конфигурация не публикуется, и предыдущая остается.
+	previous := store.Config()
+	writeConfig(t, dir, "main.yaml", strings.Replace(withLevel("loud"), "port: 8000", "port: 0", 1))
+	err := store.Reload()
+	assert.ErrorContains(t, err, `log.level: "loud" is not one of`)
+	assert.ErrorContains(t, err, `http.port: "0" is not a port number`)
+	assert.Same(t, previous, store.Config())
+	assert.Equal(t, []string{"debug"}, levels)
+	assert.Empty(t, ports)
+}
+
+func TestStore_keepsProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "main.yaml", testConfig)
+	writeConfig(t, dir, "main.dev.yaml", "log:\n  level: warn\n")
+	store := newStore(t, path)
+
+	writeConfig(t, dir, "main.yaml", strings.Replace(testConfig, "env: local", "env: dev", 1))
+	require.NoError(t, store.Reload())
+	assert.Equal(t, "local", store.Config().Env)
+	assert.Equal(t, "info", store.Config().Log.Level)
+}
+
+func TestStore_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "main.yaml", testConfig)
+	store := newStore(t, path)
+
+	var (
+		mu     sync.Mutex
+		levels []string
+		errs   []error
+	)
+	Subscribe(store, func(c *Config) string { return c.Log.Level }, func(level string) {
+		mu.Lock()
+		defer mu.Unlock()
+		levels = append(levels, level)
+	})
+	store.Watch(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	})
+
+	writeConfig(t, dir, "main.yaml", withLevel("debug"))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(levels) == 1 && levels[0] == "debug"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Накладываемый файл профиля тоже отслеживается, даже созданный позже.
+	writeConfig(t, dir, "main.local.yaml", "log:\n  level: error\n")
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(levels) == 2 && levels[1] == "error"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeConfig(t, dir, "main.local.yaml", "log:\n  level: loud\n")
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "error", store.Config().Log.Level)
+}
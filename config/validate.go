@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// Validate проверяет всю конфигурацию и возвращает все найденные проблемы
+// вместе, каждую с префиксом ее ключа.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		fail(key, "%q is not one of %q", value, allowed)
+	}
+	nonNegativeDuration := func(key string, d time.Duration) {
+		if d < 0 {
+			fail(key, "must not be negative")
+		}
+	}
+	positiveDuration := func(key string, d time.Duration) {
+		if d <= 0 {
+			fail(key, "must be positive")
+		}
+	}
+	nonNegativeInt := func(key string, n int) {
+		if n < 0 {
+			fail(key, "must not be negative")
+		}
+	}
+	required := func(key, value string) {
+		if value == "" {
+			fail(key, "is required")
+		}
+	}
+
+	oneOf("env", c.Env, Profiles...)
+
+	oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	oneOf("log.format", c.Log.Format, "json", "console")
+	for i, out := range c.Log.Outputs {
+		key := fmt.Sprintf("log.outputs[%d]", i)
+		oneOf(key+".type", out.Type, "stdout", "file", "syslog")
+		if out.Type == "file" {
+			required(key+".path", out.Path)
+		}
+	}
+	for pkg, level := range c.Log.Packages {
+		oneOf("log.packages."+pkg, level, "debug", "info", "warn", "error")
+	}
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		fail("http.port", "%q is not a port number", c.HTTP.Port)
+	}
+	nonNegativeDuration("http.readTimeout", c.HTTP.ReadTimeout)
+	nonNegativeDuration("http.readHeaderTimeout", c.HTTP.ReadHeaderTimeout)
+	nonNegativeDuration("http.writeTimeout", c.HTTP.WriteTimeout)
+	nonNegativeDuration("http.idleTimeout", c.HTTP.IdleTimeout)
+	nonNegativeDuration("http.handlerTimeout", c.HTTP.HandlerTimeout)
+	nonNegativeDuration("http.shutdownDelay", c.HTTP.ShutdownDelay)
+	positiveDuration("http.shutdownTimeout", c.HTTP.ShutdownTimeout)
+	nonNegativeInt("http.maxHeaderBytes", c.HTTP.MaxHeaderMegabytes)
+	if c.HTTP.MaxBodyBytes < 0 {
+		fail("http.maxBodyBytes", "must not be negative")
+	}
+	if c.HTTP.HandlerTimeout > 0 && c.HTTP.WriteTimeout > 0 && c.HTTP.HandlerTimeout >= c.HTTP.WriteTimeout {
+		fail("http.handlerTimeout", "must be shorter than http.writeTimeout")
+	}
+	if tls := c.HTTP.TLS; tls.Enabled {
+		required("http.tls.certFile", tls.CertFile)
+		required("http.tls.keyFile", tls.KeyFile)
+		oneOf("http.tls.clientAuth", tls.ClientAuth, "require", "optional")
+		oneOf("http.tls.minVersion", tls.MinVersion, "1.2", "1.3")
+	}
+	if c.HTTP.CORS.AllowCredentials {
+		for _, origin := range c.HTTP.CORS.AllowedOrigins {
+			if origin == "*" {
+				fail("http.cors.allowCredentials", "cannot be used with the origin *")
+			}
+		}
+	}
+
+	oneOf("rateLimit.store", c.RateLimit.Store, "memory")
+	if c.RateLimit.Enabled {
+		for i, g := range c.RateLimit.Groups {
+			key := fmt.Sprintf("rateLimit.groups[%d]", i)
+			required(key+".name", g.Name)
+			if g.Requests <= 0 {
+				fail(key+".requests", "must be positive")
+			}
+			positiveDuration(key+".per", g.Per)
+			nonNegativeInt(key+".burst", g.Burst)
+		}
+	}
+
+	nonNegativeDuration("repository.slowThreshold", c.Repository.SlowThreshold)
+	nonNegativeDuration("repository.timeout", c.Repository.Timeout)
+	timeouts := c.Repository.Timeouts
+	nonNegativeDuration("repository.timeouts.createTask", timeouts.CreateTask)
+	nonNegativeDuration("repository.timeouts.updateTask", timeouts.UpdateTask)
+	nonNegativeDuration("repository.timeouts.deleteTask", timeouts.DeleteTask)
+	nonNegativeDuration("repository.timeouts.statusUpdate", timeouts.StatusUpdate)
+	nonNegativeDuration("repository.timeouts.getTasks", timeouts.GetTasks)
+	nonNegativeDuration("repository.timeouts.countTasks", timeouts.CountTasks)
+	nonNegativeDuration("repository.timeouts.searchTasks", timeouts.SearchTasks)
+	nonNegativeInt("repository.retry.maxAttempts", c.Repository.Retry.MaxAttempts)
+	nonNegativeDuration("repository.retry.backoff", c.Repository.Retry.Backoff)
+
+	oneOf("taskCache.store", c.TaskCache.Store, "memory")
+	if c.TaskCache.Enabled {
+		if c.TaskCache.Size <= 0 {
+			fail("taskCache.size", "must be positive")
+		}
+		positiveDuration("taskCache.ttl", c.TaskCache.TTL)
+	}
+
+	oneOf("db.driver", c.Mongo.Driver, DriverMongo, DriverPostgres, DriverSQLite, DriverMemory)
+	oneOf("db.taskIds", c.Mongo.TaskIDs, entity.TaskIDObjectID, entity.TaskIDUUIDv7, entity.TaskIDULID)
+	switch c.Mongo.Driver {
+	case DriverMongo:
+		m := c.Mongo
+		required("db.uri", m.URI)
+		required("db.databaseName", m.Name)
+		if m.MaxPoolSize > 0 && m.MinPoolSize > m.MaxPoolSize {
+			fail("db.minPoolSize", "must not be above db.maxPoolSize")
+		}
+		nonNegativeDuration("db.maxConnIdleTime", m.MaxConnIdleTime)
+		nonNegativeDuration("db.connectTimeout", m.ConnectTimeout)
+		nonNegativeDuration("db.serverSelectionTimeout", m.ServerSelectionTimeout)
+		nonNegativeDuration("db.socketTimeout", m.SocketTimeout)
+		oneOf("db.readConcern", m.ReadConcern, "", "local", "available", "majority", "linearizable", "snapshot")
+		if w := m.WriteConcern.W; w != "" && w != "majority" {
+			if n, err := strconv.Atoi(w); err != nil || n < 0 {
+				fail("db.writeConcern.w", "%q is neither majority nor a number", w)
+			}
+		}
+		nonNegativeDuration("db.writeConcern.wtimeout", m.WriteConcern.WTimeout)
+		oneOf("db.readPreference", m.ReadPreference, "", "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+		nonNegativeInt("db.connectRetry.maxAttempts", m.ConnectRetry.MaxAttempts)
+		positiveDuration("db.connectRetry.backoffBase", m.ConnectRetry.BackoffBase)
+		if m.ConnectRetry.BackoffMax < m.ConnectRetry.BackoffBase {
+			fail("db.connectRetry.backoffMax", "must not be below db.connectRetry.backoffBase")
+		}
+		if m.CircuitBreaker.Enabled {
+			if m.CircuitBreaker.Failures <= 0 {
+				fail("db.circuitBreaker.failures", "must be positive")
+			}
+			positiveDuration("db.circuitBreaker.openTimeout", m.CircuitBreaker.OpenTimeout)
+		}
+	case DriverPostgres:
+		required("postgres.dsn", c.Postgres.DSN)
+	case DriverSQLite:
+		required("sqlite.path", c.SQLite.Path)
+	}
+
+	// Нулевые интервалы и размеры воркеров значат их значения по умолчанию.
+	if c.Mongo.Driver == DriverMongo {
+		nonNegativeDuration("outbox.interval", c.Outbox.Interval)
+		nonNegativeInt("outbox.batchSize", c.Outbox.BatchSize)
+		nonNegativeDuration("outbox.lease", c.Outbox.Lease)
+		nonNegativeInt("outbox.maxAttempts", c.Outbox.MaxAttempts)
+		nonNegativeDuration("outbox.retryDelay", c.Outbox.RetryDelay)
+		nonNegativeInt("webhook.workers", c.Webhook.Workers)
+		nonNegativeDuration("webhook.interval", c.Webhook.Interval)
+		nonNegativeDuration("webhook.lease", c.Webhook.Lease)
+		if c.Webhook.Lease > 0 && c.Webhook.Timeout >= c.Webhook.Lease {
+			fail("webhook.lease", "must be longer than webhook.timeout")
+		}
+		nonNegativeInt("webhook.maxAttempts", c.Webhook.MaxAttempts)
+		nonNegativeDuration("webhook.timeout", c.Webhook.Timeout)
+		nonNegativeDuration("reminder.interval", c.Reminder.Interval)
+		for i, channel := range c.Reminder.Channels {
+			oneOf(fmt.Sprintf("reminder.channels[%d]", i), channel, "log", "email", "webhook")
+		}
+		nonNegativeDuration("digest.interval", c.Digest.Interval)
+		nonNegativeDuration("digest.lease", c.Digest.Lease)
+		if c.Digest.Lease > 0 && c.SMTP.Timeout >= c.Digest.Lease {
+			fail("digest.lease", "must be longer than smtp.timeout")
+		}
+	}
+
+	nonNegativeDuration("metrics.refreshInterval", c.Metrics.RefreshInterval)
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "", "none", "stdout", "otlp")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sampleRatio", "%v is not between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+// cors отвечает на preflight-запросы браузера и разрешает ответы разрешенным
+// источникам. Источник "*" разрешает любой, но без cookies и авторизации
+// браузера (Access-Control-Allow-Credentials), как требует спецификация.
+func cors(cfg config.CORSConfig) gin.HandlerFunc {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		anyOrigin, ok := allowedOrigin(cfg.AllowedOrigins, origin)
+		if !ok {
+			if preflight {
+				errorResponse(c, http.StatusForbidden, "origin not allowed")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			if exposed != "" {
+				h.Set("Access-Control-Expose-Headers", exposed)
+			}
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", methods)
+		if headers != "" {
+			h.Set("Access-Control-Allow-Headers", headers)
+		}
+		if cfg.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// allowedOrigin сообщает, разрешен ли источник, и разрешен ли он правилом "*".
+func allowedOrigin(allowed []string, origin string) (anyOrigin bool, ok bool) {
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			return true, true
+		}
+
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if strings.EqualFold(pattern, origin) {
+				return false, true
+			}
+			continue
+		}
+
+		// https://*.example.com: поддомен любой глубины, но не другой хост.
+		if len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			sub := origin[len(prefix) : len(origin)-len(suffix)]
+			if !strings.ContainsAny(sub, "/:@") {
+				return false, true
+			}
+		}
+	}
+
+	return false, false
+}
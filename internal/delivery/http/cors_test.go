@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+func newCORSRouter(cfg config.CORSConfig) *gin.Engine {
+	r := gin.New()
+	r.Use(cors(cfg))
+	r.GET("/tasks", func(c *gin.Context) { c.Status(200) })
+	return r
+}
+
+func TestCORS(t *testing.T) {
+	r := newCORSRouter(config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "https://app.example.com")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	w = do("GET", "https://api.eu.example.org")
+	assert.Equal(t, "https://api.eu.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+
+	for _, origin := range []string{"https://evil.com", "https://example.org", "https://evil.com/.example.org", "http://app.example.com"} {
+		w = do("GET", origin)
+		assert.Equal(t, 200, w.Code, origin)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+
+	w = do("GET", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = do("OPTIONS", "https://app.example.com")
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = do("OPTIONS", "https://evil.com")
+	assert.Equal(t, 403, w.Code)
+	assert.JSONEq(t, `{"error":"origin not allowed"}`, w.Body.String())
+}
+
+func TestCORS_anyOrigin(t *testing.T) {
+	r := newCORSRouter(config.CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET"},
+		AllowCredentials: true,
+	})
+
+	req := httptest.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), "credentials are never allowed for any origin")
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// @Summary Subscribe to daily digest
+// @Tags digests
+// @Description Opt an email address in to the daily digest of today's, overdue and yesterday's completed tasks. The digest is sent at hour in the IANA timezone
+// @Accept json
+// @Produce json
+// @Param input body entity.DigestSubscription true "Digest subscription"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} response
+// @Failure 413 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/digests [post]
+// Подписаться на ежедневную сводку
+func (h *Handler) createDigestSubscription(c *gin.Context) {
+	var input entity.DigestSubscription
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Error(err)
+		bindError(c, err)
+
+		return
+	}
+
+	id, err := h.service.CreateSubscription(c.Request.Context(), input)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// @Summary Get digest subscriptions
+// @Tags digests
+// @Description Get all daily digest subscriptions
+// @Produce json
+// @Success 200 {array} entity.DigestSubscription "List of subscriptions"
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/digests [get]
+// Получить все подписки на сводку
+func (h *Handler) getDigestSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.GetSubscriptions(c.Request.Context())
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		subscriptions = []entity.DigestSubscription{}
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// @Summary Update digest subscription
+// @Tags digests
+// @Description Change the timezone, send hour or opt-in of a digest subscription
+// @Accept json
+// @Param id path string true "Subscription ID"
+// @Param input body entity.DigestSubscription true "Digest subscription"
+// @Success 201 {string} string "Successfully updated"
+// @Failure 400 {object} response
+// @Failure 413 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/digests/{id} [put]
+// Изменить подписку на сводку по id
+func (h *Handler) updateDigestSubscription(c *gin.Context) {
+	subscriptionId, err := parseIdFromPath(c, "id")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	var input entity.DigestSubscription
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Error(err)
+		bindError(c, err)
+
+		return
+	}
+
+	err = h.service.UpdateSubscription(c.Request.Context(), input, subscriptionId)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, "successfully updated")
+}
+
+// @Summary Delete digest subscription
+// @Tags digests
+// @Description Unsubscribe from the daily digest
+// @Param id path string true "Subscription ID"
+// @Success 201 {string} string "Successfully deleted"
+// @Failure 400 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/digests/{id} [delete]
+// Удалить подписку на сводку по id
+func (h *Handler) deleteDigestSubscription(c *gin.Context) {
+	subscriptionId, err := parseIdFromPath(c, "id")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	err = h.service.DeleteSubscription(c.Request.Context(), subscriptionId)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, "successfully deleted")
+}
@@ -1,12 +1,58 @@
 package handler
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+)
 
 type response struct {
 	Error string `json:"error" example:"message"`
 }
 
+// errorResponse прерывает запрос ошибкой. Если истек срок обработки запроса
+// (http.handlerTimeout), ошибка вызвана им, и клиент получает 503 вместо
+// кода обработчика.
 func errorResponse(c *gin.Context, code int, msg string) {
-	
+	if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		code, msg = http.StatusServiceUnavailable, "request timed out"
+	}
+
 	c.AbortWithStatusJSON(code, response{msg})
+}
+
+// statusClientClosedRequest — нестандартный код nginx: клиент закрыл
+// соединение раньше, чем получил ответ.
+const statusClientClosedRequest = 499
+
+// serviceError прерывает запрос ошибкой сервиса. Если драйвер базы не
+// поддерживает операцию (repository.ErrUnsupported), клиент получает 501;
+// если база недоступна (repository.ErrUnavailable) или истек срок операции,
+// 503; если запрос отменен, 499; иначе code.
+func serviceError(c *gin.Context, code int, err error) {
+	var canceled *repository.CanceledError
+
+	switch {
+	case errors.Is(err, repository.ErrUnsupported):
+		errorResponse(c, http.StatusNotImplemented, err.Error())
+
+		return
+	case errors.Is(err, repository.ErrUnavailable):
+		errorResponse(c, http.StatusServiceUnavailable, repository.ErrUnavailable.Error())
+
+		return
+	case errors.As(err, &canceled) && errors.Is(canceled.Err, context.DeadlineExceeded):
+		errorResponse(c, http.StatusServiceUnavailable, "request timed out")
+
+		return
+	case errors.As(err, &canceled):
+		errorResponse(c, statusClientClosedRequest, "request canceled")
+
+		return
+	}
+
+	errorResponse(c, code, err.Error())
 }
\ No newline at end of file
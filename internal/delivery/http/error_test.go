@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+)
+
+func TestServiceError(t *testing.T) {
+	testTable := []struct {
+		name                 string
+		err                  error
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Unsupported",
+			err:                  fmt.Errorf("create webhook: %w", repository.ErrUnsupported),
+			expectedStatusCode:   501,
+			expectedResponseBody: `{"error":"create webhook: not supported by this database driver"}`,
+		},
+		{
+			name:                 "Unavailable",
+			err:                  fmt.Errorf("%w: connection refused", repository.ErrUnavailable),
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"error":"database unavailable"}`,
+		},
+		{
+			name:                 "TimedOut",
+			err:                  &repository.CanceledError{Op: "GetWebhooks", Err: context.DeadlineExceeded},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"error":"request timed out"}`,
+		},
+		{
+			name:                 "Canceled",
+			err:                  &repository.CanceledError{Op: "GetWebhooks", Err: context.Canceled},
+			expectedStatusCode:   499,
+			expectedResponseBody: `{"error":"request canceled"}`,
+		},
+		{
+			name:                 "Other",
+			err:                  errors.New("webhook not found"),
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"error":"webhook not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/todo-list/webhooks", nil)
+
+			serviceError(c, http.StatusNotFound, testCase.err)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -2,11 +2,18 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
 	"github.com/yervsil/toDo-microservice/internal/service"
+	"github.com/yervsil/toDo-microservice/pkg/health"
 	"github.com/yervsil/toDo-microservice/pkg/logger"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/yervsil/toDo-microservice/pkg/ratelimit"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
 	"github.com/swaggo/gin-swagger" // gin-swagger middleware
     "github.com/swaggo/files" // swagger embed files
     _ "github.com/yervsil/toDo-microservice/docs"
@@ -14,25 +21,73 @@ import (
 
 type Handler struct {
 	service *service.Service
+	health *health.Checker
 	logger *logger.Logger
+	// limiter ограничивает частоту запросов; nil отключает ограничение.
+	limiter *ratelimit.Limiter
+	// logAdminToken защищает /admin/log/level; пустой отключает маршрут.
+	logAdminToken string
+	trustedProxies []string
+	cors config.CORSConfig
+	securityHeaders config.SecurityHeadersConfig
+	maxBodyBytes int64
+	// handlerTimeout - срок обработки запроса; 0 без срока.
+	handlerTimeout time.Duration
 }
 
-func NewHandler(services *service.Service, logger *logger.Logger) *Handler{
+func NewHandler(services *service.Service, health *health.Checker, logger *logger.Logger, cfg *config.Config, limiter *ratelimit.Limiter) *Handler{
 	return &Handler{
 		service: services,
+		health: health,
 		logger: logger,
+		limiter: limiter,
+		logAdminToken: cfg.Log.AdminToken,
+		trustedProxies: cfg.HTTP.TrustedProxies,
+		cors: cfg.HTTP.CORS,
+		securityHeaders: cfg.HTTP.SecurityHeaders,
+		maxBodyBytes: cfg.HTTP.MaxBodyBytes,
+		handlerTimeout: cfg.HTTP.HandlerTimeout,
 	}
 }
 
 
 func (h *Handler) InitRoutes() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	if err := router.SetTrustedProxies(h.trustedProxies); err != nil {
+		h.logger.Error(fmt.Errorf("http.trustedProxies: %w; X-Forwarded-For is ignored", err))
+		_ = router.SetTrustedProxies(nil)
+	}
+
+	router.Use(gin.Recovery())
+	router.Use(tracing.GinMiddleware())
+	router.Use(requestLogger(h.logger.Package("http")))
+	router.Use(metrics.GinMiddleware())
+	if h.securityHeaders.Enabled {
+		router.Use(securityHeaders(h.securityHeaders))
+	}
+	if len(h.cors.AllowedOrigins) > 0 {
+		router.Use(cors(h.cors))
+	}
+	if h.limiter != nil {
+		router.Use(h.rateLimit(h.limiter))
+	}
+	if h.maxBodyBytes > 0 {
+		router.Use(bodyLimit(h.maxBodyBytes))
+	}
+	if h.handlerTimeout > 0 {
+		router.Use(deadline(h.handlerTimeout))
+	}
 
 	h.initAPI(router)
 
 	return router
 }
 
+// log возвращает логгер запроса: все его строки несут один request_id.
+func (h *Handler) log(c *gin.Context) *logger.Logger {
+	return logger.FromContext(c.Request.Context())
+}
+
 func (h *Handler) initAPI(router *gin.Engine) {
 	api := router.Group("/api")
 	{
@@ -43,22 +98,47 @@ func (h *Handler) initAPI(router *gin.Engine) {
 		v1.DELETE("/tasks/:id", h.deleteTask)
 		v1.PATCH("/tasks/:id/done", h.statusUpdate)
 		v1.GET("/tasks", h.getTasks)
+		v1.GET("/tasks/search", h.searchTasks)
+
+		v1.POST("/webhooks", h.createWebhook)
+		v1.GET("/webhooks", h.getWebhooks)
+		v1.DELETE("/webhooks/:id", h.deleteWebhook)
+		v1.GET("/webhooks/:id/deliveries", h.getDeliveries)
+		v1.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", h.redeliver)
+
+		v1.POST("/digests", h.createDigestSubscription)
+		v1.GET("/digests", h.getDigestSubscriptions)
+		v1.PUT("/digests/:id", h.updateDigestSubscription)
+		v1.DELETE("/digests/:id", h.deleteDigestSubscription)
+	}
 	}
+
+	router.GET("/healthz", h.healthz)
+	router.GET("/readyz", h.readyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	if h.logAdminToken != "" {
+		levels := gin.WrapH(logger.LevelHandler(h.logger.Levels(), h.logAdminToken))
+		router.GET("/admin/log/level", levels)
+		router.PUT("/admin/log/level", levels)
 	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
-func parseIdFromPath(c *gin.Context, param string) (primitive.ObjectID, error) {
+// parseIdFromPath возвращает идентификатор вебхука, доставки или подписки из
+// пути. Как и у задач, его формат проверяет репозиторий.
+func parseIdFromPath(c *gin.Context, param string) (string, error) {
 	idParam := c.Param(param)
 	if idParam == "" {
-		return primitive.ObjectID{}, errors.New("empty id param")
+		return "", errors.New("empty id param")
 	}
 
-	id, err := primitive.ObjectIDFromHex(idParam)
-	if err != nil {
-		return primitive.ObjectID{}, errors.New("invalid id param")
-	}
+	return idParam, nil
+}
 
-	return id, nil
+// parseTaskIdFromPath возвращает идентификатор задачи из пути. Формат
+// идентификатора зависит от хранилища, и проверяет его репозиторий.
+func parseTaskIdFromPath(c *gin.Context, param string) (entity.TaskID, error) {
+	return entity.ParseTaskID(c.Param(param))
 }
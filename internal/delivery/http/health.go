@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+)
+
+// @Summary Liveness probe
+// @Tags health
+// @Description Answers 200 as long as the process can serve requests
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+// Процесс жив
+func (h *Handler) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, map[string]string{
+		"status": health.StatusOK,
+	})
+}
+
+// @Summary Readiness probe
+// @Tags health
+// @Description Runs the dependency checks: database, migrations, background workers. Fails while the server shuts down
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+// Готов ли сервис принимать запросы
+func (h *Handler) readyz(c *gin.Context) {
+	report := h.health.Ready(c.Request.Context())
+	if !report.OK() {
+		c.JSON(http.StatusServiceUnavailable, report)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+func TestHandler_healthz(t *testing.T) {
+	handler := Handler{logger: logger.New("local")}
+
+	r := gin.New()
+	r.GET("/healthz", handler.healthz)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHandler_readyz(t *testing.T) {
+	tests := []struct {
+		name               string
+		check              health.Check
+		shutdown           bool
+		expectedStatusCode int
+		expectedStatus     string
+		expectedError      string
+		expectedCheckError string
+	}{
+		{
+			name:               "Ok",
+			check:              func(ctx context.Context) error { return nil },
+			expectedStatusCode: 200,
+			expectedStatus:     "ok",
+		},
+		{
+			name:               "CheckFailed",
+			check:              func(ctx context.Context) error { return errors.New("server selection timeout") },
+			expectedStatusCode: 503,
+			expectedStatus:     "fail",
+			expectedCheckError: "server selection timeout",
+		},
+		{
+			name:               "ShuttingDown",
+			check:              func(ctx context.Context) error { return nil },
+			shutdown:           true,
+			expectedStatusCode: 503,
+			expectedStatus:     "fail",
+			expectedError:      "shutting down",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := health.New(time.Second)
+			checker.Add("mongo", test.check)
+			if test.shutdown {
+				checker.Shutdown()
+			}
+
+			handler := Handler{health: checker, logger: logger.New("local")}
+
+			r := gin.New()
+			r.GET("/readyz", handler.readyz)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+			var report health.Report
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, test.expectedStatus, report.Status)
+			assert.Equal(t, test.expectedError, report.Error)
+			assert.Equal(t, test.expectedCheckError, report.Checks["mongo"].Error)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimit отклоняет тела больше max: заявленные в Content-Length сразу,
+// остальные при чтении (см. bindError).
+func bodyLimit(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > max {
+			errorResponse(c, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
+
+// deadline ограничивает время обработки запроса: контекст запроса
+// отменяется через timeout, и хранилища прерывают запросы к базе.
+func deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			errorResponse(c, http.StatusServiceUnavailable, "request timed out")
+		}
+	}
+}
+
+// bindError отвечает на ошибку чтения JSON-тела: 413 для слишком большого
+// тела, 408 для тела, не присланного за http.readTimeout, иначе 400.
+func bindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &tooLarge):
+		errorResponse(c, http.StatusRequestEntityTooLarge, "request body too large")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		// Соединение уже не читается: клиенту не отправить тело запроса заново.
+		c.Header("Connection", "close")
+		errorResponse(c, http.StatusRequestTimeout, "request body read timed out")
+	default:
+		errorResponse(c, http.StatusBadRequest, "invalid input body")
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// slowBody завершается ошибкой, как соединение с истекшим сроком чтения.
+type slowBody struct{}
+
+func (slowBody) Read([]byte) (int, error) { return 0, os.ErrDeadlineExceeded }
+
+func TestHandler_bodyLimit(t *testing.T) {
+	handler := Handler{logger: logger.New("error"), maxBodyBytes: 16}
+	r := handler.InitRoutes()
+
+	do := func(body io.Reader, length int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/todo-list/tasks", body)
+		req.ContentLength = length
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"title":"` + strings.Repeat("a", 32) + `"}`
+
+	w := do(strings.NewReader(body), int64(len(body)))
+	assert.Equal(t, 413, w.Code)
+	assert.JSONEq(t, `{"error":"request body too large"}`, w.Body.String())
+
+	// Без Content-Length предел достигается при чтении.
+	w = do(strings.NewReader(body), -1)
+	assert.Equal(t, 413, w.Code)
+	assert.JSONEq(t, `{"error":"request body too large"}`, w.Body.String())
+
+	w = do(strings.NewReader(`{"title":`), -1)
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"error":"invalid input body"}`, w.Body.String())
+
+	w = do(slowBody{}, -1)
+	assert.Equal(t, 408, w.Code)
+	assert.Equal(t, "close", w.Header().Get("Connection"))
+	assert.JSONEq(t, `{"error":"request body read timed out"}`, w.Body.String())
+}
+
+func TestHandler_deadline(t *testing.T) {
+	handler := Handler{logger: logger.New("error"), handlerTimeout: 20 * time.Millisecond}
+	r := handler.InitRoutes()
+
+	r.GET("/slow-error", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		errorResponse(c, 404, c.Request.Context().Err().Error())
+	})
+	r.GET("/slow-silent", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		c.Status(204)
+	})
+
+	for _, path := range []string{"/slow-error", "/slow-silent"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 503, w.Code, path)
+		assert.JSONEq(t, `{"error":"request timed out"}`, w.Body.String(), path)
+	}
+
+	req := httptest.NewRequest("GET", "/fast", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
+	"github.com/yervsil/toDo-microservice/pkg/ratelimit"
+)
+
+// UserKey - ключ gin.Context, под которым middleware аутентификации кладет
+// идентификатор пользователя; лимиты считаются по нему, если он есть.
+const UserKey = "user"
+
+// rateLimit ограничивает запросы группы маршрутов для каждого субъекта и
+// отвечает 429, когда его корзина пуста. Если хранилище недоступно, запрос
+// пропускается: лимиты не должны ронять API.
+func (h *Handler) rateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := limiter.Match(c.Request.Method, c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		res, err := limiter.Allow(c.Request.Context(), rule, principal(c))
+		if err != nil {
+			h.log(c).Warn("rate limit %s: %s", rule.Name, err)
+			c.Next()
+			return
+		}
+
+		limit := rule.Limit
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, ceilSeconds(limit.Per), limit.Burst))
+
+		if !res.Allowed {
+			metrics.RateLimited.WithLabelValues(rule.Name).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Max(1, float64(ceilSeconds(res.RetryAfter))))))
+			errorResponse(c, http.StatusTooManyRequests, "rate limit exceeded")
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// principal возвращает, чьи запросы считать вместе: пользователя, которого
+// установила middleware аутентификации, иначе IP клиента. Сам заголовок
+// Authorization не учитывается: пока его никто не проверил, каждый новый
+// токен давал бы новую корзину.
+func principal(c *gin.Context) string {
+	if user := c.GetString(UserKey); user != "" {
+		return "user:" + user
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/health"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func newLimiter(t *testing.T, store ratelimit.Store) *ratelimit.Limiter {
+	t.Helper()
+
+	limiter, err := ratelimit.New(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{
+		{Name: "probes", Paths: []string{"/healthz"}, Requests: 2, Per: time.Minute},
+	}}, store)
+	require.NoError(t, err)
+	return limiter
+}
+
+func TestHandler_rateLimit(t *testing.T) {
+	handler := Handler{
+		health:  health.New(time.Second),
+		logger:  logger.New("error"),
+		limiter: newLimiter(t, ratelimit.NewMemoryStore(clock.New())),
+	}
+	r := handler.InitRoutes()
+
+	do := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60;burst=2", w.Header().Get("RateLimit-Policy"))
+
+	// X-Forwarded-For пришел не от доверенного прокси: клиент все еще 10.0.0.1.
+	w = do("X-Forwarded-For", "192.0.2.7")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = do("", "")
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.JSONEq(t, `{"error":"rate limit exceeded"}`, w.Body.String())
+
+	w = do("Authorization", "Bearer token-1")
+	assert.Equal(t, 429, w.Code, "an unchecked token does not get its own bucket")
+
+	w = do("", "")
+	assert.Equal(t, 429, w.Code)
+	assert.NotEmpty(t, w.Header().Get(HeaderRequestID), "rejected requests still get an ID")
+
+	r.GET("/open", func(c *gin.Context) { c.Status(204) })
+	req := httptest.NewRequest("GET", "/open", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 204, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"), "unmatched routes are not limited")
+}
+
+func TestHandler_rateLimitFailsOpen(t *testing.T) {
+	handler := Handler{
+		health:  health.New(time.Second),
+		logger:  logger.New("error"),
+		limiter: newLimiter(t, failingStore{}),
+	}
+	r := handler.InitRoutes()
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		assert.Equal(t, 200, w.Code)
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:5000"
+	assert.Equal(t, "ip:10.0.0.1", principal(c))
+
+	c.Request.Header.Set("Authorization", "Bearer secret-token")
+	assert.Equal(t, "ip:10.0.0.1", principal(c))
+
+	c.Set(UserKey, "42")
+	assert.Equal(t, "user:42", principal(c))
+}
+
+func TestHandler_rateLimitPerUser(t *testing.T) {
+	handler := Handler{logger: logger.New("error")}
+
+	// authenticated заменяет middleware аутентификации.
+	authenticated := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set(UserKey, user)
+		}
+	}
+	r := gin.New()
+	r.Use(authenticated, handler.rateLimit(newLimiter(t, ratelimit.NewMemoryStore(clock.New()))))
+	r.GET("/healthz", func(c *gin.Context) { c.Status(200) })
+
+	do := func(user string) int {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 200, do("anna"))
+	assert.Equal(t, 200, do("anna"))
+	assert.Equal(t, 429, do("anna"))
+
+	assert.Equal(t, 200, do("john"), "another user on the same IP has a bucket of their own")
+	assert.Equal(t, 200, do(""), "so do anonymous requests from that IP")
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderRequestID - заголовок с идентификатором запроса.
+const HeaderRequestID = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// quietPaths опрашиваются инфраструктурой; их запросы пишутся в журнал только на уровне debug.
+var quietPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// requestLogger присваивает запросу идентификатор из X-Request-ID или новый,
+// возвращает его в ответе и кладет в контекст запроса логгер с этим
+// идентификатором (и trace_id, если запрос трассируется). По завершении
+// запроса пишет строку журнала доступа.
+func requestLogger(base *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(HeaderRequestID, id)
+
+		l := base.With("request_id", id)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			l = l.With("trace_id", span.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), l))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		access := l.
+			With("method", c.Request.Method).
+			With("path", c.Request.URL.Path).
+			With("status", status).
+			With("latency", time.Since(start))
+
+		switch {
+		case quietPaths[c.Request.URL.Path]:
+			access.Debug("request")
+		case status >= 500:
+			access.Error("request")
+		default:
+			access.Info("request")
+		}
+	}
+}
+
+// validRequestID принимает непустые идентификаторы разумной длины из
+// видимых символов ASCII, чтобы в журнал не попало что угодно от клиента.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
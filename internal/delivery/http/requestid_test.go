@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := gin.New()
+	r.Use(requestLogger(logger.NewWriter(&buf, "info")))
+	r.GET("/tasks", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Info("listing tasks")
+		c.Status(200)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(200) })
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{name: "propagated", requestID: "req-42"},
+		{name: "generated", generated: true},
+		{name: "invalid replaced", requestID: "bad id\n", generated: true},
+		{name: "too long replaced", requestID: strings.Repeat("a", maxRequestIDLength+1), generated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+
+			req := httptest.NewRequest("GET", "/tasks", nil)
+			if test.requestID != "" {
+				req.Header.Set(HeaderRequestID, test.requestID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(HeaderRequestID)
+			if test.generated {
+				assert.Len(t, id, 36)
+				assert.NotEqual(t, test.requestID, id)
+			} else {
+				assert.Equal(t, test.requestID, id)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+
+			var handlerLine, accessLine map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+
+			assert.Equal(t, "listing tasks", handlerLine["message"])
+			assert.Equal(t, id, handlerLine["request_id"])
+			assert.Equal(t, id, accessLine["request_id"])
+			assert.Equal(t, "request", accessLine["message"])
+			assert.Equal(t, "/tasks", accessLine["path"])
+			assert.Equal(t, float64(200), accessLine["status"])
+		})
+	}
+
+	t.Run("probes at debug", func(t *testing.T) {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+		assert.Empty(t, buf.String())
+	})
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+// securityHeaders добавляет к ответам стандартные заголовки защиты.
+// Content-Security-Policy не отправляется для /swagger/: интерфейсу
+// документации нужны скрипты и стили.
+func securityHeaders(cfg config.SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+
+		if cfg.ContentSecurityPolicy != "" && !strings.HasPrefix(c.Request.URL.Path, "/swagger/") {
+			h.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+
+		// Браузеры учитывают HSTS только из ответов по HTTPS.
+		if hsts != "" && c.Request.TLS != nil {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	r := gin.New()
+	r.Use(securityHeaders(config.SecurityHeadersConfig{
+		Enabled:               true,
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		ContentSecurityPolicy: "default-src 'none'",
+	}))
+	r.GET("/tasks", func(c *gin.Context) { c.Status(200) })
+	r.GET("/swagger/*any", func(c *gin.Context) { c.Status(200) })
+
+	req := httptest.NewRequest("GET", "/tasks", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "default-src 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "HSTS is only sent over HTTPS")
+
+	req = httptest.NewRequest("GET", "/tasks", nil)
+	req.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "max-age=31536000", w.Header().Get("Strict-Transport-Security"))
+
+	req = httptest.NewRequest("GET", "/swagger/index.html", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
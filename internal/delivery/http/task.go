@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,22 +19,23 @@ import (
 // @Param input body entity.Task true "Task information"
 // @Success 200 {integer} integer 1
 // @Failure 400 {object} response
+// @Failure 413 {object} response
 // @Failure 404 {object} response
+// @Failure 503 {object} response
 // @Router /api/todo-list/tasks [post]
-
 // Создать задачу
 func (h *Handler) createTask(c *gin.Context) {
 	var input entity.Task
 
-	if err := c.BindJSON(&input); err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusBadRequest, "invalid input body")
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Error(err)
+		bindError(c, err)
 
 		return
 	}
 
 	if !isValidDateFormat(input.ActiveAt){
-		h.logger.Error("incorrect date format")
+		h.log(c).Error("incorrect date format")
 		errorResponse(c, http.StatusBadRequest, "incorrect date format")
 
 		return
@@ -41,8 +44,8 @@ func (h *Handler) createTask(c *gin.Context) {
 
 	id, err := h.service.CreateTask(c.Request.Context(), input)
 	if err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusNotFound, err.Error())
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
 
 		return
 	}
@@ -61,12 +64,13 @@ func (h *Handler) createTask(c *gin.Context) {
 // @Param input body entity.Task true "Updated task information"
 // @Success 201 {string} string "Successfully updated"
 // @Failure 400 {object} response
+// @Failure 413 {object} response
 // @Failure 404 {object} response
+// @Failure 503 {object} response
 // @Router /api/todo-list/tasks/{int} [put]
-
 // Заменить задачу по id
 func (h *Handler) updateTask(c *gin.Context) {
-	taskId, err := parseIdFromPath(c, "id")
+	taskId, err := parseTaskIdFromPath(c, "id")
 	if err != nil {
 		errorResponse(c, http.StatusBadRequest, "invalid id param")
 
@@ -75,15 +79,15 @@ func (h *Handler) updateTask(c *gin.Context) {
 	
 	var input entity.Task
 
-	if err := c.BindJSON(&input); err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusBadRequest, "invalid input body")
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Error(err)
+		bindError(c, err)
 
 		return
 	}
 
 	if !isValidDateFormat(input.ActiveAt){
-		h.logger.Error("incorrect date format")
+		h.log(c).Error("incorrect date format")
 		errorResponse(c, http.StatusBadRequest, "incorrect date format")
 
 		return
@@ -93,9 +97,9 @@ func (h *Handler) updateTask(c *gin.Context) {
 	err = h.service.UpdateTask(c.Request.Context(), input, taskId)
 
 	if err != nil {
-		h.logger.Error(err)
+		h.log(c).Error(err)
 		
-		errorResponse(c, http.StatusNotFound, err.Error())
+		serviceError(c, http.StatusNotFound, err)
 
 		return
 	}
@@ -111,11 +115,11 @@ func (h *Handler) updateTask(c *gin.Context) {
 // @Success 201 {string} string "Successfully deleted"
 // @Failure 400 {object} response
 // @Failure 404 {object} response
+// @Failure 503 {object} response
 // @Router /api/todo-list/tasks/{id} [delete]
-
 // Удалить задачу по id
 func (h *Handler) deleteTask(c *gin.Context) {
-	taskId, err := parseIdFromPath(c, "id")
+	taskId, err := parseTaskIdFromPath(c, "id")
 	if err != nil {
 		errorResponse(c, http.StatusBadRequest, "invalid id param")
 
@@ -124,8 +128,8 @@ func (h *Handler) deleteTask(c *gin.Context) {
 
 	err = h.service.DeleteTask(c.Request.Context(), taskId)
 	if err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusNotFound, err.Error())
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
 
 		return
 	}
@@ -141,11 +145,11 @@ func (h *Handler) deleteTask(c *gin.Context) {
 // @Success 201 {string} string "Status has been changed"
 // @Failure 400 {object} response
 // @Failure 404 {object} response
+// @Failure 503 {object} response
 // @Router /api/todo-list/tasks/{id}/done [patch]
-
 // Обновить статус задачи на выполнено по id
 func (h *Handler) statusUpdate(c *gin.Context) {
-	taskId, err := parseIdFromPath(c, "id")
+	taskId, err := parseTaskIdFromPath(c, "id")
 	if err != nil {
 		errorResponse(c, http.StatusBadRequest, "invalid id param")
 
@@ -154,8 +158,8 @@ func (h *Handler) statusUpdate(c *gin.Context) {
 
 	err = h.service.StatusUpdate(c.Request.Context(), taskId)
 	if err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusNotFound, err.Error())
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
 
 		return
 	}
@@ -169,19 +173,27 @@ func (h *Handler) statusUpdate(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param status query string false "Status filter: active or done"
+// @Param X-Timezone header string false "IANA timezone of the user, UTC by default"
 // @Success 200 {array} entity.Task "List of todo items"
 // @Failure 400 {object} response
 // @Failure 404 {object} response
+// @Failure 503 {object} response
 // @Router /api/todo-list/tasks [get]
-
 // Получить все задачи взависимости от статуса
 func (h *Handler) getTasks(c *gin.Context) {
 	status := c.DefaultQuery("status", "active")
 
-	tasks, err := h.service.GetTasks(c.Request.Context(), status)
+	loc, err := parseTimezone(c)
 	if err != nil {
-		h.logger.Error(err)
-		errorResponse(c, http.StatusNotFound, err.Error())
+		errorResponse(c, http.StatusBadRequest, "invalid timezone")
+
+		return
+	}
+
+	tasks, err := h.service.GetTasks(c.Request.Context(), status, loc)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
 
 		return
 	}
@@ -193,7 +205,55 @@ func (h *Handler) getTasks(c *gin.Context) {
 	c.JSON(http.StatusOK, tasks)
 }
 
+// @Summary Search todo items
+// @Tags tasks
+// @Description Search todo items of any status by the words of their title, best matches first. Needs db.driver sqlite.
+// @Produce json
+// @Param q query string true "Words of the title; each one matches as a prefix"
+// @Success 200 {array} entity.Task "Matching todo items"
+// @Failure 400 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Router /api/todo-list/tasks/search [get]
+// Найти задачи по заголовку
+func (h *Handler) searchTasks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		errorResponse(c, http.StatusBadRequest, "empty search query")
+
+		return
+	}
+
+	tasks, err := h.service.SearchTasks(c.Request.Context(), query)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	if len(tasks) == 0 {
+		tasks = []entity.Task{}
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// isValidDateFormat принимает дату на весь день (YYYY-MM-DD) или дату и время в RFC 3339.
 func isValidDateFormat(dateStr string) bool {
-	_, err := time.Parse("2006-01-02", dateStr)
+	_, _, err := entity.ParseActiveAt(dateStr)
 	return err == nil
+}
+
+// parseTimezone возвращает часовой пояс пользователя из заголовка X-Timezone.
+func parseTimezone(c *gin.Context) (*time.Location, error) {
+	name := c.GetHeader("X-Timezone")
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, errors.New("invalid timezone")
+	}
+
+	return time.LoadLocation(name)
 }
\ No newline at end of file
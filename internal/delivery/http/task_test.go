@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/repository"
 	"github.com/yervsil/toDo-microservice/internal/service"
 	service_mocks "github.com/yervsil/toDo-microservice/internal/service/mocks"
 	"github.com/yervsil/toDo-microservice/pkg/logger"
@@ -20,7 +23,7 @@ import (
 )
 
 func TestHandler_createTask(t *testing.T) {
-	taskID := primitive.NewObjectID()
+	taskID := mustTaskID(primitive.NewObjectID().Hex())
 
 	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, task entity.Task)
 
@@ -43,7 +46,30 @@ func TestHandler_createTask(t *testing.T) {
 				r.EXPECT().CreateTask(ctx, task).Return(taskID, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: fmt.Sprintf(`{"id":"%s"}`, taskID.Hex()),
+			expectedResponseBody: fmt.Sprintf(`{"id":"%s"}`, taskID),
+		},
+
+		{
+			name:      "OkDateTime",
+			inputBody: `{"title":"Созвон", "activeAt":"2023-08-04T09:30:00+03:00", "timezone":"Europe/Moscow"}`,
+			inputTask: entity.Task{
+				Title:    "Созвон",
+				ActiveAt: "2023-08-04T09:30:00+03:00",
+				Timezone: "Europe/Moscow",
+			},
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task) {
+				r.EXPECT().CreateTask(ctx, task).Return(taskID, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: fmt.Sprintf(`{"id":"%s"}`, taskID),
+		},
+
+		{
+			name:                 "InvalidTimezone",
+			inputBody:            `{"title":"Купить книгу", "activeAt":"2023-08-04", "timezone":"Mars/Olympus"}`,
+			mockBehavior:         func(r *service_mocks.MockTask, ctx context.Context, task entity.Task) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input body"}`,
 		},
 
 		{
@@ -70,7 +96,7 @@ func TestHandler_createTask(t *testing.T) {
 				ActiveAt: "2023-08-04",
 			},
 			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task) {
-				r.EXPECT().CreateTask(ctx, task).Return(primitive.ObjectID{}, errors.New("service error"))
+				r.EXPECT().CreateTask(ctx, task).Return(entity.TaskID{}, errors.New("service error"))
 			},
 			expectedStatusCode:   http.StatusNotFound,
 			expectedResponseBody: `{"error":"service error"}`,
@@ -87,7 +113,7 @@ func TestHandler_createTask(t *testing.T) {
 			test.mockBehavior(repo, context.Background(), test.inputTask)
 
 			services := &service.Service{Task: repo}
-			handler := Handler{services, logger.New("local")}
+			handler := Handler{service: services, logger: logger.New("local")}
 
 			// Init Endpoint
 			r := gin.New()
@@ -110,7 +136,7 @@ func TestHandler_createTask(t *testing.T) {
 
 func TestHandler_updateTask(t *testing.T) {
 
-	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID)
+	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID)
 
 	tests := []struct {
 		name                 string
@@ -130,7 +156,7 @@ func TestHandler_updateTask(t *testing.T) {
 				ActiveAt: "2023-08-05",
 			},
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID) {
 				r.EXPECT().UpdateTask(ctx, task, taskID).Return(nil)
 			},
 			expectedStatusCode:   201,
@@ -141,20 +167,24 @@ func TestHandler_updateTask(t *testing.T) {
 			inputBody: `{}`, // Invalid input body
 			inputTask: entity.Task{},
 			taskID:    "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID) {
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"error":"invalid input body"}`,
 		},
 		{
-			name:      "incorrectIDParam",
+			name:      "UnknownIDFormat",
 			inputBody: `{"title":"Купить книгу - Высоконагруженные приложения", "activeAt":"2023-08-05"}`,
-			inputTask: entity.Task{},
+			inputTask: entity.Task{
+				Title:    "Купить книгу - Высоконагруженные приложения",
+				ActiveAt: "2023-08-05",
+			},
 			taskID:    "64d1c8747124f",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID) {
+				r.EXPECT().UpdateTask(ctx, task, taskID).Return(errors.New("no record found"))
 			},
-			expectedStatusCode:   400,
-			expectedResponseBody: `{"error":"invalid id param"}`,
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"error":"no record found"}`,
 		},
 		{
 			name:      "InvalidDateFormat",
@@ -164,7 +194,7 @@ func TestHandler_updateTask(t *testing.T) {
 				ActiveAt: "2023/08/05",
 			},
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID) {
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"error":"incorrect date format"}`,
@@ -177,7 +207,7 @@ func TestHandler_updateTask(t *testing.T) {
 				ActiveAt: "2023-08-05",
 			},
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, task entity.Task, taskID entity.TaskID) {
 				r.EXPECT().UpdateTask(ctx, task, taskID).Return(errors.New("update error"))
 			},
 			expectedStatusCode:   404,
@@ -186,16 +216,15 @@ func TestHandler_updateTask(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		id, _ := primitive.ObjectIDFromHex(test.taskID)
 		t.Run(test.name, func(t *testing.T) {
 			c := gomock.NewController(t)
 			defer c.Finish()
 
 			repo := service_mocks.NewMockTask(c)
-			test.mockBehavior(repo, context.Background(), test.inputTask, id)
+			test.mockBehavior(repo, context.Background(), test.inputTask, mustTaskID(test.taskID))
 
 			services := &service.Service{Task: repo}
-			handler := Handler{services, logger.New("local")}
+			handler := Handler{service: services, logger: logger.New("local")}
 
 			// Init Endpoint
 			r := gin.New()
@@ -217,7 +246,7 @@ func TestHandler_updateTask(t *testing.T) {
 }
 
 func TestHandler_deleteTask(t *testing.T) {
-	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID)
+	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID)
 
 	tests := []struct {
 		name                 string
@@ -229,24 +258,25 @@ func TestHandler_deleteTask(t *testing.T) {
 		{
 			name:   "Ok",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().DeleteTask(ctx, taskID).Return(nil)
 			},
 			expectedStatusCode:   201,
 			expectedResponseBody: `"successfully deleted"`,
 		},
 		{
-			name:                 "InvalidIDParam",
-			taskID:               "64d1c8747124f40af8030b", // Invalid taskID
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			name:                 "UnknownIDFormat",
+			taskID:               "64d1c8747124f40af8030b", // Формат проверяет репозиторий
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
+				r.EXPECT().DeleteTask(ctx, taskID).Return(errors.New("no record found"))
 			},
-			expectedStatusCode:   400,
-			expectedResponseBody: `{"error":"invalid id param"}`,
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"error":"no record found"}`,
 		},
 		{
 			name:   "NotFound",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().DeleteTask(ctx, taskID).Return(errors.New("task not found"))
 			},
 			expectedStatusCode:   404,
@@ -255,7 +285,7 @@ func TestHandler_deleteTask(t *testing.T) {
 		{
 			name:   "InternalServerError",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().DeleteTask(ctx, taskID).Return(errors.New("something went wrong"))
 			},
 			expectedStatusCode:   404,
@@ -264,16 +294,15 @@ func TestHandler_deleteTask(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		id, _ := primitive.ObjectIDFromHex(test.taskID)
 		t.Run(test.name, func(t *testing.T) {
 			c := gomock.NewController(t)
 			defer c.Finish()
 
 			repo := service_mocks.NewMockTask(c)
-			test.mockBehavior(repo, context.Background(), id)
+			test.mockBehavior(repo, context.Background(), mustTaskID(test.taskID))
 
 			services := &service.Service{Task: repo}
-			handler := Handler{services, logger.New("local")}
+			handler := Handler{service: services, logger: logger.New("local")}
 
 			// Init Endpoint
 			r := gin.New()
@@ -294,7 +323,7 @@ func TestHandler_deleteTask(t *testing.T) {
 }
 
 func TestHandler_statusUpdate(t *testing.T) {
-	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID)
+	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID)
 
 	tests := []struct {
 		name                 string
@@ -306,24 +335,25 @@ func TestHandler_statusUpdate(t *testing.T) {
 		{
 			name:   "Ok",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().StatusUpdate(ctx, taskID).Return(nil)
 			},
 			expectedStatusCode:   201,
 			expectedResponseBody: `"status has been changed"`,
 		},
 		{
-			name:                 "InvalidIDParam",
-			taskID:               "64d1c8747124f40af8030b", // Invalid taskID
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			name:                 "UnknownIDFormat",
+			taskID:               "64d1c8747124f40af8030b", // Формат проверяет репозиторий
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
+				r.EXPECT().StatusUpdate(ctx, taskID).Return(errors.New("no record found"))
 			},
-			expectedStatusCode:   400,
-			expectedResponseBody: `{"error":"invalid id param"}`,
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"error":"no record found"}`,
 		},
 		{
 			name:   "NotFound",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().StatusUpdate(ctx, taskID).Return(errors.New("task not found"))
 			},
 			expectedStatusCode:   404,
@@ -332,7 +362,7 @@ func TestHandler_statusUpdate(t *testing.T) {
 		{
 			name:   "InternalServerError",
 			taskID: "64d1c8747124f40af803840b",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID primitive.ObjectID) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, taskID entity.TaskID) {
 				r.EXPECT().StatusUpdate(ctx, taskID).Return(errors.New("internal server error"))
 			},
 			expectedStatusCode:   404,
@@ -347,12 +377,11 @@ func TestHandler_statusUpdate(t *testing.T) {
 
 			repo := service_mocks.NewMockTask(c)
 			ctx := context.Background()
-			taskID, _ := primitive.ObjectIDFromHex(test.taskID)
-			
-			test.mockBehavior(repo, ctx, taskID)
+
+			test.mockBehavior(repo, ctx, mustTaskID(test.taskID))
 
 			services := &service.Service{Task: repo}
-			handler := Handler{services, logger.New("local")}
+			handler := Handler{service: services, logger: logger.New("local")}
 
 			// Init Endpoint
 			r := gin.New()
@@ -374,11 +403,12 @@ func TestHandler_statusUpdate(t *testing.T) {
 
 
 func TestHandler_getTasks(t *testing.T) {
-	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, status string)
+	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location)
 
 	tests := []struct {
 		name                 string
 		queryStatus          string
+		timezone             string
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
@@ -386,8 +416,8 @@ func TestHandler_getTasks(t *testing.T) {
 		{
 			name:         "ActiveStatus_NoTasks",
 			queryStatus:  "active",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string) {
-				r.EXPECT().GetTasks(ctx, status).Return([]entity.Task{}, nil)
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return([]entity.Task{}, nil)
 			},
 			expectedStatusCode:   200,
 			expectedResponseBody: `[]`,
@@ -395,8 +425,8 @@ func TestHandler_getTasks(t *testing.T) {
 		{
 			name:         "CompletedStatus_NoTasks",
 			queryStatus:  "done",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string) {
-				r.EXPECT().GetTasks(ctx, status).Return([]entity.Task{}, nil)
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return([]entity.Task{}, nil)
 			},
 			expectedStatusCode:   200,
 			expectedResponseBody: `[]`,
@@ -404,21 +434,69 @@ func TestHandler_getTasks(t *testing.T) {
 		{
 			name:         "ActiveStatus_WithTasks",
 			queryStatus:  "active",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string) {
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
 				tasks := []entity.Task{
 					{Title: "Task 1", ActiveAt: "2023-08-10"},
 					{Title: "Task 2", ActiveAt: "2023-08-11"},
 				}
-				r.EXPECT().GetTasks(ctx, status).Return(tasks, nil)
+				r.EXPECT().GetTasks(ctx, status, loc).Return(tasks, nil)
 			},
 			expectedStatusCode:   200,
 			expectedResponseBody: `[{"title":"Task 1","activeAt":"2023-08-10"},{"title":"Task 2","activeAt":"2023-08-11"}]`,
 		},
+		{
+			name:         "UserTimezone",
+			queryStatus:  "active",
+			timezone:     "Asia/Almaty",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				tasks := []entity.Task{
+					{Title: "Task 1", ActiveAt: "2023-08-10T09:00:00+05:00", Timezone: "Asia/Almaty"},
+				}
+				r.EXPECT().GetTasks(ctx, status, loc).Return(tasks, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"title":"Task 1","activeAt":"2023-08-10T09:00:00+05:00","timezone":"Asia/Almaty"}]`,
+		},
+		{
+			name:         "DatabaseUnavailable",
+			queryStatus:  "active",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return(nil, fmt.Errorf("%w: server selection timeout", repository.ErrUnavailable))
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"error":"database unavailable"}`,
+		},
+		{
+			name:         "DatabaseTimeout",
+			queryStatus:  "active",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return(nil, &repository.CanceledError{Op: "GetTasks", Err: context.DeadlineExceeded})
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"error":"request timed out"}`,
+		},
+		{
+			name:         "RequestCanceled",
+			queryStatus:  "active",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return(nil, &repository.CanceledError{Op: "GetTasks", Err: context.Canceled})
+			},
+			expectedStatusCode:   499,
+			expectedResponseBody: `{"error":"request canceled"}`,
+		},
+		{
+			name:                 "InvalidTimezone",
+			queryStatus:          "active",
+			timezone:             "Mars/Olympus",
+			mockBehavior:         func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"error":"invalid timezone"}`,
+		},
 		{
 			name:         "InvalidStatus",
 			queryStatus:  "invalid_status",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string) {
-				r.EXPECT().GetTasks(ctx, status).Return([]entity.Task{}, errors.New("invalid status parameter"))
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return([]entity.Task{}, errors.New("invalid status parameter"))
 			},
 			expectedStatusCode:   404,
 			expectedResponseBody: `{"error":"invalid status parameter"}`,
@@ -426,8 +504,8 @@ func TestHandler_getTasks(t *testing.T) {
 		{
 			name:         "InternalServerError",
 			queryStatus:  "active",
-			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string) {
-				r.EXPECT().GetTasks(ctx, status).Return(nil, errors.New("internal server error"))
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, status string, loc *time.Location) {
+				r.EXPECT().GetTasks(ctx, status, loc).Return(nil, errors.New("internal server error"))
 			},
 			expectedStatusCode:   404,
 			expectedResponseBody: `{"error":"internal server error"}`,
@@ -441,10 +519,16 @@ func TestHandler_getTasks(t *testing.T) {
 
 			repo := service_mocks.NewMockTask(c)
 			ctx := context.Background()
-			test.mockBehavior(repo, ctx, test.queryStatus)
+			loc := time.UTC
+			if test.timezone != "" {
+				if l, err := time.LoadLocation(test.timezone); err == nil {
+					loc = l
+				}
+			}
+			test.mockBehavior(repo, ctx, test.queryStatus, loc)
 
 			services := &service.Service{Task: repo}
-			handler := Handler{services, logger.New("local")}
+			handler := Handler{service: services, logger: logger.New("local")}
 
 			// Init Endpoint
 			r := gin.New()
@@ -453,6 +537,92 @@ func TestHandler_getTasks(t *testing.T) {
 			// Create Request
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("GET", fmt.Sprintf("/tasks?status=%s", test.queryStatus), nil)
+			if test.timezone != "" {
+				req.Header.Set("X-Timezone", test.timezone)
+			}
+
+			// Make Request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+func TestHandler_searchTasks(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockTask, ctx context.Context, query string)
+
+	tests := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "Ok",
+			query: "книгу",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, query string) {
+				r.EXPECT().SearchTasks(ctx, query).Return([]entity.Task{{Status: "active", Title: "Купить книгу", ActiveAt: "2023-08-05"}}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"status":"active","title":"Купить книгу","activeAt":"2023-08-05"}]`,
+		},
+		{
+			name:  "NoMatches",
+			query: "книгу",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, query string) {
+				r.EXPECT().SearchTasks(ctx, query).Return(nil, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "EmptyQuery",
+			query:                "",
+			mockBehavior:         func(r *service_mocks.MockTask, ctx context.Context, query string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"error":"empty search query"}`,
+		},
+		{
+			name:  "UnsupportedDriver",
+			query: "книгу",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, query string) {
+				r.EXPECT().SearchTasks(ctx, query).Return(nil, repository.ErrUnsupported)
+			},
+			expectedStatusCode:   501,
+			expectedResponseBody: `{"error":"not supported by this database driver"}`,
+		},
+		{
+			name:  "ServiceError",
+			query: "книгу",
+			mockBehavior: func(r *service_mocks.MockTask, ctx context.Context, query string) {
+				r.EXPECT().SearchTasks(ctx, query).Return(nil, errors.New("search error"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"error":"search error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			repo := service_mocks.NewMockTask(c)
+			test.mockBehavior(repo, context.Background(), test.query)
+
+			services := &service.Service{Task: repo}
+			handler := Handler{service: services, logger: logger.New("local")}
+
+			// Init Endpoint
+			r := gin.New()
+			r.GET("/tasks/search", handler.searchTasks)
+
+			// Create Request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/tasks/search?q="+url.QueryEscape(test.query), nil)
 
 			// Make Request
 			r.ServeHTTP(w, req)
@@ -462,4 +632,12 @@ func TestHandler_getTasks(t *testing.T) {
 			assert.Equal(t, test.expectedResponseBody, w.Body.String())
 		})
 	}
-}
\ No newline at end of file
+}
+
+func mustTaskID(s string) entity.TaskID {
+	id, err := entity.ParseTaskID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// @Summary Create webhook
+// @Tags webhooks
+// @Description Subscribe a URL to task events. Deliveries are signed with HMAC-SHA256 of the body using the secret (X-Todo-Signature header)
+// @Accept json
+// @Produce json
+// @Param input body entity.Webhook true "Webhook information"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} response
+// @Failure 413 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/webhooks [post]
+// Создать подписку на события задач
+func (h *Handler) createWebhook(c *gin.Context) {
+	var input entity.Webhook
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Error(err)
+		bindError(c, err)
+
+		return
+	}
+
+	id, err := h.service.CreateWebhook(c.Request.Context(), input)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// @Summary Get webhooks
+// @Tags webhooks
+// @Description Get all webhook subscriptions
+// @Produce json
+// @Success 200 {array} entity.Webhook "List of webhooks"
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/webhooks [get]
+// Получить все подписки
+func (h *Handler) getWebhooks(c *gin.Context) {
+	webhooks, err := h.service.GetWebhooks(c.Request.Context())
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	if len(webhooks) == 0 {
+		webhooks = []entity.Webhook{}
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Delete webhook
+// @Tags webhooks
+// @Description Delete a webhook subscription and its delivery history
+// @Param id path string true "Webhook ID"
+// @Success 201 {string} string "Successfully deleted"
+// @Failure 400 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/webhooks/{id} [delete]
+// Удалить подписку по id
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	webhookId, err := parseIdFromPath(c, "id")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	err = h.service.DeleteWebhook(c.Request.Context(), webhookId)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, "successfully deleted")
+}
+
+// @Summary Get webhook deliveries
+// @Tags webhooks
+// @Description Get delivery history of a webhook with every attempt, newest first
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {array} entity.WebhookDelivery "List of deliveries"
+// @Failure 400 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/webhooks/{id}/deliveries [get]
+// Получить историю доставок подписки
+func (h *Handler) getDeliveries(c *gin.Context) {
+	webhookId, err := parseIdFromPath(c, "id")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(c.Request.Context(), webhookId)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	if len(deliveries) == 0 {
+		deliveries = []entity.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Redeliver webhook delivery
+// @Tags webhooks
+// @Description Send an existing delivery to the subscriber again
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {string} string "Redelivery scheduled"
+// @Failure 400 {object} response
+// @Failure 404 {object} response
+// @Failure 501 {object} response
+// @Failure 503 {object} response
+// @Router /api/todo-list/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+// Повторно отправить доставку
+func (h *Handler) redeliver(c *gin.Context) {
+	webhookId, err := parseIdFromPath(c, "id")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	deliveryId, err := parseIdFromPath(c, "deliveryId")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "invalid id param")
+
+		return
+	}
+
+	err = h.service.Redeliver(c.Request.Context(), webhookId, deliveryId)
+	if err != nil {
+		h.log(c).Error(err)
+		serviceError(c, http.StatusNotFound, err)
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, "redelivery scheduled")
+}
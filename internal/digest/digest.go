@@ -0,0 +1,98 @@
+// Package digest отправляет пользователям ежедневную сводку их задач по почте.
+package digest
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var funcs = map[string]any{
+	"day":   formatDay,
+	"at":    formatStart,
+	"clock": formatClock,
+}
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt.tmpl").Funcs(funcs).ParseFS(templates, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html.tmpl").Funcs(funcs).ParseFS(templates, "templates/digest.html.tmpl"))
+)
+
+// Digest — сводка одного дня пользователя.
+type Digest struct {
+	Name     string
+	Date     time.Time
+	Location *time.Location
+
+	// Today хранит активные задачи, приходящиеся на Date, Overdue — активные
+	// задачи прошлых дней, Completed — задачи, выполненные за день до Date.
+	Today     []entity.Task
+	Overdue   []entity.Task
+	Completed []entity.Task
+}
+
+// Empty сообщает, что рассказать пользователю не о чем.
+func (d Digest) Empty() bool {
+	return len(d.Today) == 0 && len(d.Overdue) == 0 && len(d.Completed) == 0
+}
+
+// Render собирает письмо для d с текстовым и HTML-телом.
+func Render(d Digest) (mail.Message, error) {
+	var text, html bytes.Buffer
+
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return mail.Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		Subject: "Задачи на " + d.Date.Format("02.01.2006"),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// formatDay выводит день задачи в часовом поясе пользователя.
+func formatDay(task entity.Task, loc *time.Location) string {
+	day, err := task.Day(loc)
+	if err != nil {
+		return task.ActiveAt
+	}
+
+	at, err := time.Parse(entity.DateLayout, day)
+	if err != nil {
+		return day
+	}
+
+	return at.Format("02.01.2006")
+}
+
+// formatStart выводит, когда задача со временем начинается в часовом поясе
+// пользователя; у задач на весь день времени начала нет.
+func formatStart(task entity.Task, loc *time.Location) string {
+	at, allDay, err := entity.ParseActiveAt(task.ActiveAt)
+	if err != nil || allDay {
+		return ""
+	}
+
+	return at.In(loc).Format("15:04")
+}
+
+// formatClock выводит, когда задача выполнена, в часовом поясе пользователя.
+func formatClock(doneAt *time.Time, loc *time.Location) string {
+	if doneAt == nil {
+		return ""
+	}
+
+	return doneAt.In(loc).Format("15:04")
+}
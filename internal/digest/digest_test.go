@@ -0,0 +1,88 @@
+package digest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func doneAt(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestRender_golden(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		digest Digest
+	}{
+		{
+			name: "full",
+			digest: Digest{
+				Name:     "Анна",
+				Date:     time.Date(2023, 8, 15, 0, 0, 0, 0, moscow),
+				Location: moscow,
+				Today: []entity.Task{
+					{Title: "Купить книгу", ActiveAt: "2023-08-15"},
+					{Title: "Позвонить <маме> & папе", ActiveAt: "2023-08-15"},
+					{Title: "Созвон с командой", ActiveAt: "2023-08-15T07:00:00Z"},
+				},
+				Overdue: []entity.Task{
+					{Title: "Оплатить счет", ActiveAt: "2023-08-10"},
+				},
+				Completed: []entity.Task{
+					{Title: "Сдать отчет", ActiveAt: "2023-08-14", DoneAt: doneAt("2023-08-14T15:30:00Z")},
+				},
+			},
+		},
+		{
+			name: "only_overdue",
+			digest: Digest{
+				Date:     time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC),
+				Location: time.UTC,
+				Overdue: []entity.Task{
+					{Title: "Оплатить счет", ActiveAt: "2023-08-10"},
+					{Title: "Записаться к врачу", ActiveAt: "2023-08-12T23:30:00-04:00"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := Render(test.digest)
+			require.NoError(t, err)
+
+			assert.Equal(t, "Задачи на 15.08.2023", msg.Subject)
+			assertGolden(t, test.name+".txt", msg.Text)
+			assertGolden(t, test.name+".html", msg.HTML)
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
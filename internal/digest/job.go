@@ -0,0 +1,220 @@
+package digest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store отдает задаче подписавшихся пользователей и их задачи. ClaimDigest
+// выдает сводку дня в аренду одной реплике; CompleteDigest отмечает ее
+// отправленной только после доставки, поэтому сводку реплики, упавшей во
+// время отправки, заберет следующая аренда.
+type Store interface {
+	GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error)
+	ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error)
+	CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error
+	ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error
+	GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error)
+	GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error)
+}
+
+// Job просыпается каждые interval и отправляет сводку каждому пользователю, у
+// которого наступил местный час отправки и который еще не получил сегодняшнюю.
+type Job struct {
+	store    Store
+	sender   mail.Sender
+	clock    clock.Clock
+	logger   logger.Interface
+	owner    string
+	interval time.Duration
+	lease    time.Duration
+}
+
+// NewJob -.
+func NewJob(store Store, sender mail.Sender, c clock.Clock, cfg config.DigestConfig, l logger.Interface) *Job {
+	j := &Job{
+		store:    store,
+		sender:   sender,
+		clock:    c,
+		logger:   l,
+		owner:    ownerID(),
+		interval: cfg.Interval,
+		lease:    cfg.Lease,
+	}
+
+	if j.interval <= 0 {
+		j.interval = time.Minute
+	}
+	if j.lease <= 0 {
+		j.lease = 5 * time.Minute
+	}
+
+	return j
+}
+
+// Run отправляет сводки, которым пора, пока ctx не отменен.
+func (j *Job) Run(ctx context.Context) {
+	for {
+		if _, err := j.Tick(ctx); err != nil && ctx.Err() == nil {
+			j.logger.Error(fmt.Errorf("digest: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.clock.After(j.interval):
+		}
+	}
+}
+
+// Tick отправляет все сводки, которым пора сейчас, и возвращает, сколько
+// отправлено.
+func (j *Job) Tick(ctx context.Context) (int, error) {
+	subscriptions, err := j.store.GetEnabledSubscriptions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get subscriptions: %w", err)
+	}
+
+	now := j.clock.Now()
+	sent := 0
+	for _, subscription := range subscriptions {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		ok, err := j.send(ctx, subscription, now)
+		if err != nil {
+			j.logger.Error(fmt.Errorf("digest: %s: %w", subscription.Email, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+func (j *Job) send(ctx context.Context, subscription entity.DigestSubscription, now time.Time) (bool, error) {
+	loc, err := time.LoadLocation(subscription.Timezone)
+	if err != nil {
+		return false, err
+	}
+
+	local := now.In(loc)
+	day := local.Format(entity.DateLayout)
+	if local.Hour() < subscription.Hour || subscription.LastSentOn == day {
+		return false, nil
+	}
+
+	claimed, err := j.store.ClaimDigest(ctx, subscription.ID, day, now, j.owner, j.lease)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	deliverCtx, cancel := context.WithTimeout(ctx, j.lease)
+	delivered, err := j.deliver(deliverCtx, subscription, local)
+	cancel()
+	if err != nil {
+		if releaseErr := j.store.ReleaseDigest(ctx, subscription.ID, j.owner); releaseErr != nil {
+			j.logger.Error(fmt.Errorf("digest: release %s: %w", subscription.Email, releaseErr))
+		}
+		return false, err
+	}
+
+	if err := j.store.CompleteDigest(ctx, subscription.ID, j.owner, day); err != nil {
+		// Письмо ушло; если аренду забрали, пользователь может получить сводку
+		// дважды, но не останется без нее.
+		j.logger.Error(fmt.Errorf("digest: complete %s: %w", subscription.Email, err))
+	}
+
+	return delivered, nil
+}
+
+func ownerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "todo"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// deliver отправляет сводку, если в ней что-то есть; пустой день все равно
+// считается отправленным, чтобы пользователя не проверяли до завтра.
+func (j *Job) deliver(ctx context.Context, subscription entity.DigestSubscription, local time.Time) (bool, error) {
+	digest, err := j.build(ctx, subscription, local)
+	if err != nil {
+		return false, err
+	}
+	if digest.Empty() {
+		return false, nil
+	}
+
+	msg, err := Render(digest)
+	if err != nil {
+		return false, err
+	}
+	msg.To = []string{subscription.Email}
+
+	if err := j.sender.Send(ctx, msg); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// build собирает сводку за день local в часовом поясе local.
+func (j *Job) build(ctx context.Context, subscription entity.DigestSubscription, local time.Time) (Digest, error) {
+	loc := local.Location()
+	day := local.Format(entity.DateLayout)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	digest := Digest{
+		Name:     subscription.Name,
+		Date:     start,
+		Location: loc,
+	}
+
+	tasks, err := j.store.GetTasksActiveUntil(ctx, start.AddDate(0, 0, 1).Add(-time.Millisecond), loc)
+	if err != nil {
+		return Digest{}, err
+	}
+
+	for _, task := range tasks {
+		taskDay, err := task.Day(loc)
+		if err != nil {
+			return Digest{}, err
+		}
+
+		// Задача, привязанная к зоне восточнее пользователя, может уже начаться
+		// в свою дату, которая здесь еще завтра; она относится к сегодняшнему
+		// дню.
+		if taskDay < day {
+			digest.Overdue = append(digest.Overdue, task)
+		} else {
+			digest.Today = append(digest.Today, task)
+		}
+	}
+
+	digest.Completed, err = j.store.GetTasksDoneBetween(ctx, start.AddDate(0, 0, -1), start)
+	if err != nil {
+		return Digest{}, err
+	}
+
+	return digest, nil
+}
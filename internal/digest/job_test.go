@@ -0,0 +1,256 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryStore struct {
+	mu            sync.Mutex
+	subscriptions []entity.DigestSubscription
+	tasks         []entity.Task
+}
+
+func (s *memoryStore) GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []entity.DigestSubscription
+	for _, sub := range s.subscriptions {
+		if sub.Enabled {
+			res = append(res, sub)
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.subscriptions {
+		sub := &s.subscriptions[i]
+		if sub.ID == subscriptionId && sub.Enabled && sub.LastSentOn != day && !sub.LeaseUntil.After(now) {
+			sub.LeaseOwner = owner
+			sub.LeaseUntil = now.Add(lease)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error {
+	return s.updateLeased(subscriptionId, owner, func(sub *entity.DigestSubscription) {
+		sub.LastSentOn = day
+	})
+}
+
+func (s *memoryStore) ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error {
+	return s.updateLeased(subscriptionId, owner, func(sub *entity.DigestSubscription) {})
+}
+
+func (s *memoryStore) updateLeased(subscriptionId primitive.ObjectID, owner string, fn func(sub *entity.DigestSubscription)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.subscriptions {
+		sub := &s.subscriptions[i]
+		if sub.ID == subscriptionId && sub.LeaseOwner == owner {
+			fn(sub)
+			sub.LeaseOwner = ""
+			sub.LeaseUntil = time.Time{}
+			return nil
+		}
+	}
+	return errors.New("digest lease lost")
+}
+
+func (s *memoryStore) GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error) {
+	var res []entity.Task
+	for _, task := range s.tasks {
+		start, err := task.Start(loc)
+		if err != nil {
+			return nil, err
+		}
+		if task.Status == "active" && !start.After(until) {
+			res = append(res, task)
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error) {
+	var res []entity.Task
+	for _, task := range s.tasks {
+		if task.Status == "done" && !task.DoneAt.Before(from) && task.DoneAt.Before(to) {
+			res = append(res, task)
+		}
+	}
+	return res, nil
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	messages []mail.Message
+	fail     int
+}
+
+func (r *recorder) Send(ctx context.Context, msg mail.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("smtp: 451 try again later")
+	}
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *recorder) sent() []mail.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]mail.Message(nil), r.messages...)
+}
+
+func testStore() *memoryStore {
+	return &memoryStore{
+		subscriptions: []entity.DigestSubscription{
+			{ID: primitive.NewObjectID(), Email: "anna@example.com", Name: "Анна", Timezone: "Europe/Moscow", Hour: 8, Enabled: true},
+			{ID: primitive.NewObjectID(), Email: "john@example.com", Timezone: "America/New_York", Hour: 8, Enabled: true},
+			{ID: primitive.NewObjectID(), Email: "off@example.com", Timezone: "UTC", Hour: 0, Enabled: false},
+		},
+		tasks: []entity.Task{
+			{Status: "active", Title: "Купить книгу", ActiveAt: "2023-08-15"},
+			{Status: "active", Title: "Оплатить счет", ActiveAt: "2023-08-10"},
+			{Status: "active", Title: "Завтра", ActiveAt: "2023-08-16"},
+			{Status: "active", Title: "Созвон", ActiveAt: "2023-08-15T07:00:00Z"},
+			// Полночь по Владивостоку 16 августа - это 14:00 UTC 15-го,
+			// поэтому задача попадает в сводку 15-го.
+			{Status: "active", Title: "Вылет", ActiveAt: "2023-08-16", Timezone: "Asia/Vladivostok"},
+			// 14 августа 00:30 по Москве: вчера для Москвы.
+			{Status: "done", Title: "Сдать отчет", ActiveAt: "2023-08-13", DoneAt: doneAt("2023-08-13T21:30:00Z")},
+			// 15 августа 00:30 по Москве: уже сегодня.
+			{Status: "done", Title: "Ночная задача", ActiveAt: "2023-08-14", DoneAt: doneAt("2023-08-14T21:30:00Z")},
+		},
+	}
+}
+
+func TestJob_sendsAtLocalHour(t *testing.T) {
+	store := testStore()
+	sender := &recorder{}
+	// 07:30 в Москве, 00:30 в Нью-Йорке.
+	clk := clock.NewFake(time.Date(2023, 8, 15, 4, 30, 0, 0, time.UTC))
+	job := NewJob(store, sender, clk, config.DigestConfig{}, logger.New("error"))
+
+	sent, err := job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	clk.Advance(30 * time.Minute)
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	messages := sender.sent()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"anna@example.com"}, messages[0].To)
+	assert.Equal(t, "Задачи на 15.08.2023", messages[0].Subject)
+	assert.Contains(t, messages[0].Text, "Сегодня (3):\n  - Купить книгу\n  - Созвон в 10:00\n  - Вылет\n")
+	assert.Contains(t, messages[0].Text, "Просрочено (1):\n  - Оплатить счет (с 10.08.2023)")
+	assert.Contains(t, messages[0].Text, "Выполнено вчера (1):\n  - Сдать отчет в 00:30")
+	assert.NotContains(t, messages[0].Text, "Завтра")
+
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "digest is sent once a day")
+
+	// 08:00 в Нью-Йорке: вчера там было 14 августа, 21:30 UTC это 17:30.
+	clk.Set(time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC))
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	messages = sender.sent()
+	require.Len(t, messages, 2)
+	assert.Equal(t, []string{"john@example.com"}, messages[1].To)
+	assert.Contains(t, messages[1].Text, "Сегодня (3):\n  - Купить книгу\n  - Созвон в 03:00\n  - Вылет\n")
+	assert.Contains(t, messages[1].Text, "Выполнено вчера (1):\n  - Ночная задача в 17:30")
+
+	// Следующим утром в Москве сводка уходит снова.
+	clk.Set(time.Date(2023, 8, 16, 5, 0, 0, 0, time.UTC))
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "Задачи на 16.08.2023", sender.sent()[2].Subject)
+	assert.Contains(t, sender.sent()[2].Text, "Сегодня (2):\n  - Завтра\n  - Вылет\n\nПросрочено (3):\n  - Купить книгу (с 15.08.2023)\n")
+}
+
+func TestJob_retriesFailedSend(t *testing.T) {
+	store := testStore()
+	sender := &recorder{fail: 1}
+	clk := clock.NewFake(time.Date(2023, 8, 15, 5, 0, 0, 0, time.UTC))
+	job := NewJob(store, sender, clk, config.DigestConfig{}, logger.New("error"))
+
+	sent, err := job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, store.subscriptions[0].LastSentOn)
+
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "2023-08-15", store.subscriptions[0].LastSentOn)
+}
+
+func TestJob_skipsEmptyDigest(t *testing.T) {
+	store := testStore()
+	store.tasks = nil
+	sender := &recorder{}
+	clk := clock.NewFake(time.Date(2023, 8, 15, 5, 0, 0, 0, time.UTC))
+	job := NewJob(store, sender, clk, config.DigestConfig{}, logger.New("error"))
+
+	sent, err := job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, sender.sent())
+	assert.Equal(t, "2023-08-15", store.subscriptions[0].LastSentOn)
+}
+
+func TestJob_retriesAfterCrash(t *testing.T) {
+	store := testStore()
+	sender := &recorder{}
+	clk := clock.NewFake(time.Date(2023, 8, 15, 5, 0, 0, 0, time.UTC))
+	job := NewJob(store, sender, clk, config.DigestConfig{Lease: 5 * time.Minute}, logger.New("error"))
+
+	// Другая реплика взяла сводку в аренду и упала, не отправив письмо.
+	ok, err := store.ClaimDigest(context.Background(), store.subscriptions[0].ID, "2023-08-15", clk.Now(), "crashed", 5*time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	sent, err := job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "the digest is leased to another replica")
+	assert.Empty(t, store.subscriptions[0].LastSentOn, "a claimed digest is not marked sent")
+
+	clk.Advance(5 * time.Minute)
+	sent, err = job.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"anna@example.com"}, sender.sent()[0].To)
+	assert.Equal(t, "2023-08-15", store.subscriptions[0].LastSentOn)
+	assert.Empty(t, store.subscriptions[0].LeaseOwner)
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DigestSubscription — согласие пользователя получать ежедневную сводку по
+// почте. Сводка отправляется раз в день, в Hour по Timezone пользователя.
+type DigestSubscription struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty" swaggertype:"string"`
+	Email      string             `json:"email" binding:"required,email"`
+	Name       string             `json:"name,omitempty" binding:"max=100"`
+	Timezone   string             `json:"timezone" binding:"required,timezone"`
+	Hour       int                `json:"hour" binding:"min=0,max=23"`
+	Enabled    bool               `json:"enabled"`
+	LastSentOn string             `json:"lastSentOn,omitempty" bson:"lastsenton,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	// LeaseOwner отправляет сегодняшнюю сводку до LeaseUntil; если реплика упала
+	// во время отправки, аренда истекает и сводка отправляется снова.
+	LeaseOwner string    `json:"-" bson:",omitempty"`
+	LeaseUntil time.Time `json:"-" bson:",omitempty"`
+}
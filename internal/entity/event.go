@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+	EventTaskDone    = "task.done"
+)
+
+// Event описывает изменение задачи, о котором уведомляются внешние подписчики.
+type Event struct {
+	// ID не меняется, сколько бы раз событие ни публиковалось: по нему
+	// подписчики отбрасывают повторы.
+	ID         string    `json:"id"`
+	Type       string    `json:"event"`
+	TaskID     string    `json:"taskId"`
+	Task       *Task     `json:"task,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// TraceContext - заголовки W3C (traceparent) запроса, изменившего задачу,
+	// чтобы доставка события продолжила его трассировку.
+	TraceContext map[string]string `json:"-" bson:",omitempty"`
+}
+
+const (
+	OutboxPending = "pending"
+	OutboxFailed  = "failed"
+)
+
+// OutboxMessage - событие, записанное в outbox в одной транзакции с изменением задачи
+// и ожидающее публикации. Событие, которое не удалось опубликовать за
+// outbox.maxAttempts попыток, остается в outbox со статусом OutboxFailed.
+type OutboxMessage struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Event      Event
+	Status     string
+	Attempts   int
+	LastError  string
+	LeaseOwner string
+	LeaseUntil time.Time
+	CreatedAt  time.Time
+}
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderFailed  = "failed"
+)
+
+// Reminder - напоминание о задаче, которое планировщик отправит в момент RemindAt.
+type Reminder struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	TaskID     string
+	Title      string
+	RemindAt   time.Time
+	Status     string
+	Attempts   int
+	LastError  string
+	LeaseOwner string
+	LeaseUntil time.Time
+	SentAt     time.Time
+}
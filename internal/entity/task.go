@@ -1,10 +1,87 @@
 package entity
 
 import (
+	"time"
 )
 
+// DateLayout — формат значений ActiveAt на весь день.
+const DateLayout = "2006-01-02"
+
+// TaskCounts — число незавершенных задач, которое отдается в метриках.
+type TaskCounts struct {
+	// Активные задачи уже начались и не выполнены, как в списке active
+	// пользователя в UTC.
+	Active int64
+	// Просроченные задачи — активные задачи, начавшиеся раньше сегодняшнего дня
+	// (UTC).
+	Overdue int64
+}
+
 type Task struct {
 	Status string      `json:"status,omitempty"`
 	Title    string    `json:"title" binding:"required,max=200"`
+	// ActiveAt — либо дата на весь день (2006-01-02), либо дата и время в RFC 3339.
 	ActiveAt string    `json:"activeAt" binding:"required"`
+	// Timezone привязывает ActiveAt на весь день к полуночи в этой зоне IANA. Без
+	// нее дата плавает: она начинается в полночь там, где находится пользователь.
+	Timezone string    `json:"timezone,omitempty" binding:"omitempty,timezone"`
+	RemindAt []time.Time `json:"remindAt,omitempty" bson:"remindat,omitempty" binding:"omitempty,max=10"`
+	DoneAt *time.Time `json:"doneAt,omitempty" bson:"doneat,omitempty" swaggertype:"string"`
+}
+
+// ParseActiveAt разбирает дату на весь день или дату и время в RFC 3339. Дата
+// на весь день возвращается как полночь UTC этого дня.
+func ParseActiveAt(activeAt string) (time.Time, bool, error) {
+	if day, err := time.Parse(DateLayout, activeAt); err == nil {
+		return day, true, nil
+	}
+
+	at, err := time.Parse(time.RFC3339, activeAt)
+	return at, false, err
+}
+
+// FormatActiveAt обратна Task.Start для сохраненных задач: она выводит момент
+// начала как дату на весь день или как дату и время RFC 3339 в timezone.
+func FormatActiveAt(start time.Time, allDay bool, timezone string) string {
+	loc := time.UTC
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		}
+	}
+
+	if allDay {
+		return start.In(loc).Format(DateLayout)
+	}
+
+	return start.In(loc).Format(time.RFC3339)
+}
+
+// Start возвращает момент, когда задача становится активной для пользователя в loc.
+func (t Task) Start(loc *time.Location) (time.Time, error) {
+	at, allDay, err := ParseActiveAt(t.ActiveAt)
+	if err != nil || !allDay {
+		return at, err
+	}
+
+	if t.Timezone != "" {
+		if loc, err = time.LoadLocation(t.Timezone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// Day возвращает календарный день задачи для пользователя в loc.
+func (t Task) Day(loc *time.Location) (string, error) {
+	at, allDay, err := ParseActiveAt(t.ActiveAt)
+	if err != nil {
+		return "", err
+	}
+	if allDay {
+		return t.ActiveAt, nil
+	}
+
+	return at.In(loc).Format(DateLayout), nil
 }
\ No newline at end of file
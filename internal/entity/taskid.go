@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Генераторы идентификаторов задач для db.taskIds.
+const (
+	TaskIDObjectID = "objectid"
+	TaskIDUUIDv7   = "uuidv7"
+	TaskIDULID     = "ulid"
+)
+
+// ErrInvalidTaskID возвращает ParseTaskID для пустого идентификатора.
+var ErrInvalidTaskID = errors.New("invalid task id")
+
+// TaskID идентифицирует задачу. Его формат зависит от генератора и хранилища,
+// поэтому вне репозиториев это непрозрачная строка: ее можно сравнивать и
+// выводить, но не разбирать.
+type TaskID struct {
+	value string
+}
+
+// ParseTaskID принимает любую непустую строку. Может ли существовать задача с
+// таким идентификатором, решает репозиторий, иначе он ответит "no record found".
+func ParseTaskID(s string) (TaskID, error) {
+	if s == "" {
+		return TaskID{}, ErrInvalidTaskID
+	}
+
+	return TaskID{value: s}, nil
+}
+
+func (id TaskID) String() string {
+	return id.value
+}
+
+func (id TaskID) IsZero() bool {
+	return id.value == ""
+}
+
+func (id TaskID) MarshalText() ([]byte, error) {
+	return []byte(id.value), nil
+}
+
+func (id *TaskID) UnmarshalText(text []byte) error {
+	parsed, err := ParseTaskID(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// TaskIDGenerator выдает идентификаторы новых задач в репозиториях, где их
+// выбирает приложение. Идентификаторы всех генераторов упорядочены по времени
+// создания.
+type TaskIDGenerator interface {
+	NewTaskID() TaskID
+}
+
+// TaskIDGeneratorFunc превращает функцию в TaskIDGenerator.
+type TaskIDGeneratorFunc func() TaskID
+
+func (f TaskIDGeneratorFunc) NewTaskID() TaskID {
+	return f()
+}
+
+// NewTaskIDGenerator возвращает генератор, названный в db.taskIds.
+func NewTaskIDGenerator(kind string) (TaskIDGenerator, error) {
+	switch kind {
+	case TaskIDObjectID:
+		return TaskIDGeneratorFunc(func() TaskID {
+			return TaskID{value: primitive.NewObjectID().Hex()}
+		}), nil
+
+	case TaskIDUUIDv7:
+		return TaskIDGeneratorFunc(func() TaskID {
+			return TaskID{value: uuid.Must(uuid.NewV7()).String()}
+		}), nil
+
+	case TaskIDULID:
+		// ulid.Make монотонна в пределах процесса, как и два других.
+		return TaskIDGeneratorFunc(func() TaskID {
+			return TaskID{value: ulid.Make().String()}
+		}), nil
+	}
+
+	return nil, fmt.Errorf("unknown task id generator %q", kind)
+}
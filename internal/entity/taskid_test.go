@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaskIDGenerator(t *testing.T) {
+	formats := map[string]*regexp.Regexp{
+		TaskIDObjectID: regexp.MustCompile(`^[0-9a-f]{24}$`),
+		TaskIDUUIDv7:   regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		TaskIDULID:     regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`),
+	}
+
+	for kind, format := range formats {
+		t.Run(kind, func(t *testing.T) {
+			ids, err := NewTaskIDGenerator(kind)
+			require.NoError(t, err)
+
+			seen := map[TaskID]bool{}
+			var generated []string
+			for i := 0; i < 1000; i++ {
+				id := ids.NewTaskID()
+				assert.Regexp(t, format, id.String())
+				assert.False(t, seen[id], "duplicate id %s", id)
+
+				seen[id] = true
+				generated = append(generated, id.String())
+			}
+
+			assert.True(t, sort.StringsAreSorted(generated), "ids must sort by creation time")
+		})
+	}
+
+	_, err := NewTaskIDGenerator("autoincrement")
+	assert.EqualError(t, err, `unknown task id generator "autoincrement"`)
+}
+
+func TestParseTaskID(t *testing.T) {
+	id, err := ParseTaskID("42")
+	require.NoError(t, err)
+	assert.Equal(t, "42", id.String())
+	assert.False(t, id.IsZero())
+
+	_, err = ParseTaskID("")
+	assert.ErrorIs(t, err, ErrInvalidTaskID)
+	assert.True(t, TaskID{}.IsZero())
+}
+
+func TestTaskIDJSON(t *testing.T) {
+	id, err := ParseTaskID("01HF8Z5V6Q3W1T0Y4X2R7N9K8M")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]TaskID{"id": id})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"01HF8Z5V6Q3W1T0Y4X2R7N9K8M"}`, string(data))
+
+	var decoded map[string]TaskID
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, id, decoded["id"])
+
+	assert.Error(t, json.Unmarshal([]byte(`{"id":""}`), &decoded))
+}
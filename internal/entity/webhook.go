@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook - подписка внешнего сервиса на события задач.
+type Webhook struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty" swaggertype:"string"`
+	URL       string             `json:"url" binding:"required,url"`
+	Secret    string             `json:"secret,omitempty" binding:"required,min=16"`
+	Events    []string           `json:"events" binding:"required,min=1,dive,oneof=task.created task.updated task.deleted task.done"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие.
+func (w Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookDelivery - одна доставка события подписчику вместе с историей попыток.
+type WebhookDelivery struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty" swaggertype:"string"`
+	WebhookID primitive.ObjectID `json:"webhookId" swaggertype:"string"`
+	Event     string             `json:"event"`
+	Payload   string             `json:"payload"`
+	Status    string             `json:"status"`
+	Attempts  []DeliveryAttempt  `json:"attempts"`
+	CreatedAt time.Time          `json:"createdAt"`
+	// EventID - идентификатор события (Event.ID): у события одна доставка
+	// каждому подписчику.
+	EventID string `json:"eventId,omitempty" bson:",omitempty"`
+	// TraceContext - заголовки W3C события, ради которого создана доставка.
+	TraceContext map[string]string `json:"-" bson:",omitempty"`
+	// Tries - попытки текущего круга доставки: повторная отправка начинает
+	// новый круг. LeaseOwner держит доставку до LeaseUntil; у ожидающей
+	// повтора доставки LeaseUntil - время следующей попытки.
+	Tries      int       `json:"-"`
+	LeaseOwner string    `json:"-"`
+	LeaseUntil time.Time `json:"-"`
+}
+
+// DeliveryAttempt - результат одного HTTP-запроса к подписчику.
+type DeliveryAttempt struct {
+	At         time.Time     `json:"at"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration" swaggertype:"integer"`
+}
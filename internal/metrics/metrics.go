@@ -0,0 +1,124 @@
+// Package metrics хранит метрики Prometheus сервиса и то, что их записывает:
+// middleware gin, монитор команд MongoDB и задачу, обновляющую показатели
+// задач.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// unmatchedRoute — метка запросов, не подошедших ни к одному маршруту, чтобы
+// сканеры, перебирающие случайные пути, не создавали ряд на каждый путь.
+const unmatchedRoute = "unmatched"
+
+var (
+	// Registry хранит все метрики сервиса; Handler отдает их.
+	Registry = prometheus.NewRegistry()
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Requests rejected with 429 by rate limit group.",
+	}, []string{"group"})
+
+	TasksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "todo_tasks_created_total",
+		Help: "Tasks created.",
+	})
+
+	TasksCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "todo_tasks_completed_total",
+		Help: "Tasks marked done.",
+	})
+
+	TasksActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "todo_tasks_active",
+		Help: "Tasks that have started and are not done.",
+	})
+
+	TasksOverdue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "todo_tasks_overdue",
+		Help: "Active tasks that started before today (UTC).",
+	})
+
+	MongoCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongodb_command_duration_seconds",
+		Help:    "Duration of MongoDB commands by command name and outcome (ok or error).",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "outcome"})
+
+	TaskCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "todo_task_cache_requests_total",
+		Help: "Task listings read through the cache by result (hit or miss).",
+	}, []string{"result"})
+
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "State of a circuit breaker by name: 0 closed, 1 open, 2 half-open.",
+	}, []string{"name"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		RateLimited,
+		TasksCreated,
+		TasksCompleted,
+		TasksActive,
+		TasksOverdue,
+		MongoCommandDuration,
+		TaskCacheRequests,
+		CircuitBreakerState,
+	)
+}
+
+// Handler отдает метрики в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// GinMiddleware замеряет время каждого запроса. Запросы помечаются шаблоном
+// маршрута (/api/todo-list/tasks/:id), а не путем.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// MongoMonitor замеряет время каждой команды, отправленной в MongoDB.
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	HTTPRequestDuration.Reset()
+
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/tasks/:id", func(c *gin.Context) { c.Status(404) })
+	r.GET("/metrics", gin.WrapH(Handler()))
+
+	for _, path := range []string{"/tasks/1", "/tasks/2", "/nope", "/nope/either"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2, testutil.CollectAndCount(HTTPRequestDuration))
+	assert.Equal(t, uint64(2), histogramCount(t, "GET", "/tasks/:id", "404"))
+	assert.Equal(t, uint64(2), histogramCount(t, "GET", "unmatched", "404"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `http_request_duration_seconds_count{method="GET",route="/tasks/:id",status="404"} 2`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+func TestMongoMonitor(t *testing.T) {
+	MongoCommandDuration.Reset()
+	monitor := MongoMonitor()
+
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: 3 * time.Millisecond},
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", Duration: time.Millisecond},
+	})
+
+	expected := `
+# HELP mongodb_command_duration_seconds Duration of MongoDB commands by command name and outcome (ok or error).
+# TYPE mongodb_command_duration_seconds histogram
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.0005"} 0
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.001"} 0
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.0025"} 0
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.005"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.01"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.025"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.05"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.1"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.25"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="0.5"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="1"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="2.5"} 1
+mongodb_command_duration_seconds_bucket{command="find",outcome="ok",le="+Inf"} 1
+mongodb_command_duration_seconds_sum{command="find",outcome="ok"} 0.003
+mongodb_command_duration_seconds_count{command="find",outcome="ok"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.0005"} 0
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.001"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.0025"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.005"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.01"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.025"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.05"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.1"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.25"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="0.5"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="1"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="2.5"} 1
+mongodb_command_duration_seconds_bucket{command="insert",outcome="error",le="+Inf"} 1
+mongodb_command_duration_seconds_sum{command="insert",outcome="error"} 0.001
+mongodb_command_duration_seconds_count{command="insert",outcome="error"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(MongoCommandDuration, strings.NewReader(expected)))
+}
+
+type fakeCounter struct {
+	counts entity.TaskCounts
+	err    error
+	now    time.Time
+}
+
+func (f *fakeCounter) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	f.now = now
+	return f.counts, f.err
+}
+
+func TestTaskGauges_Refresh(t *testing.T) {
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	tasks := &fakeCounter{counts: entity.TaskCounts{Active: 7, Overdue: 3}}
+
+	g := NewTaskGauges(tasks, config.MetricsConfig{}, logger.New("local"))
+	g.now = func() time.Time { return now }
+
+	require.NoError(t, g.Refresh(context.Background()))
+	assert.Equal(t, now, tasks.now)
+	assert.Equal(t, float64(7), testutil.ToFloat64(TasksActive))
+	assert.Equal(t, float64(3), testutil.ToFloat64(TasksOverdue))
+
+	tasks.err = errors.New("connection refused")
+	assert.EqualError(t, g.Refresh(context.Background()), "count tasks: connection refused")
+	assert.Equal(t, float64(7), testutil.ToFloat64(TasksActive), "gauges keep the last values")
+}
+
+func histogramCount(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+
+	metric, err := HTTPRequestDuration.GetMetricWithLabelValues(labels...)
+	require.NoError(t, err)
+
+	m := &dto.Metric{}
+	require.NoError(t, metric.(prometheus.Histogram).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// TaskCounter считает задачи для показателей.
+type TaskCounter interface {
+	CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error)
+}
+
+// TaskGauges поддерживает TasksActive и TasksOverdue актуальными. Подсчет
+// выполняет запрос, поэтому он идет периодически, а не при каждом сборе.
+type TaskGauges struct {
+	tasks    TaskCounter
+	interval time.Duration
+	logger   logger.Interface
+	now      func() time.Time
+}
+
+func NewTaskGauges(tasks TaskCounter, cfg config.MetricsConfig, l logger.Interface) *TaskGauges {
+	g := &TaskGauges{
+		tasks:    tasks,
+		interval: cfg.RefreshInterval,
+		logger:   l,
+		now:      time.Now,
+	}
+
+	if g.interval <= 0 {
+		g.interval = 30 * time.Second
+	}
+
+	return g
+}
+
+// Run обновляет показатели каждые interval, пока ctx не отменен.
+func (g *TaskGauges) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := g.Refresh(ctx); err != nil && ctx.Err() == nil {
+			g.logger.Error(fmt.Errorf("metrics: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh считает задачи один раз. При ошибке показатели сохраняют прошлые
+// значения.
+func (g *TaskGauges) Refresh(ctx context.Context) error {
+	counts, err := g.tasks.CountTasks(ctx, g.now())
+	if err != nil {
+		return fmt.Errorf("count tasks: %w", err)
+	}
+
+	TasksActive.Set(float64(counts.Active))
+	TasksOverdue.Set(float64(counts.Overdue))
+
+	return nil
+}
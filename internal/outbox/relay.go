@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/yervsil/toDo-microservice/internal/outbox")
+
+// Publisher получает события, извлеченные из outbox. Доставка "хотя бы один
+// раз": сообщение публикуется всем издателям заново, если один из них
+// ошибся, поэтому реализации должны выдерживать повтор одного события. Его
+// идентификатор каждый раз тот же.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+// PublisherFunc превращает функцию в Publisher.
+type PublisherFunc func(ctx context.Context, event entity.Event) error
+
+// Publish -.
+func (f PublisherFunc) Publish(ctx context.Context, event entity.Event) error {
+	return f(ctx, event)
+}
+
+// Store выдает в аренду сообщения, записанные репозиторием. Арендовать можно
+// только самое старое непроваленное сообщение задачи, чтобы ретрансляторы
+// нескольких реплик публиковали события одной задачи в порядке записи.
+// Аренда упавшей реплики истекает, и сообщение забирает другая.
+type Store interface {
+	AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error)
+	DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error
+	RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error
+	FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error
+}
+
+// Relay переносит outbox в издателей. Сообщение удаляется, только когда его
+// приняли все издатели. Неудавшееся сообщение повторяется через retryDelay, а
+// следующие сообщения его задачи ждут; после maxAttempts оно помечается
+// проваленным и перестает их задерживать.
+type Relay struct {
+	store      Store
+	publishers []Publisher
+	clock      clock.Clock
+	logger     logger.Interface
+	owner      string
+
+	interval    time.Duration
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewRelay -.
+func NewRelay(store Store, c clock.Clock, cfg config.OutboxConfig, l logger.Interface, publishers ...Publisher) *Relay {
+	r := &Relay{
+		store:       store,
+		publishers:  publishers,
+		clock:       c,
+		logger:      l,
+		owner:       ownerID(),
+		interval:    cfg.Interval,
+		batchSize:   cfg.BatchSize,
+		lease:       cfg.Lease,
+		maxAttempts: cfg.MaxAttempts,
+		retryDelay:  cfg.RetryDelay,
+	}
+
+	if r.interval <= 0 {
+		r.interval = time.Second
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = 100
+	}
+	if r.lease <= 0 {
+		r.lease = 30 * time.Second
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = 10
+	}
+	if r.retryDelay <= 0 {
+		r.retryDelay = 5 * time.Second
+	}
+
+	return r
+}
+
+// Run опустошает outbox каждые interval, пока ctx не отменен. За проходом,
+// который что-то опубликовал, сразу следует следующий: следующие события его
+// задач можно арендовать только теперь.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, err := r.Drain(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error(fmt.Errorf("outbox: %w", err))
+		}
+
+		if n == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.clock.After(r.interval):
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Drain публикует одну пачку арендованных сообщений и возвращает, сколько из
+// них опубликовано.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	messages, err := r.store.AcquireMessages(ctx, r.clock.Now(), r.owner, r.lease, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("acquire messages: %w", err)
+	}
+
+	published := 0
+	for _, msg := range messages {
+		if r.dispatch(ctx, msg) {
+			published++
+		}
+	}
+
+	return published, nil
+}
+
+func (r *Relay) dispatch(ctx context.Context, msg entity.OutboxMessage) bool {
+	err := r.publish(ctx, msg.Event)
+	if err == nil {
+		if err := r.store.DeleteMessage(ctx, msg.ID, r.owner); err != nil {
+			r.logger.Error(fmt.Errorf("outbox: delete message %s: %w", msg.ID.Hex(), err))
+		}
+		return true
+	}
+
+	if msg.Attempts+1 >= r.maxAttempts {
+		r.logger.Error(fmt.Errorf("outbox: %s for task %s failed after %d attempts: %w", msg.Event.Type, msg.Event.TaskID, msg.Attempts+1, err))
+		if err := r.store.FailMessage(ctx, msg.ID, r.owner, err.Error()); err != nil {
+			r.logger.Error(fmt.Errorf("outbox: fail message %s: %w", msg.ID.Hex(), err))
+		}
+		return false
+	}
+
+	r.logger.Warn("outbox: %s for task %s not published (attempt %d): %s", msg.Event.Type, msg.Event.TaskID, msg.Attempts+1, err)
+	retryAt := r.clock.Now().Add(r.retryDelay)
+	if err := r.store.RetryMessage(ctx, msg.ID, r.owner, retryAt, err.Error()); err != nil {
+		r.logger.Error(fmt.Errorf("outbox: retry message %s: %w", msg.ID.Hex(), err))
+	}
+
+	return false
+}
+
+// publish передает event каждому издателю в спане, продолжающем трассировку
+// вызвавшего его запроса.
+func (r *Relay) publish(ctx context.Context, event entity.Event) (err error) {
+	ctx, span := tracer.Start(tracing.Extract(ctx, event.TraceContext), "outbox.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("event.type", event.Type),
+			attribute.String("task.id", event.TaskID),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	for _, p := range r.publishers {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ownerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "todo"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,274 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStore выдает сообщения в аренду, как репозиторий Mongo: только самое
+// старое непроваленное сообщение задачи и только если его аренда свободна.
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []entity.OutboxMessage
+}
+
+func (s *memoryStore) add(taskId, eventType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, entity.OutboxMessage{
+		ID:     primitive.NewObjectID(),
+		Event:  entity.Event{Type: eventType, TaskID: taskId},
+		Status: entity.OutboxPending,
+	})
+}
+
+func (s *memoryStore) AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []entity.OutboxMessage
+	heads := make(map[string]bool)
+	for i := range s.messages {
+		m := &s.messages[i]
+		if m.Status == entity.OutboxFailed || heads[m.Event.TaskID] {
+			continue
+		}
+		heads[m.Event.TaskID] = true
+		if m.LeaseUntil.After(now) || len(res) == limit {
+			continue
+		}
+		m.LeaseOwner, m.LeaseUntil = owner, now.Add(lease)
+		res = append(res, *m)
+	}
+	return res, nil
+}
+
+func (s *memoryStore) DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.messages {
+		if m.ID == messageId && m.LeaseOwner == owner {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("outbox message lease lost")
+}
+
+func (s *memoryStore) RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return s.update(messageId, owner, func(m *entity.OutboxMessage) {
+		m.Attempts++
+		m.LastError = reason
+		m.LeaseOwner, m.LeaseUntil = "", retryAt
+	})
+}
+
+func (s *memoryStore) FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error {
+	return s.update(messageId, owner, func(m *entity.OutboxMessage) {
+		m.Attempts++
+		m.LastError = reason
+		m.Status = entity.OutboxFailed
+		m.LeaseOwner, m.LeaseUntil = "", time.Time{}
+	})
+}
+
+func (s *memoryStore) update(messageId primitive.ObjectID, owner string, fn func(m *entity.OutboxMessage)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.messages {
+		if s.messages[i].ID == messageId && s.messages[i].LeaseOwner == owner {
+			fn(&s.messages[i])
+			return nil
+		}
+	}
+	return errors.New("outbox message lease lost")
+}
+
+func (s *memoryStore) pending() []entity.OutboxMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]entity.OutboxMessage(nil), s.messages...)
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	events []entity.Event
+	failOn map[string]int
+}
+
+func (r *recorder) Publish(ctx context.Context, event entity.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failOn[event.TaskID] > 0 {
+		r.failOn[event.TaskID]--
+		return errors.New("broker unavailable")
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recorder) published() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []string
+	for _, e := range r.events {
+		res = append(res, e.TaskID+":"+e.Type)
+	}
+	return res
+}
+
+// drain выполняет проходы, пока один не опубликует ничего, и возвращает,
+// сколько сообщений опубликовано всего.
+func drain(t *testing.T, relay *Relay) int {
+	t.Helper()
+	total := 0
+	for {
+		n, err := relay.Drain(context.Background())
+		require.NoError(t, err)
+		if n == 0 {
+			return total
+		}
+		total += n
+	}
+}
+
+func TestRelay_Drain(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", entity.EventTaskCreated)
+	store.add("b", entity.EventTaskCreated)
+	store.add("a", entity.EventTaskUpdated)
+	store.add("b", entity.EventTaskDone)
+	store.add("a", entity.EventTaskDeleted)
+
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	rec := &recorder{failOn: map[string]int{"a": 1}}
+	relay := NewRelay(store, clk, config.OutboxConfig{BatchSize: 10, RetryDelay: time.Minute}, logger.New("error"), rec)
+
+	assert.Equal(t, 2, drain(t, relay))
+	assert.Equal(t, []string{"b:task.created", "b:task.done"}, rec.published())
+
+	pending := store.pending()
+	require.Len(t, pending, 3)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "broker unavailable", pending[0].LastError)
+	assert.Equal(t, 0, pending[1].Attempts, "later events of a blocked task are not attempted")
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, 3, drain(t, relay))
+	assert.Equal(t, []string{
+		"b:task.created", "b:task.done",
+		"a:task.created", "a:task.updated", "a:task.deleted",
+	}, rec.published())
+	assert.Empty(t, store.pending())
+}
+
+func TestRelay_maxAttempts(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", entity.EventTaskCreated)
+	store.add("a", entity.EventTaskUpdated)
+
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	rec := &recorder{failOn: map[string]int{"a": 2}}
+	relay := NewRelay(store, clk, config.OutboxConfig{MaxAttempts: 2, RetryDelay: time.Minute}, logger.New("error"), rec)
+
+	assert.Equal(t, 0, drain(t, relay))
+	clk.Advance(time.Minute)
+	assert.Equal(t, 0, drain(t, relay))
+	assert.Equal(t, 1, drain(t, relay), "the failed event no longer blocks the next one")
+	assert.Equal(t, []string{"a:task.updated"}, rec.published())
+
+	pending := store.pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, entity.EventTaskCreated, pending[0].Event.Type)
+	assert.Equal(t, entity.OutboxFailed, pending[0].Status)
+	assert.Equal(t, 2, pending[0].Attempts)
+	assert.Equal(t, "broker unavailable", pending[0].LastError)
+}
+
+func TestRelay_lease(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", entity.EventTaskCreated)
+	store.add("a", entity.EventTaskUpdated)
+	store.add("b", entity.EventTaskCreated)
+
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	rec := &recorder{}
+	relay := NewRelay(store, clk, config.OutboxConfig{Lease: time.Minute}, logger.New("error"), rec)
+
+	// Первое событие задачи a держит другая реплика.
+	leased, err := store.AcquireMessages(context.Background(), clk.Now(), "other", time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, leased, 1)
+
+	assert.Equal(t, 1, drain(t, relay))
+	assert.Equal(t, []string{"b:task.created"}, rec.published(), "task a waits for the lease")
+
+	// Другая реплика упала: ее аренда истекает, и сообщение забирают.
+	clk.Advance(time.Minute)
+	assert.Equal(t, 2, drain(t, relay))
+	assert.Equal(t, []string{"b:task.created", "a:task.created", "a:task.updated"}, rec.published())
+	assert.Empty(t, store.pending())
+}
+
+func TestRelay_atLeastOnceAcrossPublishers(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", entity.EventTaskCreated)
+
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	first := &recorder{}
+	second := &recorder{failOn: map[string]int{"a": 1}}
+	relay := NewRelay(store, clk, config.OutboxConfig{RetryDelay: time.Minute}, logger.New("error"), first, second)
+
+	drain(t, relay)
+	assert.Len(t, store.pending(), 1)
+
+	clk.Advance(time.Minute)
+	drain(t, relay)
+	assert.Empty(t, store.pending())
+	assert.Equal(t, []string{"a:task.created", "a:task.created"}, first.published())
+	assert.Equal(t, []string{"a:task.created"}, second.published())
+}
+
+func TestRelay_Run(t *testing.T) {
+	store := &memoryStore{}
+	for i := 0; i < 5; i++ {
+		store.add("a", entity.EventTaskUpdated)
+	}
+
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	rec := &recorder{}
+	relay := NewRelay(store, clk, config.OutboxConfig{Interval: time.Second, BatchSize: 2}, logger.New("error"), rec)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	// Проходы следуют друг за другом, пока публикуют, затем ретранслятор ждет.
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, rec.published(), 5)
+	assert.Empty(t, store.pending())
+
+	store.add("b", entity.EventTaskCreated)
+	clk.Advance(time.Second - time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, rec.published(), 5, "the next pass waits for the interval")
+
+	clk.Advance(time.Millisecond)
+	require.Eventually(t, func() bool { return len(rec.published()) == 6 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,179 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/webhook"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+)
+
+const (
+	ChannelLog     = "log"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+
+	eventReminderDue = "reminder.due"
+)
+
+// Notifier доставляет напоминание, которому пора, по одному каналу.
+// Возвращенная ошибка заставляет планировщик повторить напоминание позже.
+type Notifier interface {
+	Notify(ctx context.Context, reminder entity.Reminder) error
+}
+
+// NewNotifier создает уведомитель для каналов, перечисленных в cfg.
+func NewNotifier(cfg config.ReminderConfig, sender mail.Sender, l logger.Interface) (Notifier, error) {
+	if len(cfg.Channels) == 0 {
+		return LogNotifier{logger: l}, nil
+	}
+
+	var notifiers Multi
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case ChannelLog:
+			notifiers = append(notifiers, LogNotifier{logger: l})
+		case ChannelEmail:
+			if len(cfg.Email.To) == 0 {
+				return nil, errors.New("reminder: email channel needs reminder.email.to")
+			}
+			notifiers = append(notifiers, NewEmailNotifier(sender, cfg.Email.To))
+		case ChannelWebhook:
+			if cfg.Webhook.URL == "" {
+				return nil, errors.New("reminder: webhook channel needs reminder.webhook.url")
+			}
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.Webhook.URL, cfg.Webhook.Secret, nil))
+		default:
+			return nil, fmt.Errorf("reminder: unknown channel %q", channel)
+		}
+	}
+
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+
+	return notifiers, nil
+}
+
+// Multi отправляет напоминание через каждый уведомитель. Пробуются все, и
+// напоминание повторяется, если хоть один ошибся, поэтому каналы могут
+// получить его больше одного раза.
+type Multi []Notifier
+
+// Notify -.
+func (m Multi) Notify(ctx context.Context, reminder entity.Reminder) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, reminder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LogNotifier пишет напоминания в лог приложения.
+type LogNotifier struct {
+	logger logger.Interface
+}
+
+// NewLogNotifier -.
+func NewLogNotifier(l logger.Interface) LogNotifier {
+	return LogNotifier{logger: l}
+}
+
+// Notify -.
+func (n LogNotifier) Notify(ctx context.Context, reminder entity.Reminder) error {
+	n.logger.Info("reminder: task %s %q is due at %s", reminder.TaskID, reminder.Title, reminder.RemindAt.Format(time.RFC3339))
+	return nil
+}
+
+// EmailNotifier отправляет напоминания по почте на заданный список адресов.
+type EmailNotifier struct {
+	sender mail.Sender
+	to     []string
+}
+
+// NewEmailNotifier -.
+func NewEmailNotifier(sender mail.Sender, to []string) EmailNotifier {
+	return EmailNotifier{sender: sender, to: to}
+}
+
+// Notify -.
+func (n EmailNotifier) Notify(ctx context.Context, reminder entity.Reminder) error {
+	return n.sender.Send(ctx, mail.Message{
+		To:      n.to,
+		Subject: "Напоминание: " + reminder.Title,
+		Text: fmt.Sprintf("Напоминание о задаче «%s» на %s.\n",
+			reminder.Title, reminder.RemindAt.UTC().Format("2006-01-02 15:04 MST")),
+	})
+}
+
+// WebhookNotifier отправляет напоминания в JSON, подписанном так же, как
+// вебхуки задач.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier -.
+func NewWebhookNotifier(url, secret string, client *http.Client) WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return WebhookNotifier{url: url, secret: secret, client: client}
+}
+
+type reminderPayload struct {
+	Event    string    `json:"event"`
+	TaskID   string    `json:"taskId"`
+	Title    string    `json:"title"`
+	RemindAt time.Time `json:"remindAt"`
+}
+
+// Notify -.
+func (n WebhookNotifier) Notify(ctx context.Context, reminder entity.Reminder) error {
+	body, err := json.Marshal(reminderPayload{
+		Event:    eventReminderDue,
+		TaskID:   reminder.TaskID,
+		Title:    reminder.Title,
+		RemindAt: reminder.RemindAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, eventReminderDue)
+	if n.secret != "" {
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("reminder webhook: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
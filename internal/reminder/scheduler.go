@@ -0,0 +1,146 @@
+package reminder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store выдает в аренду напоминания, которым пора. Аренда позволяет запускать
+// планировщик в каждой реплике: напоминание одновременно держит один
+// владелец, а аренда упавшей реплики истекает, и напоминание забирает другая.
+type Store interface {
+	AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error)
+	CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error
+	RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error
+	FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error
+}
+
+// Scheduler просыпается каждые interval и рассылает напоминания, которым пора.
+type Scheduler struct {
+	store    Store
+	notifier Notifier
+	clock    clock.Clock
+	logger   logger.Interface
+	owner    string
+
+	interval    time.Duration
+	lease       time.Duration
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewScheduler -.
+func NewScheduler(store Store, notifier Notifier, c clock.Clock, cfg config.ReminderConfig, l logger.Interface) *Scheduler {
+	s := &Scheduler{
+		store:       store,
+		notifier:    notifier,
+		clock:       c,
+		logger:      l,
+		owner:       ownerID(),
+		interval:    cfg.Interval,
+		lease:       cfg.Lease,
+		maxAttempts: cfg.MaxAttempts,
+		retryDelay:  cfg.RetryDelay,
+	}
+
+	if s.interval <= 0 {
+		s.interval = 30 * time.Second
+	}
+	if s.lease <= 0 {
+		s.lease = time.Minute
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = 3
+	}
+	if s.retryDelay <= 0 {
+		s.retryDelay = time.Minute
+	}
+
+	return s
+}
+
+// Run рассылает напоминания, которым пора, пока ctx не отменен.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		if _, err := s.Tick(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error(fmt.Errorf("reminder: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(s.interval):
+		}
+	}
+}
+
+// Tick рассылает все напоминания, которым пора сейчас, и возвращает, сколько
+// отправлено.
+func (s *Scheduler) Tick(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		reminder, err := s.store.AcquireDueReminder(ctx, s.clock.Now(), s.owner, s.lease)
+		if err != nil {
+			return sent, fmt.Errorf("acquire due reminder: %w", err)
+		}
+		if reminder == nil {
+			return sent, nil
+		}
+
+		if s.dispatch(ctx, *reminder) {
+			sent++
+		}
+	}
+
+	return sent, ctx.Err()
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, reminder entity.Reminder) bool {
+	notifyCtx, cancel := context.WithTimeout(ctx, s.lease)
+	err := s.notifier.Notify(notifyCtx, reminder)
+	cancel()
+
+	if err == nil {
+		if err := s.store.CompleteReminder(ctx, reminder.ID, s.owner, s.clock.Now()); err != nil {
+			s.logger.Error(fmt.Errorf("reminder: complete %s: %w", reminder.ID.Hex(), err))
+		}
+		return true
+	}
+
+	if reminder.Attempts+1 >= s.maxAttempts {
+		s.logger.Warn("reminder: %s for task %s failed after %d attempts: %s", reminder.ID.Hex(), reminder.TaskID, reminder.Attempts+1, err)
+		if err := s.store.FailReminder(ctx, reminder.ID, s.owner, err.Error()); err != nil {
+			s.logger.Error(fmt.Errorf("reminder: fail %s: %w", reminder.ID.Hex(), err))
+		}
+		return false
+	}
+
+	retryAt := s.clock.Now().Add(s.retryDelay)
+	if err := s.store.RetryReminder(ctx, reminder.ID, s.owner, retryAt, err.Error()); err != nil {
+		s.logger.Error(fmt.Errorf("reminder: retry %s: %w", reminder.ID.Hex(), err))
+	}
+
+	return false
+}
+
+func ownerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "todo"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,399 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/mail"
+	"github.com/yervsil/toDo-microservice/pkg/mail/mailtest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStore повторяет аренду репозитория напоминаний Mongo.
+type memoryStore struct {
+	mu        sync.Mutex
+	reminders map[primitive.ObjectID]*entity.Reminder
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{reminders: map[primitive.ObjectID]*entity.Reminder{}}
+}
+
+func (s *memoryStore) ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := map[time.Time]bool{}
+	for _, t := range times {
+		wanted[t.UTC()] = true
+	}
+	for id, r := range s.reminders {
+		if r.TaskID == taskId && r.Status == entity.ReminderPending && !wanted[r.RemindAt] {
+			delete(s.reminders, id)
+		}
+	}
+	for at := range wanted {
+		found := false
+		for _, r := range s.reminders {
+			if r.TaskID == taskId && r.RemindAt.Equal(at) {
+				r.Title, found = title, true
+			}
+		}
+		if !found {
+			id := primitive.NewObjectID()
+			s.reminders[id] = &entity.Reminder{ID: id, TaskID: taskId, Title: title, RemindAt: at, Status: entity.ReminderPending}
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) CancelReminders(ctx context.Context, taskId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, r := range s.reminders {
+		if r.TaskID == taskId && r.Status == entity.ReminderPending {
+			delete(s.reminders, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*entity.Reminder
+	for _, r := range s.reminders {
+		if r.Status == entity.ReminderPending && !r.RemindAt.After(now) && !r.LeaseUntil.After(now) {
+			due = append(due, r)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].RemindAt.Before(due[j].RemindAt) })
+
+	due[0].LeaseOwner, due[0].LeaseUntil = owner, now.Add(lease)
+	r := *due[0]
+	return &r, nil
+}
+
+func (s *memoryStore) leased(id primitive.ObjectID, owner string) (*entity.Reminder, error) {
+	r, ok := s.reminders[id]
+	if !ok || r.LeaseOwner != owner {
+		return nil, errors.New("reminder lease lost")
+	}
+	return r, nil
+}
+
+func (s *memoryStore) CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.leased(reminderId, owner)
+	if err != nil {
+		return err
+	}
+	r.Status, r.SentAt, r.LeaseOwner, r.LeaseUntil = entity.ReminderSent, sentAt, "", time.Time{}
+	return nil
+}
+
+func (s *memoryStore) RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.leased(reminderId, owner)
+	if err != nil {
+		return err
+	}
+	r.Attempts++
+	r.LastError, r.LeaseOwner, r.LeaseUntil = reason, "", retryAt
+	return nil
+}
+
+func (s *memoryStore) FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.leased(reminderId, owner)
+	if err != nil {
+		return err
+	}
+	r.Attempts++
+	r.Status, r.LastError, r.LeaseOwner, r.LeaseUntil = entity.ReminderFailed, reason, "", time.Time{}
+	return nil
+}
+
+func (s *memoryStore) byTitle(title string) entity.Reminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reminders {
+		if r.Title == title {
+			return *r
+		}
+	}
+	return entity.Reminder{}
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	got   []string
+	fails map[string]int
+}
+
+func (r *recorder) Notify(ctx context.Context, reminder entity.Reminder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fails[reminder.Title] > 0 {
+		r.fails[reminder.Title]--
+		return errors.New("channel unavailable")
+	}
+	r.got = append(r.got, reminder.Title)
+	return nil
+}
+
+func (r *recorder) titles() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.got...)
+}
+
+var start = time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC)
+
+func testConfig() config.ReminderConfig {
+	return config.ReminderConfig{
+		Interval:    time.Minute,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		RetryDelay:  5 * time.Minute,
+	}
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	store := newMemoryStore()
+	syncer := NewSync(store)
+	require.NoError(t, syncer.Publish(context.Background(), entity.Event{
+		Type:   entity.EventTaskCreated,
+		TaskID: "1",
+		Task:   &entity.Task{Title: "Позвонить", RemindAt: []time.Time{start.Add(10 * time.Minute), start.Add(time.Hour)}},
+	}))
+
+	clk := clock.NewFake(start)
+	rec := &recorder{}
+	s := NewScheduler(store, rec, clk, testConfig(), logger.New("error"))
+
+	n, err := s.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	clk.Advance(10 * time.Minute)
+	n, err = s.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	clk.Advance(time.Hour)
+	n, err = s.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = s.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "sent reminders are not dispatched again")
+	assert.Equal(t, []string{"Позвонить", "Позвонить"}, rec.titles())
+}
+
+func TestScheduler_retriesAndGivesUp(t *testing.T) {
+	store := newMemoryStore()
+	require.NoError(t, store.ReplaceReminders(context.Background(), "1", "flaky", []time.Time{start}))
+	require.NoError(t, store.ReplaceReminders(context.Background(), "2", "broken", []time.Time{start.Add(time.Second)}))
+
+	clk := clock.NewFake(start.Add(time.Second))
+	rec := &recorder{fails: map[string]int{"flaky": 2, "broken": 10}}
+	s := NewScheduler(store, rec, clk, testConfig(), logger.New("error"))
+
+	_, err := s.Tick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.byTitle("flaky").Attempts)
+	assert.Equal(t, "channel unavailable", store.byTitle("flaky").LastError)
+
+	clk.Advance(4 * time.Minute)
+	n, _ := s.Tick(context.Background())
+	assert.Equal(t, 0, n, "retry waits for the retry delay")
+
+	clk.Advance(time.Minute)
+	_, err = s.Tick(context.Background())
+	require.NoError(t, err)
+
+	clk.Advance(5 * time.Minute)
+	_, err = s.Tick(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.ReminderSent, store.byTitle("flaky").Status)
+	assert.Equal(t, 2, store.byTitle("flaky").Attempts)
+	assert.Equal(t, entity.ReminderFailed, store.byTitle("broken").Status)
+	assert.Equal(t, 3, store.byTitle("broken").Attempts)
+	assert.Equal(t, []string{"flaky"}, rec.titles())
+}
+
+func TestScheduler_defaultMaxAttempts(t *testing.T) {
+	store := newMemoryStore()
+	require.NoError(t, store.ReplaceReminders(context.Background(), "1", "broken", []time.Time{start}))
+
+	cfg := testConfig()
+	cfg.MaxAttempts = 0
+	clk := clock.NewFake(start)
+	rec := &recorder{fails: map[string]int{"broken": 10}}
+	s := NewScheduler(store, rec, clk, cfg, logger.New("error"))
+
+	for i := 1; i <= 3; i++ {
+		_, err := s.Tick(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, i, store.byTitle("broken").Attempts)
+		clk.Advance(cfg.RetryDelay)
+	}
+
+	assert.Equal(t, entity.ReminderFailed, store.byTitle("broken").Status)
+}
+
+func TestScheduler_leasePreventsDoubleDispatch(t *testing.T) {
+	store := newMemoryStore()
+	for _, title := range []string{"a", "b", "c", "d", "e", "f"} {
+		require.NoError(t, store.ReplaceReminders(context.Background(), title, title, []time.Time{start}))
+	}
+
+	clk := clock.NewFake(start)
+	rec := &recorder{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		s := NewScheduler(store, rec, clk, testConfig(), logger.New("error"))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.Tick(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	got := rec.titles()
+	sort.Strings(got)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, got)
+}
+
+func TestScheduler_expiredLeaseIsTakenOver(t *testing.T) {
+	store := newMemoryStore()
+	require.NoError(t, store.ReplaceReminders(context.Background(), "1", "orphan", []time.Time{start}))
+
+	clk := clock.NewFake(start)
+	crashed, err := store.AcquireDueReminder(context.Background(), clk.Now(), "crashed-replica", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, crashed)
+
+	rec := &recorder{}
+	s := NewScheduler(store, rec, clk, testConfig(), logger.New("error"))
+
+	n, _ := s.Tick(context.Background())
+	assert.Equal(t, 0, n)
+
+	clk.Advance(time.Minute)
+	n, _ = s.Tick(context.Background())
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"orphan"}, rec.titles())
+}
+
+func TestScheduler_RunWithFakeClock(t *testing.T) {
+	store := newMemoryStore()
+	require.NoError(t, store.ReplaceReminders(context.Background(), "1", "later", []time.Time{start.Add(90 * time.Second)}))
+
+	clk := clock.NewFake(start)
+	rec := &recorder{}
+	s := NewScheduler(store, rec, clk, testConfig(), logger.New("error"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for i := 0; i < 2; i++ {
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Advance(time.Minute)
+	}
+
+	require.Eventually(t, func() bool { return len(rec.titles()) == 1 }, time.Second, time.Millisecond)
+}
+
+func TestSync(t *testing.T) {
+	store := newMemoryStore()
+	syncer := NewSync(store)
+	ctx := context.Background()
+
+	require.NoError(t, syncer.Publish(ctx, entity.Event{
+		Type: entity.EventTaskCreated, TaskID: "1",
+		Task: &entity.Task{Title: "old", RemindAt: []time.Time{start, start.Add(time.Hour)}},
+	}))
+	require.NoError(t, store.CompleteReminder(ctx, mustAcquire(t, store, start), "test", start))
+
+	require.NoError(t, syncer.Publish(ctx, entity.Event{
+		Type: entity.EventTaskUpdated, TaskID: "1",
+		Task: &entity.Task{Title: "new", RemindAt: []time.Time{start, start.Add(2 * time.Hour)}},
+	}))
+
+	var statuses []string
+	for _, r := range store.reminders {
+		statuses = append(statuses, r.RemindAt.Sub(start).String()+" "+r.Status+" "+r.Title)
+	}
+	sort.Strings(statuses)
+	assert.Equal(t, []string{"0s sent new", "2h0m0s pending new"}, statuses)
+
+	require.NoError(t, syncer.Publish(ctx, entity.Event{Type: entity.EventTaskDone, TaskID: "1"}))
+	assert.Len(t, store.reminders, 1)
+}
+
+func mustAcquire(t *testing.T, store *memoryStore, now time.Time) primitive.ObjectID {
+	r, err := store.AcquireDueReminder(context.Background(), now, "test", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	return r.ID
+}
+
+func TestEmailNotifier(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	sender := mail.NewSMTPClient(config.SMTPConfig{Host: srv.Host(), Port: srv.Port(), From: "todo@example.com"})
+	notifier, err := NewNotifier(config.ReminderConfig{
+		Channels: []string{ChannelEmail},
+		Email:    config.ReminderEmailConfig{To: []string{"anna@example.com"}},
+	}, sender, logger.New("error"))
+	require.NoError(t, err)
+
+	err = notifier.Notify(context.Background(), entity.Reminder{TaskID: "1", Title: "Купить книгу", RemindAt: start})
+	require.NoError(t, err)
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"anna@example.com"}, messages[0].To)
+	assert.True(t, strings.Contains(string(messages[0].Data), "Subject: =?utf-8?q?"))
+}
+
+func TestNewNotifier_validatesChannels(t *testing.T) {
+	_, err := NewNotifier(config.ReminderConfig{Channels: []string{"sms"}}, nil, logger.New("error"))
+	assert.EqualError(t, err, `reminder: unknown channel "sms"`)
+
+	_, err = NewNotifier(config.ReminderConfig{Channels: []string{ChannelWebhook}}, nil, logger.New("error"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// TaskReminders хранит напоминания, полученные из задач.
+type TaskReminders interface {
+	ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error
+	CancelReminders(ctx context.Context, taskId string) error
+}
+
+// Sync — издатель outbox, который держит напоминания в соответствии с полем
+// remindAt задач. Повтор события безвреден: отправленные напоминания
+// сохраняются, а ожидающие только заменяются.
+type Sync struct {
+	store TaskReminders
+}
+
+// NewSync -.
+func NewSync(store TaskReminders) *Sync {
+	return &Sync{store: store}
+}
+
+// Publish -.
+func (s *Sync) Publish(ctx context.Context, event entity.Event) error {
+	switch event.Type {
+	case entity.EventTaskCreated, entity.EventTaskUpdated:
+		if event.Task == nil {
+			return nil
+		}
+		return s.store.ReplaceReminders(ctx, event.TaskID, event.Task.Title, event.Task.RemindAt)
+	case entity.EventTaskDeleted, entity.EventTaskDone:
+		return s.store.CancelReminders(ctx, event.TaskID)
+	}
+
+	return nil
+}
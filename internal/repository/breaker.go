@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/breaker"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrUnavailable возвращается, когда MongoDB недоступна: вызов завершился
+// сетевой ошибкой или ошибкой выбора сервера, либо открыт предохранитель.
+// Ошибка оборачивает причину.
+var ErrUnavailable = errors.New("database unavailable")
+
+// WithBreaker возвращает копию repo, вызовы MongoDB которой идут через b:
+// пока b открыт, они сразу завершаются ErrUnavailable, а вызовы, не
+// достучавшиеся до базы, открывают его. С nil b ошибки вызовов только
+// распознаются как ErrUnavailable. Репозиторий задач не меняется: его
+// вызовы идут через b, если он собран с декоратором Guarded(b).
+func WithBreaker(repo *Repository, b *breaker.Breaker) *Repository {
+	g := guard{b}
+
+	return &Repository{
+		Task:     repo.Task,
+		Search:   repo.Search,
+		Outbox:   guardedOutbox{repo.Outbox, g},
+		Webhook:  guardedWebhook{repo.Webhook, g},
+		Reminder: guardedReminder{repo.Reminder, g},
+		Digest:   guardedDigest{repo.Digest, g},
+	}
+}
+
+// Guarded пропускает вызовы репозитория задач через b, как WithBreaker
+// остальные репозитории. В TaskDecorators он стоит сразу под кэшем: попадание
+// в кэш не доходит до базы, а повторы вызова b видит одним вызовом.
+func Guarded(b *breaker.Breaker) TaskDecorator {
+	g := guard{b}
+
+	return func(next Task) Task {
+		return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+			return g.do(ctx, func() error { return call(ctx) })
+		}}
+	}
+}
+
+// unavailable сообщает, значит ли err, что база недоступна.
+func unavailable(ctx context.Context, err error) bool {
+	if err == nil || cutShort(ctx, err) {
+		return false
+	}
+
+	return mongo.IsNetworkError(err) || mongo.IsTimeout(err) || errors.Is(err, mongo.ErrClientDisconnected)
+}
+
+// cutShort сообщает, что вызов прерван не базой: завершен контекст
+// вызывающего или истек срок операции (CanceledError из WithTimeouts, пока
+// контекст запроса еще жив). Медленный запрос не значит, что база недоступна.
+func cutShort(ctx context.Context, err error) bool {
+	var canceledErr *CanceledError
+	return err != nil && (ctx.Err() != nil || errors.As(err, &canceledErr))
+}
+
+type guard struct {
+	breaker *breaker.Breaker
+}
+
+func (g guard) do(ctx context.Context, fn func() error) error {
+	_, err := call(g, ctx, func() (struct{}, error) { return struct{}{}, fn() })
+	return err
+}
+
+func call[T any](g guard, ctx context.Context, fn func() (T, error)) (T, error) {
+	if g.breaker != nil {
+		if err := g.breaker.Allow(); err != nil {
+			var zero T
+			return zero, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+	}
+
+	v, err := fn()
+	failed := unavailable(ctx, err)
+
+	if g.breaker != nil {
+		if cutShort(ctx, err) {
+			g.breaker.Ignore()
+		} else {
+			g.breaker.Record(failed)
+		}
+	}
+
+	if failed {
+		return v, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return v, err
+}
+
+type guardedOutbox struct {
+	next Outbox
+	g    guard
+}
+
+func (r guardedOutbox) AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error) {
+	return call(r.g, ctx, func() ([]entity.OutboxMessage, error) { return r.next.AcquireMessages(ctx, now, owner, lease, limit) })
+}
+
+func (r guardedOutbox) DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error {
+	return r.g.do(ctx, func() error { return r.next.DeleteMessage(ctx, messageId, owner) })
+}
+
+func (r guardedOutbox) RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return r.g.do(ctx, func() error { return r.next.RetryMessage(ctx, messageId, owner, retryAt, reason) })
+}
+
+func (r guardedOutbox) FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error {
+	return r.g.do(ctx, func() error { return r.next.FailMessage(ctx, messageId, owner, reason) })
+}
+
+type guardedWebhook struct {
+	next Webhook
+	g    guard
+}
+
+func (r guardedWebhook) CreateWebhook(ctx context.Context, webhook entity.Webhook) (primitive.ObjectID, error) {
+	return call(r.g, ctx, func() (primitive.ObjectID, error) { return r.next.CreateWebhook(ctx, webhook) })
+}
+
+func (r guardedWebhook) GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error) {
+	return call(r.g, ctx, func() (entity.Webhook, error) { return r.next.GetWebhook(ctx, webhookId) })
+}
+
+func (r guardedWebhook) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	return call(r.g, ctx, func() ([]entity.Webhook, error) { return r.next.GetWebhooks(ctx) })
+}
+
+func (r guardedWebhook) GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error) {
+	return call(r.g, ctx, func() ([]entity.Webhook, error) { return r.next.GetWebhooksByEvent(ctx, event) })
+}
+
+func (r guardedWebhook) DeleteWebhook(ctx context.Context, webhookId primitive.ObjectID) error {
+	return r.g.do(ctx, func() error { return r.next.DeleteWebhook(ctx, webhookId) })
+}
+
+func (r guardedWebhook) CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error) {
+	return call(r.g, ctx, func() (primitive.ObjectID, error) { return r.next.CreateDelivery(ctx, delivery) })
+}
+
+func (r guardedWebhook) GetDelivery(ctx context.Context, deliveryId primitive.ObjectID) (entity.WebhookDelivery, error) {
+	return call(r.g, ctx, func() (entity.WebhookDelivery, error) { return r.next.GetDelivery(ctx, deliveryId) })
+}
+
+func (r guardedWebhook) GetDeliveries(ctx context.Context, webhookId primitive.ObjectID) ([]entity.WebhookDelivery, error) {
+	return call(r.g, ctx, func() ([]entity.WebhookDelivery, error) { return r.next.GetDeliveries(ctx, webhookId) })
+}
+
+func (r guardedWebhook) AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error) {
+	return call(r.g, ctx, func() (*entity.WebhookDelivery, error) { return r.next.AcquireDelivery(ctx, now, owner, lease) })
+}
+
+func (r guardedWebhook) AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error {
+	return r.g.do(ctx, func() error { return r.next.AddDeliveryAttempt(ctx, deliveryId, owner, attempt, status, retryAt) })
+}
+
+func (r guardedWebhook) ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error {
+	return r.g.do(ctx, func() error { return r.next.ResetDelivery(ctx, deliveryId) })
+}
+
+type guardedReminder struct {
+	next Reminder
+	g    guard
+}
+
+func (r guardedReminder) ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error {
+	return r.g.do(ctx, func() error { return r.next.ReplaceReminders(ctx, taskId, title, times) })
+}
+
+func (r guardedReminder) CancelReminders(ctx context.Context, taskId string) error {
+	return r.g.do(ctx, func() error { return r.next.CancelReminders(ctx, taskId) })
+}
+
+func (r guardedReminder) AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error) {
+	return call(r.g, ctx, func() (*entity.Reminder, error) { return r.next.AcquireDueReminder(ctx, now, owner, lease) })
+}
+
+func (r guardedReminder) CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error {
+	return r.g.do(ctx, func() error { return r.next.CompleteReminder(ctx, reminderId, owner, sentAt) })
+}
+
+func (r guardedReminder) RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return r.g.do(ctx, func() error { return r.next.RetryReminder(ctx, reminderId, owner, retryAt, reason) })
+}
+
+func (r guardedReminder) FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error {
+	return r.g.do(ctx, func() error { return r.next.FailReminder(ctx, reminderId, owner, reason) })
+}
+
+type guardedDigest struct {
+	next Digest
+	g    guard
+}
+
+func (r guardedDigest) CreateSubscription(ctx context.Context, subscription entity.DigestSubscription) (primitive.ObjectID, error) {
+	return call(r.g, ctx, func() (primitive.ObjectID, error) { return r.next.CreateSubscription(ctx, subscription) })
+}
+
+func (r guardedDigest) GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return call(r.g, ctx, func() ([]entity.DigestSubscription, error) { return r.next.GetSubscriptions(ctx) })
+}
+
+func (r guardedDigest) GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return call(r.g, ctx, func() ([]entity.DigestSubscription, error) { return r.next.GetEnabledSubscriptions(ctx) })
+}
+
+func (r guardedDigest) UpdateSubscription(ctx context.Context, subscription entity.DigestSubscription, subscriptionId primitive.ObjectID) error {
+	return r.g.do(ctx, func() error { return r.next.UpdateSubscription(ctx, subscription, subscriptionId) })
+}
+
+func (r guardedDigest) DeleteSubscription(ctx context.Context, subscriptionId primitive.ObjectID) error {
+	return r.g.do(ctx, func() error { return r.next.DeleteSubscription(ctx, subscriptionId) })
+}
+
+func (r guardedDigest) ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error) {
+	return call(r.g, ctx, func() (bool, error) { return r.next.ClaimDigest(ctx, subscriptionId, day, now, owner, lease) })
+}
+
+func (r guardedDigest) CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error {
+	return r.g.do(ctx, func() error { return r.next.CompleteDigest(ctx, subscriptionId, owner, day) })
+}
+
+func (r guardedDigest) ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error {
+	return r.g.do(ctx, func() error { return r.next.ReleaseDigest(ctx, subscriptionId, owner) })
+}
+
+func (r guardedDigest) GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error) {
+	return call(r.g, ctx, func() ([]entity.Task, error) { return r.next.GetTasksActiveUntil(ctx, until, loc) })
+}
+
+func (r guardedDigest) GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error) {
+	return call(r.g, ctx, func() ([]entity.Task, error) { return r.next.GetTasksDoneBetween(ctx, from, to) })
+}
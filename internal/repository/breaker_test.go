@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/breaker"
+	"github.com/yervsil/toDo-microservice/pkg/cache"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+// failingTasks возвращает err из CountTasks и считает вызовы.
+type failingTasks struct {
+	Task
+	err   error
+	calls int
+}
+
+func (r *failingTasks) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	r.calls++
+	return entity.TaskCounts{}, r.err
+}
+
+func TestGuarded(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	tasks := &failingTasks{err: context.DeadlineExceeded}
+	repo := DecorateTask(tasks, Guarded(breaker.New(2, time.Second, breaker.WithClock(clk))))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.CountTasks(ctx, time.Now())
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	// Предохранитель открыт: база не вызывается.
+	_, err := repo.CountTasks(ctx, time.Now())
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, 2, tasks.calls)
+
+	// Другие ошибки показывают, что база отвечает, и закрывают предохранитель.
+	clk.Advance(time.Second)
+	tasks.err = errors.New("no record found")
+	_, err = repo.CountTasks(ctx, time.Now())
+	assert.EqualError(t, err, "no record found")
+	assert.Equal(t, 3, tasks.calls)
+
+	tasks.err = nil
+	_, err = repo.CountTasks(ctx, time.Now())
+	require.NoError(t, err)
+}
+
+func TestGuarded_callerTimeout(t *testing.T) {
+	tasks := &failingTasks{}
+	repo := DecorateTask(tasks, Guarded(breaker.New(1, time.Minute)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	tasks.err = ctx.Err()
+
+	// Время кончилось у запроса, а не у базы: ошибка остается как есть, а
+	// предохранитель закрыт.
+	for i := 0; i < 3; i++ {
+		_, err := repo.CountTasks(ctx, time.Now())
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}
+	assert.Equal(t, 3, tasks.calls)
+}
+
+func TestGuarded_operationTimeout(t *testing.T) {
+	b := breaker.New(1, time.Minute)
+	// WithTimeouts прерывает вызов, пока запрос еще жив.
+	repo := decorateTask(blockingTasks{}, []TaskDecorator{Guarded(b), WithTimeouts(time.Millisecond, config.RepositoryTimeoutsConfig{})})
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.GetTasks(context.Background(), "active", time.UTC)
+		var canceledErr *CanceledError
+		require.ErrorAs(t, err, &canceledErr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrUnavailable)
+	}
+	assert.Equal(t, breaker.Closed, b.State(), "a slow query is not an outage")
+}
+
+func TestGuarded_nil(t *testing.T) {
+	tasks := &failingTasks{err: context.DeadlineExceeded}
+	repo := DecorateTask(tasks, Guarded(nil))
+
+	for i := 0; i < 10; i++ {
+		_, err := repo.CountTasks(context.Background(), time.Now())
+		assert.ErrorIs(t, err, ErrUnavailable)
+	}
+	assert.Equal(t, 10, tasks.calls)
+}
+
+func TestGuarded_cacheHit(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	tasks := &failingTasks{err: context.DeadlineExceeded}
+	b := breaker.New(1, time.Minute, breaker.WithClock(clk))
+	store := cache.NewLRU(10, clk)
+	require.NoError(t, store.Set(context.Background(), "tasks|active|UTC", []byte("[]"), time.Minute))
+	repo := DecorateTask(tasks, Cached(store, time.Minute), Guarded(b))
+
+	_, err := repo.CountTasks(context.Background(), time.Now())
+	assert.ErrorIs(t, err, ErrUnavailable)
+	require.Equal(t, breaker.Open, b.State())
+
+	// Попадание в кэш не идет в базу, и открытый предохранитель ему не мешает.
+	got, err := repo.GetTasks(context.Background(), "active", time.UTC)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+// failingOutbox возвращает err из AcquireMessages и считает вызовы.
+type failingOutbox struct {
+	Outbox
+	err   error
+	calls int
+}
+
+func (r *failingOutbox) AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error) {
+	r.calls++
+	return nil, r.err
+}
+
+func TestWithBreaker(t *testing.T) {
+	tasks := &failingTasks{}
+	outbox := &failingOutbox{err: context.DeadlineExceeded}
+	b := breaker.New(1, time.Minute)
+	repo := WithBreaker(&Repository{Task: tasks, Outbox: outbox}, b)
+
+	_, err := repo.AcquireMessages(context.Background(), time.Now(), "relay-1", time.Minute, 10)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	_, err = repo.AcquireMessages(context.Background(), time.Now(), "relay-1", time.Minute, 10)
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, 1, outbox.calls)
+
+	// Задачи идут через b, только если собраны с Guarded(b).
+	assert.Same(t, tasks, repo.Task)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+// CanceledError сообщает, что операция прервана: контекст вызова отменен
+// (клиент ушел) или истек его срок (запроса или операции, см.
+// WithTimeouts). Err — ошибка контекста, поэтому errors.Is(err,
+// context.Canceled) и errors.Is(err, context.DeadlineExceeded) работают.
+// Cause — исходная ошибка драйвера, если она другая.
+type CanceledError struct {
+	Op    string
+	Err   error
+	Cause error
+}
+
+func (e *CanceledError) Error() string {
+	if e.Cause == nil {
+		return e.Op + ": " + e.Err.Error()
+	}
+
+	return e.Op + ": " + e.Err.Error() + ": " + e.Cause.Error()
+}
+
+func (e *CanceledError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Err, e.Cause}
+}
+
+// canceled возвращает CanceledError вместо ошибки err операции op, если
+// контекст ctx завершен: тогда err вызвана им, а не базой.
+func canceled(ctx context.Context, op string, err error) error {
+	var already *CanceledError
+	if err == nil || ctx.Err() == nil || errors.As(err, &already) {
+		return err
+	}
+
+	canceledErr := &CanceledError{Op: op, Err: ctx.Err()}
+	if err != canceledErr.Err {
+		canceledErr.Cause = err
+	}
+
+	return canceledErr
+}
+
+// surfaceCancellation переводит ошибки вызовов с завершенным контекстом в
+// CanceledError. Конструкторы репозиториев ставят его ближе всех к базе,
+// чтобы его видели все декораторы.
+func surfaceCancellation(next Task) Task {
+	return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+		return canceled(ctx, op, call(ctx))
+	}}
+}
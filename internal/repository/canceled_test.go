@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSurfaceCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db := &scriptedTasks{errs: []error{context.Canceled}}
+	err := surfaceCancellation(db).DeleteTask(ctx, entity.TaskID{})
+
+	var canceledErr *CanceledError
+	require.ErrorAs(t, err, &canceledErr)
+	assert.Equal(t, "DeleteTask", canceledErr.Op)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, canceledErr.Cause, "the context error is not kept twice")
+	assert.EqualError(t, err, "DeleteTask: context canceled")
+
+	// Ошибка драйвера сохраняется рядом с ошибкой контекста.
+	driverErr := errors.New("connection reset")
+	db = &scriptedTasks{errs: []error{driverErr}}
+	err = surfaceCancellation(db).DeleteTask(ctx, entity.TaskID{})
+	require.ErrorAs(t, err, &canceledErr)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, driverErr)
+	assert.EqualError(t, err, "DeleteTask: context canceled: connection reset")
+
+	// Ошибки вызовов с живым контекстом сохраняются.
+	db = &scriptedTasks{errs: []error{errors.New("no record found")}}
+	err = surfaceCancellation(db).DeleteTask(context.Background(), entity.TaskID{})
+	assert.EqualError(t, err, "no record found")
+}
+
+// blockingTasks ждет в GetTasks и SearchTasks, пока не завершится его контекст.
+type blockingTasks struct {
+	Task
+}
+
+func (blockingTasks) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingTasks) SearchTasks(ctx context.Context, query string) ([]entity.Task, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestWithTimeouts_canceledError(t *testing.T) {
+	// Вызов прерывает таймаут декоратора, а не запрос.
+	repo := decorateTask(blockingTasks{}, []TaskDecorator{WithTimeouts(10*time.Millisecond, config.RepositoryTimeoutsConfig{})})
+
+	_, err := repo.GetTasks(context.Background(), "active", time.UTC)
+	var canceledErr *CanceledError
+	require.ErrorAs(t, err, &canceledErr)
+	assert.Equal(t, "GetTasks", canceledErr.Op)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestTaskRepository_canceled проверяет, что репозиторий задач MongoDB
+// выполняет каждую операцию с контекстом вызывающего: отмененный запрос не
+// должен менять задачи.
+func TestTaskRepository_canceled(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	task := entity.Task{Status: active, Title: "Купить книгу", ActiveAt: "2023-08-04"}
+	id := mustTaskID(primitive.NewObjectID().Hex())
+
+	calls := map[string]func(Task) error{
+		"CreateTask": func(r Task) error {
+			_, err := r.CreateTask(ctx, task)
+			return err
+		},
+		"UpdateTask":   func(r Task) error { return r.UpdateTask(ctx, task, id) },
+		"DeleteTask":   func(r Task) error { return r.DeleteTask(ctx, id) },
+		"StatusUpdate": func(r Task) error {
+			_, err := r.StatusUpdate(ctx, id)
+			return err
+		},
+		"GetTasks": func(r Task) error {
+			_, err := r.GetTasks(ctx, active, time.UTC)
+			return err
+		},
+	}
+	for op, call := range calls {
+		mt.Run(op, func(mt *mtest.T) {
+			// Ответы, с которыми операция завершилась бы успешно.
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+			)
+			repo := NewRepository(mt.DB, newTaskIDs(t, entity.TaskIDObjectID)).Task
+
+			err := call(repo)
+
+			var canceledErr *CanceledError
+			require.ErrorAs(t, err, &canceledErr)
+			assert.Equal(t, op, canceledErr.Op)
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+// TestTaskRepository_deadlineAbortsCall проверяет, что срок запроса прерывает
+// выполняющийся вызов MongoDB. Сервер принимает соединения и никогда не
+// отвечает, поэтому без контекста запроса вызов ждал бы таймаута выбора
+// сервера.
+func TestTaskRepository_deadlineAbortsCall(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://"+ln.Addr().String()+"/?directConnection=true").
+		SetServerSelectionTimeout(time.Minute))
+	require.NoError(t, err)
+	defer client.Disconnect(context.Background())
+
+	repo := NewRepository(client.Database("test"), newTaskIDs(t, entity.TaskIDObjectID)).Task
+	id := mustTaskID(primitive.NewObjectID().Hex())
+
+	for op, call := range map[string]func(ctx context.Context) error{
+		"UpdateTask": func(ctx context.Context) error {
+			return repo.UpdateTask(ctx, entity.Task{Status: active, Title: "Купить книгу", ActiveAt: "2023-08-04"}, id)
+		},
+		"DeleteTask": func(ctx context.Context) error { return repo.DeleteTask(ctx, id) },
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		done := make(chan error, 1)
+		go func() { done <- call(ctx) }()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-time.After(5 * time.Second):
+			cancel()
+			t.Fatalf("%s: the call was not aborted by the deadline", op)
+		}
+		cancel()
+
+		var canceledErr *CanceledError
+		require.ErrorAs(t, err, &canceledErr, op)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, op)
+	}
+}
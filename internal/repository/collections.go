@@ -1,5 +1,10 @@
 package repository
 
 const (
-	tasksCollection = "task"
-)
\ No newline at end of file
+	tasksCollection      = "task"
+	outboxCollection     = "outbox"
+	webhooksCollection   = "webhook"
+	deliveriesCollection = "webhook_delivery"
+	remindersCollection  = "reminder"
+	digestsCollection    = "digest_subscription"
+)
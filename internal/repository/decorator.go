@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/cache"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TaskDecorator добавляет поведение вокруг вызовов репозитория задач:
+// логирование, повторы, ограничение времени, кэш.
+type TaskDecorator func(Task) Task
+
+// DecorateTask оборачивает task декораторами. Первый из них внешний: его
+// вызов видит все остальные.
+func DecorateTask(task Task, decorators ...TaskDecorator) Task {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		task = decorators[i](task)
+	}
+
+	return task
+}
+
+// TaskDecorators возвращает декораторы, включенные в cfg и taskCache: кэш,
+// логирование, ограничение времени и повторы. Кэш внешний, чтобы попадание в
+// него не шло в базу. Время ограничивает вызов вместе с повторами, а лог
+// видит его целиком. Непустой guard (см. Guarded) ставится сразу под кэшем.
+func TaskDecorators(cfg config.RepositoryConfig, taskCache config.TaskCacheConfig, guard TaskDecorator) ([]TaskDecorator, error) {
+	var decorators []TaskDecorator
+	if taskCache.Enabled {
+		store, err := newCacheStore(taskCache)
+		if err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, Cached(store, taskCache.TTL))
+	}
+	if guard != nil {
+		decorators = append(decorators, guard)
+	}
+	if cfg.Log {
+		decorators = append(decorators, Logged(cfg.SlowThreshold))
+	}
+	if cfg.Timeout > 0 || cfg.Timeouts != (config.RepositoryTimeoutsConfig{}) {
+		decorators = append(decorators, WithTimeouts(cfg.Timeout, cfg.Timeouts))
+	}
+	if cfg.Retry.MaxAttempts > 1 {
+		decorators = append(decorators, Retrying(cfg.Retry))
+	}
+
+	return decorators, nil
+}
+
+// Cached кэширует списки задач в store на ttl, как NewCachedTaskRepository.
+func Cached(store cache.Store, ttl time.Duration) TaskDecorator {
+	return func(next Task) Task {
+		return NewCachedTaskRepository(next, store, ttl)
+	}
+}
+
+// Logged пишет в лог запроса каждый вызов с его длительностью: на уровне
+// debug, а вызовы дольше slow (если slow больше нуля) — на уровне warn.
+func Logged(slow time.Duration) TaskDecorator {
+	return func(next Task) Task {
+		return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+			start := time.Now()
+			err := call(ctx)
+			elapsed := time.Since(start)
+
+			log := logger.FromContext(ctx).Package("repository").With("op", op).With("duration", elapsed.String())
+			if err != nil {
+				log = log.With("error", err.Error())
+			}
+			if slow > 0 && elapsed > slow {
+				log.Warn("slow repository call")
+			} else {
+				log.Debug("repository call")
+			}
+
+			return err
+		}}
+	}
+}
+
+// WithTimeouts ограничивает каждый вызов временем его операции из ops, а
+// если оно не задано — временем timeout. Более ранний срок контекста
+// запроса остается в силе.
+func WithTimeouts(timeout time.Duration, ops config.RepositoryTimeoutsConfig) TaskDecorator {
+	limits := map[string]time.Duration{
+		"CreateTask":   ops.CreateTask,
+		"UpdateTask":   ops.UpdateTask,
+		"DeleteTask":   ops.DeleteTask,
+		"StatusUpdate": ops.StatusUpdate,
+		"GetTasks":     ops.GetTasks,
+		"CountTasks":   ops.CountTasks,
+		"SearchTasks":  ops.SearchTasks,
+	}
+
+	return func(next Task) Task {
+		return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+			limit := limits[op]
+			if limit <= 0 {
+				limit = timeout
+			}
+			if limit <= 0 {
+				return call(ctx)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, limit)
+			defer cancel()
+
+			return call(ctx)
+		}}
+	}
+}
+
+// Retrying повторяет чтения (GetTasks, CountTasks, SearchTasks), которые не
+// удались из-за разорванного соединения, до cfg.MaxAttempts попыток. Изменения не
+// повторяются: вместе с задачей они пишут событие, и повтор после
+// потерянного ответа записал бы его дважды. Их повторяет сам драйвер
+// MongoDB, когда это безопасно (retryable writes).
+func Retrying(cfg config.RepositoryRetryConfig) TaskDecorator {
+	return func(next Task) Task {
+		return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+			if op != "GetTasks" && op != "CountTasks" && op != "SearchTasks" {
+				return call(ctx)
+			}
+
+			pause := cfg.Backoff
+			for attempt := 1; ; attempt++ {
+				err := call(ctx)
+				if attempt >= cfg.MaxAttempts || !transient(ctx, err) {
+					return err
+				}
+
+				logger.FromContext(ctx).Package("repository").With("op", op).Debug("retrying after %s", err)
+
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(pause):
+				}
+				pause *= 2
+			}
+		}}
+	}
+}
+
+// transient сообщает, что вызов не удался из-за разорванного соединения с
+// базой и его стоит повторить. Прерванные вызовы (см. cutShort) не
+// повторяются.
+func transient(ctx context.Context, err error) bool {
+	if err == nil || cutShort(ctx, err) {
+		return false
+	}
+
+	return mongo.IsNetworkError(err) || errors.Is(err, driver.ErrBadConn)
+}
+
+// taskDecorator выполняет каждый метод next через around, передавая имя
+// метода.
+type taskDecorator struct {
+	next   Task
+	around func(ctx context.Context, op string, call func(context.Context) error) error
+}
+
+func (r *taskDecorator) CreateTask(ctx context.Context, task entity.Task) (id entity.TaskID, err error) {
+	err = r.around(ctx, "CreateTask", func(ctx context.Context) error {
+		id, err = r.next.CreateTask(ctx, task)
+		return err
+	})
+	return id, err
+}
+
+func (r *taskDecorator) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error {
+	return r.around(ctx, "UpdateTask", func(ctx context.Context) error {
+		return r.next.UpdateTask(ctx, task, taskId)
+	})
+}
+
+func (r *taskDecorator) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	return r.around(ctx, "DeleteTask", func(ctx context.Context) error {
+		return r.next.DeleteTask(ctx, taskId)
+	})
+}
+
+func (r *taskDecorator) StatusUpdate(ctx context.Context, taskId entity.TaskID) (changed bool, err error) {
+	err = r.around(ctx, "StatusUpdate", func(ctx context.Context) error {
+		changed, err = r.next.StatusUpdate(ctx, taskId)
+		return err
+	})
+	return changed, err
+}
+
+func (r *taskDecorator) GetTasks(ctx context.Context, status string, loc *time.Location) (tasks []entity.Task, err error) {
+	err = r.around(ctx, "GetTasks", func(ctx context.Context) error {
+		tasks, err = r.next.GetTasks(ctx, status, loc)
+		return err
+	})
+	return tasks, err
+}
+
+func (r *taskDecorator) CountTasks(ctx context.Context, now time.Time) (counts entity.TaskCounts, err error) {
+	err = r.around(ctx, "CountTasks", func(ctx context.Context) error {
+		counts, err = r.next.CountTasks(ctx, now)
+		return err
+	})
+	return counts, err
+}
+
+func (r *taskDecorator) SearchTasks(ctx context.Context, query string) (tasks []entity.Task, err error) {
+	err = r.around(ctx, "SearchTasks", func(ctx context.Context) error {
+		tasks, err = asSearch(r.next).SearchTasks(ctx, query)
+		return err
+	})
+	return tasks, err
+}
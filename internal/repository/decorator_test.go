@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scriptedTasks возвращает по очереди ошибки из errs, затем nil, и
+// запоминает контекст каждого вызова.
+type scriptedTasks struct {
+	Task
+	errs  []error
+	calls []context.Context
+}
+
+func (r *scriptedTasks) next(ctx context.Context) error {
+	r.calls = append(r.calls, ctx)
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *scriptedTasks) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	return nil, r.next(ctx)
+}
+
+func (r *scriptedTasks) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	return r.next(ctx)
+}
+
+func TestDecorateTask_order(t *testing.T) {
+	var order []string
+	named := func(name string) TaskDecorator {
+		return func(next Task) Task {
+			return &taskDecorator{next: next, around: func(ctx context.Context, op string, call func(context.Context) error) error {
+				order = append(order, name+" "+op)
+				return call(ctx)
+			}}
+		}
+	}
+
+	repo := DecorateTask(&scriptedTasks{}, named("outer"), named("inner"))
+	_, err := repo.GetTasks(context.Background(), "active", time.UTC)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"outer GetTasks", "inner GetTasks"}, order)
+}
+
+func TestDecoratedTaskContract(t *testing.T) {
+	decorators, err := TaskDecorators(config.RepositoryConfig{
+		Log:     true,
+		Timeout: time.Second,
+		Retry:   config.RepositoryRetryConfig{MaxAttempts: 3, Backoff: time.Millisecond},
+	}, config.TaskCacheConfig{Enabled: true, Store: "memory", Size: 10, TTL: time.Minute}, Guarded(nil))
+	require.NoError(t, err)
+
+	testTaskContract(t, func(t *testing.T) Task {
+		return NewMemoryRepository(newTaskIDs(t, entity.TaskIDObjectID), decorators...).Task
+	})
+}
+
+func TestTaskDecorators(t *testing.T) {
+	decorators, err := TaskDecorators(config.RepositoryConfig{Retry: config.RepositoryRetryConfig{MaxAttempts: 1}}, config.TaskCacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, decorators)
+
+	decorators, err = TaskDecorators(config.RepositoryConfig{
+		Log:     true,
+		Timeout: time.Second,
+		Retry:   config.RepositoryRetryConfig{MaxAttempts: 3},
+	}, config.TaskCacheConfig{Enabled: true, Store: "memory", Size: 10, TTL: time.Minute}, Guarded(nil))
+	require.NoError(t, err)
+	require.Len(t, decorators, 5)
+	_, cached := DecorateTask(&scriptedTasks{}, decorators...).(*cachedTaskRepository)
+	assert.True(t, cached, "the cache is outermost")
+
+	_, err = TaskDecorators(config.RepositoryConfig{}, config.TaskCacheConfig{Enabled: true, Store: "redis"}, nil)
+	assert.EqualError(t, err, `unknown taskCache.store "redis"`)
+}
+
+func TestRetrying(t *testing.T) {
+	networkErr := mongo.CommandError{Message: "connection reset", Labels: []string{"NetworkError"}}
+	retrying := Retrying(config.RepositoryRetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	t.Run("transient read", func(t *testing.T) {
+		db := &scriptedTasks{errs: []error{networkErr, driver.ErrBadConn}}
+		_, err := retrying(db).GetTasks(context.Background(), "active", time.UTC)
+		require.NoError(t, err)
+		assert.Len(t, db.calls, 3)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		db := &scriptedTasks{errs: []error{networkErr, networkErr, networkErr, networkErr}}
+		_, err := retrying(db).GetTasks(context.Background(), "active", time.UTC)
+		assert.Equal(t, networkErr, err)
+		assert.Len(t, db.calls, 3)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		db := &scriptedTasks{errs: []error{errors.New("incorrect url query")}}
+		_, err := retrying(db).GetTasks(context.Background(), "unknown", time.UTC)
+		assert.EqualError(t, err, "incorrect url query")
+		assert.Len(t, db.calls, 1)
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		db := &scriptedTasks{errs: []error{networkErr}}
+		err := retrying(db).DeleteTask(context.Background(), entity.TaskID{})
+		assert.Equal(t, networkErr, err)
+		assert.Len(t, db.calls, 1)
+	})
+}
+
+func TestWithTimeouts(t *testing.T) {
+	db := &scriptedTasks{}
+	repo := WithTimeouts(time.Second, config.RepositoryTimeoutsConfig{GetTasks: time.Minute})(db)
+
+	_, err := repo.GetTasks(context.Background(), "active", time.UTC)
+	require.NoError(t, err)
+	deadline, ok := db.calls[0].Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 100*time.Millisecond)
+
+	// Операции без своего таймаута получают общий.
+	require.NoError(t, repo.DeleteTask(context.Background(), entity.TaskID{}))
+	deadline, ok = db.calls[1].Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	// Более ранний срок запроса остается в силе.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = repo.GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	deadline, _ = db.calls[2].Deadline()
+	want, _ := ctx.Deadline()
+	assert.Equal(t, want, deadline)
+
+	// Без таймаутов контекст передается как есть.
+	db = &scriptedTasks{}
+	_, err = WithTimeouts(0, config.RepositoryTimeoutsConfig{})(db).GetTasks(context.Background(), "active", time.UTC)
+	require.NoError(t, err)
+	_, ok = db.calls[0].Deadline()
+	assert.False(t, ok)
+}
+
+func TestLogged(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logger.WithContext(context.Background(), logger.NewWriter(&buf, "debug"))
+	db := &scriptedTasks{errs: []error{errors.New("no record found")}}
+
+	err := Logged(time.Hour)(db).DeleteTask(ctx, entity.TaskID{})
+	assert.EqualError(t, err, "no record found")
+	assert.Contains(t, buf.String(), `"level":"debug"`)
+	assert.Contains(t, buf.String(), `"op":"DeleteTask"`)
+	assert.Contains(t, buf.String(), `"error":"no record found"`)
+	assert.Contains(t, buf.String(), `"message":"repository call"`)
+
+	buf.Reset()
+	_, err = Logged(time.Nanosecond)(db).GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"message":"slow repository call"`)
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type digestRepository struct {
+	subscriptions *mongo.Collection
+	tasks         *mongo.Collection
+}
+
+func NewDigestRepository(db *mongo.Database) *digestRepository {
+	return &digestRepository{
+		subscriptions: db.Collection(digestsCollection),
+		tasks:         db.Collection(tasksCollection),
+	}
+}
+
+// CreateSubscription сохраняет подписку на ежедневную сводку.
+// На один адрес может быть только одна подписка.
+func (r *digestRepository) CreateSubscription(ctx context.Context, subscription entity.DigestSubscription) (primitive.ObjectID, error) {
+	err := r.subscriptions.FindOne(ctx, bson.M{"email": subscription.Email}).Err()
+	if err == nil {
+		return primitive.ObjectID{}, errors.New("this document already exists")
+	}
+	if err != mongo.ErrNoDocuments {
+		return primitive.ObjectID{}, err
+	}
+
+	subscription.ID = primitive.NewObjectID()
+
+	if _, err := r.subscriptions.InsertOne(ctx, subscription); err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return subscription.ID, nil
+}
+
+// GetSubscriptions возвращает все подписки на сводку.
+func (r *digestRepository) GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return r.findSubscriptions(ctx, bson.M{})
+}
+
+// GetEnabledSubscriptions возвращает подписки пользователей, включивших сводку.
+func (r *digestRepository) GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return r.findSubscriptions(ctx, bson.M{"enabled": true})
+}
+
+// UpdateSubscription обновляет настройки подписки по ее идентификатору.
+func (r *digestRepository) UpdateSubscription(ctx context.Context, subscription entity.DigestSubscription, subscriptionId primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{
+		"email":    subscription.Email,
+		"name":     subscription.Name,
+		"timezone": subscription.Timezone,
+		"hour":     subscription.Hour,
+		"enabled":  subscription.Enabled,
+	}}
+
+	res, err := r.subscriptions.UpdateOne(ctx, bson.M{"_id": subscriptionId}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("no record found")
+	}
+
+	return nil
+}
+
+// DeleteSubscription удаляет подписку по ее идентификатору.
+func (r *digestRepository) DeleteSubscription(ctx context.Context, subscriptionId primitive.ObjectID) error {
+	res, err := r.subscriptions.DeleteOne(ctx, bson.M{"_id": subscriptionId})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("no record found")
+	}
+
+	return nil
+}
+
+// ClaimDigest берет сводку за day в аренду до now+lease. Возвращает false,
+// если сводку за day уже отправили, ее держит другой экземпляр сервиса или
+// пользователь отключил сводку. Отправленной сводка становится только после
+// CompleteDigest.
+func (r *digestRepository) ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error) {
+	filter := bson.M{
+		"_id":        subscriptionId,
+		"enabled":    true,
+		"lastsenton": bson.M{"$ne": day},
+		"$or": bson.A{
+			bson.M{"leaseuntil": bson.M{"$exists": false}},
+			bson.M{"leaseuntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"leaseowner": owner, "leaseuntil": now.Add(lease)}}
+
+	res, err := r.subscriptions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// CompleteDigest отмечает сводку за day отправленной и снимает аренду, если
+// она еще у owner.
+func (r *digestRepository) CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error {
+	return r.updateLeased(ctx, subscriptionId, owner, bson.M{
+		"$set":   bson.M{"lastsenton": day},
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	})
+}
+
+// ReleaseDigest снимает аренду после неудачной отправки, чтобы сводку
+// отправили повторно, не дожидаясь конца аренды.
+func (r *digestRepository) ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error {
+	return r.updateLeased(ctx, subscriptionId, owner, bson.M{
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	})
+}
+
+func (r *digestRepository) updateLeased(ctx context.Context, subscriptionId primitive.ObjectID, owner string, update bson.M) error {
+	res, err := r.subscriptions.UpdateOne(ctx, bson.M{"_id": subscriptionId, "leaseowner": owner}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("digest lease lost")
+	}
+
+	return nil
+}
+
+// GetTasksActiveUntil возвращает невыполненные задачи, начавшиеся к моменту
+// until для пользователя в часовом поясе loc.
+func (r *digestRepository) GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "activeat", Value: 1}, {Key: "title", Value: 1}})
+
+	return findTasks(ctx, r.tasks, activeBy(until, loc), opts)
+}
+
+// GetTasksDoneBetween возвращает задачи, выполненные в промежутке [from, to).
+func (r *digestRepository) GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error) {
+	filter := bson.M{
+		"status": done,
+		"doneat": bson.M{"$gte": from, "$lt": to},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "doneat", Value: 1}})
+
+	return findTasks(ctx, r.tasks, filter, opts)
+}
+
+func (r *digestRepository) findSubscriptions(ctx context.Context, filter bson.M) ([]entity.DigestSubscription, error) {
+	cursor, err := r.subscriptions.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var subscriptions []entity.DigestSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
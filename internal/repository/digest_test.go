@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestClaimDigest(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	subscriptionId := primitive.NewObjectID()
+	now := time.Date(2023, 8, 15, 5, 0, 0, 0, time.UTC)
+
+	mt.Run("claimed", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...))
+		repo := &digestRepository{subscriptions: mt.Coll}
+
+		ok, err := repo.ClaimDigest(context.Background(), subscriptionId, "2023-08-15", now, "replica-1", 5*time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "2023-08-15", update.Lookup("q", "lastsenton", "$ne").StringValue())
+		assert.True(t, update.Lookup("q", "enabled").Boolean())
+		assert.Equal(t, now, update.Lookup("q", "$or").Array().Index(1).Value().Document().Lookup("leaseuntil", "$lte").Time().UTC())
+		assert.Equal(t, "replica-1", update.Lookup("u", "$set", "leaseowner").StringValue())
+		assert.Equal(t, now.Add(5*time.Minute), update.Lookup("u", "$set", "leaseuntil").Time().UTC())
+		_, err = update.LookupErr("u", "$set", "lastsenton")
+		assert.NotNil(t, err, "the digest is marked sent only by CompleteDigest")
+	})
+
+	mt.Run("already_sent", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 0}, {Key: "nModified", Value: 0}}...))
+		repo := &digestRepository{subscriptions: mt.Coll}
+
+		ok, err := repo.ClaimDigest(context.Background(), subscriptionId, "2023-08-15", now, "replica-1", 5*time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestCompleteDigest(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	subscriptionId := primitive.NewObjectID()
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...))
+		repo := &digestRepository{subscriptions: mt.Coll}
+
+		err := repo.CompleteDigest(context.Background(), subscriptionId, "replica-1", "2023-08-15")
+		assert.Nil(t, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "replica-1", update.Lookup("q", "leaseowner").StringValue())
+		assert.Equal(t, "2023-08-15", update.Lookup("u", "$set", "lastsenton").StringValue())
+		assert.Equal(t, "", update.Lookup("u", "$unset", "leaseowner").StringValue())
+	})
+
+	mt.Run("lease_lost", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		repo := &digestRepository{subscriptions: mt.Coll}
+
+		err := repo.CompleteDigest(context.Background(), subscriptionId, "replica-1", "2023-08-15")
+		assert.Equal(t, "digest lease lost", err.Error())
+	})
+}
+
+func TestGetTasksDoneBetween(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success", func(mt *mtest.T) {
+		from := time.Date(2023, 8, 13, 21, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 0, 1)
+		doneAt := from.Add(30 * time.Minute)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "status", Value: "done"},
+			{Key: "title", Value: "Сдать отчет"},
+			{Key: "activeat", Value: time.Date(2023, 8, 13, 0, 0, 0, 0, time.UTC)},
+			{Key: "allday", Value: true},
+			{Key: "timezone", Value: ""},
+			{Key: "doneat", Value: doneAt},
+		}))
+		repo := &digestRepository{tasks: mt.Coll}
+
+		tasks, err := repo.GetTasksDoneBetween(context.Background(), from, to)
+		assert.Nil(t, err)
+		assert.Len(t, tasks, 1)
+		assert.Equal(t, "Сдать отчет", tasks[0].Title)
+		assert.Equal(t, "2023-08-13", tasks[0].ActiveAt)
+		assert.Equal(t, doneAt, tasks[0].DoneAt.UTC())
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter")
+		assert.Equal(t, "done", filter.Document().Lookup("status").StringValue())
+		assert.Equal(t, from, filter.Document().Lookup("doneat", "$gte").Time().UTC())
+		assert.Equal(t, to, filter.Document().Lookup("doneat", "$lt").Time().UTC())
+	})
+}
@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "activeat_dates",
+		Up:      activeAtDatesUp,
+		Down:    activeAtDatesDown,
+	})
+}
+
+// activeAtDatesUp превращает строки activeat (YYYY-MM-DD) в даты BSON. Такие
+// задачи становятся плавающими задачами на весь день: полночь UTC этой даты,
+// без часового пояса.
+func activeAtDatesUp(ctx context.Context, db *mongo.Database) error {
+	tasks := db.Collection("task")
+
+	cursor, err := tasks.Find(ctx, bson.M{"activeat": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var errs []error
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			ActiveAt string             `bson:"activeat"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		day, err := time.Parse("2006-01-02", doc.ActiveAt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", doc.ID.Hex(), err))
+			continue
+		}
+
+		update := bson.M{"$set": bson.M{"activeat": day, "allday": true, "timezone": ""}}
+		if _, err := tasks.UpdateOne(ctx, bson.M{"_id": doc.ID, "activeat": doc.ActiveAt}, update); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// activeAtDatesDown превращает activeat обратно в строку YYYY-MM-DD, день
+// задачи в ее собственном часовом поясе. Время у задач со временем теряется.
+func activeAtDatesDown(ctx context.Context, db *mongo.Database) error {
+	tasks := db.Collection("task")
+
+	cursor, err := tasks.Find(ctx, bson.M{"activeat": bson.M{"$type": "date"}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			ActiveAt time.Time          `bson:"activeat"`
+			Timezone string             `bson:"timezone"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		loc := time.UTC
+		if doc.Timezone != "" {
+			if loc, err = time.LoadLocation(doc.Timezone); err != nil {
+				return fmt.Errorf("task %s: %w", doc.ID.Hex(), err)
+			}
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"activeat": doc.ActiveAt.In(loc).Format("2006-01-02")},
+			"$unset": bson.M{"allday": "", "timezone": ""},
+		}
+		if _, err := tasks.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes обслуживают запросы репозиториев. Имена фиксированы, чтобы откат
+// удалил ровно то, что создал up.
+var indexes = map[string][]mongo.IndexModel{
+	"task": {
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "activeat", Value: 1}}, Options: options.Index().SetName("status_activeat")},
+		{Keys: bson.D{{Key: "title", Value: 1}, {Key: "activeat", Value: 1}}, Options: options.Index().SetName("title_activeat")},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "doneat", Value: 1}}, Options: options.Index().SetName("status_doneat")},
+	},
+	"outbox": {
+		{Keys: bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}}, Options: options.Index().SetName("createdat_id")},
+	},
+	"webhook": {
+		{Keys: bson.D{{Key: "events", Value: 1}}, Options: options.Index().SetName("events")},
+	},
+	"webhook_delivery": {
+		{Keys: bson.D{{Key: "webhookid", Value: 1}, {Key: "createdat", Value: -1}}, Options: options.Index().SetName("webhookid_createdat")},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdat", Value: 1}}, Options: options.Index().SetName("status_createdat")},
+	},
+	"reminder": {
+		{Keys: bson.D{{Key: "taskid", Value: 1}, {Key: "remindat", Value: 1}}, Options: options.Index().SetName("taskid_remindat").SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "remindat", Value: 1}}, Options: options.Index().SetName("status_remindat")},
+	},
+	"digest_subscription": {
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email").SetUnique(true)},
+	},
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "indexes",
+		Up:      indexesUp,
+		Down:    indexesDown,
+	})
+}
+
+func indexesUp(ctx context.Context, db *mongo.Database) error {
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func indexesDown(ctx context.Context, db *mongo.Database) error {
+	for collection, models := range indexes {
+		for _, model := range models {
+			_, err := db.Collection(collection).Indexes().DropOne(ctx, *model.Options.Name)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isNotFound сообщает, что удаление не удалось, потому что коллекции или
+// индекса уже нет.
+func isNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 26 || cmdErr.Code == 27
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deliveryEvent оставляет одну доставку на событие и подписчика, чтобы
+// событие, опубликованное outbox повторно, не отправило вебхук дважды.
+// Доставки, записанные до появления идентификаторов событий, не имеют eventid
+// и в индекс не входят.
+var deliveryEvent = mongo.IndexModel{
+	Keys: bson.D{{Key: "eventid", Value: 1}, {Key: "webhookid", Value: 1}},
+	Options: options.Index().
+		SetName("eventid_webhookid").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.M{"eventid": bson.M{"$exists": true}}),
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "delivery_event",
+		Up:      deliveryEventUp,
+		Down:    deliveryEventDown,
+	})
+}
+
+func deliveryEventUp(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("webhook_delivery").Indexes().CreateOne(ctx, deliveryEvent)
+	return err
+}
+
+func deliveryEventDown(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("webhook_delivery").Indexes().DropOne(ctx, *deliveryEvent.Options.Name)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
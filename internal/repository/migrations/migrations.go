@@ -0,0 +1,352 @@
+// Package migrations ведет версии схемы базы данных. У каждой миграции есть
+// номер, она применяется не больше одного раза и записывается в коллекцию
+// schema_migrations (в таблицу для SQL-баз). Блокировка не дает репликам
+// одновременно мигрировать одну базу. Миграции Mongo — функции Go,
+// регистрируемые из пронумерованных файлов; SQL-миграции — файлы .sql в
+// каталоге на каждый диалект.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "lock"
+)
+
+// ErrLockTimeout возвращается, когда другая реплика держит блокировку дольше
+// таймаута блокировки.
+var ErrLockTimeout = errors.New("migrations: timed out waiting for lock")
+
+// Migration переводит схему из Version-1 в Version. Down отменяет Up и может
+// быть nil у миграций, которые нельзя откатить.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record — строка schema_migrations.
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedat"`
+}
+
+// Status описывает одну миграцию: известную этой сборке, примененную или обе.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Unknown задается для версий, записанных в базе, но отсутствующих в этой
+	// сборке, обычно примененных более новым релизом.
+	Unknown bool
+}
+
+var registry []Migration
+
+// register добавляет миграцию в список, который возвращает All. Ее вызывают
+// функции init пронумерованных файлов миграций.
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All возвращает миграции этой сборки по порядку версий.
+func All() []Migration {
+	all := append([]Migration(nil), registry...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+
+	return all
+}
+
+// Migrator применяет и откатывает миграции.
+type Migrator struct {
+	db          *mongo.Database
+	migrations  []Migration
+	logger      logger.Interface
+	owner       string
+	lockTimeout time.Duration
+	lockLease   time.Duration
+	retry       time.Duration
+}
+
+// New возвращает мигратор для заданных миграций, обычно All().
+func New(db *mongo.Database, migrations []Migration, cfg config.MigrationsConfig, l logger.Interface) (*Migrator, error) {
+	seen := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migrations: %q has non-positive version %d", m.Name, m.Version)
+		}
+		if seen[m.Version] {
+			return nil, fmt.Errorf("migrations: duplicate version %d", m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migrations: %04d %s has no up", m.Version, m.Name)
+		}
+		seen[m.Version] = true
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "todo"
+	}
+
+	m := &Migrator{
+		db:          db,
+		migrations:  sorted,
+		logger:      l,
+		owner:       fmt.Sprintf("%s-%d", host, os.Getpid()),
+		lockTimeout: cfg.LockTimeout,
+		lockLease:   cfg.LockLease,
+		retry:       time.Second,
+	}
+
+	if m.lockTimeout <= 0 {
+		m.lockTimeout = time.Minute
+	}
+	if m.lockLease <= 0 {
+		m.lockLease = 15 * time.Minute
+	}
+
+	return m, nil
+}
+
+// Status перечисляет все миграции, известные сборке или записанные в базе.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if rec, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = rec.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+
+	for _, rec := range applied {
+		statuses = append(statuses, Status{
+			Version:   rec.Version,
+			Name:      rec.Name,
+			Applied:   true,
+			AppliedAt: rec.AppliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Pending возвращает миграции, которые применил бы Up, по порядку.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+
+	return pending, nil
+}
+
+// Rollback возвращает последние steps примененных миграций, начиная с новой:
+// те, что отменил бы Down.
+func (m *Migrator) Rollback(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	var rollback []Migration
+	for _, v := range versions {
+		if len(rollback) == steps {
+			break
+		}
+
+		mig, ok := known[v]
+		if !ok {
+			return nil, fmt.Errorf("migrations: version %d (%s) is not known to this binary", v, applied[v].Name)
+		}
+		if mig.Down == nil {
+			return nil, fmt.Errorf("migrations: %04d %s cannot be rolled back", mig.Version, mig.Name)
+		}
+		rollback = append(rollback, mig)
+	}
+
+	return rollback, nil
+}
+
+// Up применяет все ожидающие миграции и возвращает примененные.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(ctx, func() error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending {
+			if err := mig.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migrations: %04d %s up: %w", mig.Version, mig.Name, err)
+			}
+
+			rec := Record{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC()}
+			if _, err := m.db.Collection(migrationsCollection).InsertOne(ctx, rec); err != nil {
+				return fmt.Errorf("migrations: record %04d: %w", mig.Version, err)
+			}
+
+			m.logger.Info("migrations: applied %04d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down откатывает последние steps примененных миграций и возвращает их.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(ctx, func() error {
+		rollback, err := m.Rollback(ctx, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range rollback {
+			if err := mig.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("migrations: %04d %s down: %w", mig.Version, mig.Name, err)
+			}
+
+			if _, err := m.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": mig.Version}); err != nil {
+				return fmt.Errorf("migrations: unrecord %04d: %w", mig.Version, err)
+			}
+
+			m.logger.Info("migrations: rolled back %04d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]Record, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+
+	return applied, nil
+}
+
+// withLock выполняет fn, удерживая блокировку миграций. Блокировка — один
+// документ с арендой, поэтому реплика, упавшая посреди миграции, задерживает
+// остальных только до истечения аренды.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		// Контекст вызывающего может быть уже отменен; блокировку все равно
+		// нужно снять.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err := m.db.Collection(lockCollection).DeleteOne(unlockCtx, bson.M{"_id": lockID, "owner": m.owner})
+		if err != nil {
+			m.logger.Error(fmt.Errorf("migrations: release lock: %w", err))
+		}
+	}()
+
+	return fn()
+}
+
+func (m *Migrator) lock(ctx context.Context) error {
+	locks := m.db.Collection(lockCollection)
+	deadline := time.Now().Add(m.lockTimeout)
+
+	for {
+		now := time.Now().UTC()
+		_, err := locks.InsertOne(ctx, bson.M{"_id": lockID, "owner": m.owner, "lockedat": now, "expiresat": now.Add(m.lockLease)})
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("migrations: lock: %w", err)
+		}
+
+		res, err := locks.DeleteOne(ctx, bson.M{"_id": lockID, "expiresat": bson.M{"$lte": now}})
+		if err != nil {
+			return fmt.Errorf("migrations: lock: %w", err)
+		}
+		if res.DeletedCount > 0 {
+			m.logger.Warn("migrations: took over expired lock")
+			continue
+		}
+
+		if time.Now().Add(m.retry).After(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.retry):
+		}
+	}
+}
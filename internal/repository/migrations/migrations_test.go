@@ -0,0 +1,262 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testMigrations(calls *[]string) []Migration {
+	step := func(name string) func(context.Context, *mongo.Database) error {
+		return func(context.Context, *mongo.Database) error {
+			*calls = append(*calls, name)
+			return nil
+		}
+	}
+
+	return []Migration{
+		{Version: 2, Name: "second", Up: step("up 2"), Down: step("down 2")},
+		{Version: 1, Name: "first", Up: step("up 1"), Down: step("down 1")},
+		{Version: 3, Name: "third", Up: step("up 3")},
+	}
+}
+
+func records(versions ...int) bson.D {
+	var docs []bson.D
+	for _, v := range versions {
+		docs = append(docs, bson.D{{Key: "_id", Value: v}, {Key: "name", Value: "m"}, {Key: "appliedat", Value: time.Now()}})
+	}
+	return mtest.CreateCursorResponse(0, "test.schema_migrations", mtest.FirstBatch, docs...)
+}
+
+func lockHeld() bson.D {
+	return mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"})
+}
+
+func commands(mt *mtest.T) []string {
+	var names []string
+	for _, e := range mt.GetAllStartedEvents() {
+		coll, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+		names = append(names, e.CommandName+" "+coll)
+	}
+	return names
+}
+
+func TestNew_validates(t *testing.T) {
+	up := func(context.Context, *mongo.Database) error { return nil }
+
+	_, err := New(nil, []Migration{{Version: 1, Name: "a", Up: up}, {Version: 1, Name: "b", Up: up}}, config.MigrationsConfig{}, logger.New("error"))
+	assert.EqualError(t, err, "migrations: duplicate version 1")
+
+	_, err = New(nil, []Migration{{Version: 0, Name: "a", Up: up}}, config.MigrationsConfig{}, logger.New("error"))
+	assert.EqualError(t, err, `migrations: "a" has non-positive version 0`)
+
+	_, err = New(nil, []Migration{{Version: 1, Name: "a"}}, config.MigrationsConfig{}, logger.New("error"))
+	assert.EqualError(t, err, "migrations: 0001 a has no up")
+}
+
+func TestAll_ordered(t *testing.T) {
+	all := All()
+	require.NotEmpty(t, all)
+
+	for i, m := range all {
+		assert.Equal(t, i+1, m.Version, "migrations are numbered without gaps")
+		assert.NotNil(t, m.Down, "%04d %s has no down", m.Version, m.Name)
+	}
+}
+
+func TestMigrator_Up(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("applies_pending_in_order", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(), // блокировка
+			records(1),
+			mtest.CreateSuccessResponse(), // запись 2
+			mtest.CreateSuccessResponse(), // запись 3
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), // снятие блокировки
+		)
+
+		applied, err := m.Up(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, applied, 2)
+		assert.Equal(t, []string{"up 2", "up 3"}, calls)
+		assert.Equal(t, []string{
+			"insert schema_migrations_lock",
+			"find schema_migrations",
+			"insert schema_migrations",
+			"insert schema_migrations",
+			"delete schema_migrations_lock",
+		}, commands(mt))
+	})
+
+	mt.Run("lock_held", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{LockTimeout: time.Millisecond}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(lockHeld(), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		_, err = m.Up(context.Background())
+		assert.ErrorIs(t, err, ErrLockTimeout)
+		assert.Empty(t, calls)
+	})
+
+	mt.Run("expired_lock_taken_over", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{LockTimeout: time.Millisecond}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(
+			lockHeld(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), // удаление просроченной блокировки
+			mtest.CreateSuccessResponse(),                           // блокировка
+			records(1, 2, 3),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), // снятие блокировки
+		)
+
+		applied, err := m.Up(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+
+		events := mt.GetAllStartedEvents()
+		expired := events[1].Command.Lookup("deletes").Array().Index(0).Value().Document()
+		assert.Equal(t, "lock", expired.Lookup("q", "_id").StringValue())
+		assert.NotNil(t, expired.Lookup("q", "expiresat", "$lte"))
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("rolls_back_newest_first", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls)[:2], config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			records(1, 2),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		rolledBack, err := m.Down(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Len(t, rolledBack, 2)
+		assert.Equal(t, []string{"down 2", "down 1"}, calls)
+	})
+
+	mt.Run("irreversible", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(records(1, 2, 3))
+
+		_, err = m.Rollback(context.Background(), 1)
+		assert.EqualError(t, err, "migrations: 0003 third cannot be rolled back")
+	})
+
+	mt.Run("unknown_version", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(records(1, 4))
+
+		_, err = m.Rollback(context.Background(), 1)
+		assert.EqualError(t, err, "migrations: version 4 (m) is not known to this binary")
+	})
+}
+
+func TestMigrator_Status(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success", func(mt *mtest.T) {
+		var calls []string
+		m, err := New(mt.DB, testMigrations(&calls), config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		mt.AddMockResponses(records(1, 4))
+
+		statuses, err := m.Status(context.Background())
+		require.NoError(t, err)
+		require.Len(t, statuses, 4)
+
+		var got []string
+		for _, s := range statuses {
+			got = append(got, s.Name)
+			assert.Equal(t, s.Version == 1 || s.Version == 4, s.Applied)
+			assert.Equal(t, s.Version == 4, s.Unknown)
+		}
+		assert.Equal(t, []string{"first", "second", "third", "m"}, got)
+	})
+}
+
+func TestActiveAtDatesUp(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success", func(mt *mtest.T) {
+		migratedId := primitive.NewObjectID()
+		brokenId := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: migratedId}, {Key: "activeat", Value: "2023-08-15"}},
+				bson.D{{Key: "_id", Value: brokenId}, {Key: "activeat", Value: "15.08.2023"}},
+			),
+			mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...),
+		)
+
+		err := activeAtDatesUp(context.Background(), mt.DB)
+		assert.ErrorContains(t, err, "task "+brokenId.Hex())
+
+		find := mt.GetStartedEvent().Command
+		assert.Equal(t, "string", find.Lookup("filter", "activeat", "$type").StringValue())
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, migratedId, update.Lookup("q", "_id").ObjectID())
+		assert.Equal(t, time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC), update.Lookup("u", "$set", "activeat").Time().UTC())
+		assert.True(t, update.Lookup("u", "$set", "allday").Boolean())
+		assert.Equal(t, "", update.Lookup("u", "$set", "timezone").StringValue())
+	})
+}
+
+func TestActiveAtDatesDown(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "activeat", Value: time.Date(2023, 8, 14, 21, 0, 0, 0, time.UTC)}, {Key: "timezone", Value: "Europe/Moscow"}},
+			),
+			mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...),
+		)
+
+		require.NoError(t, activeAtDatesDown(context.Background(), mt.DB))
+
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "2023-08-15", update.Lookup("u", "$set", "activeat").StringValue())
+	})
+}
@@ -0,0 +1,16 @@
+CREATE TABLE tasks (
+    id        BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    status    TEXT        NOT NULL,
+    title     TEXT        NOT NULL,
+    -- start of the task; for all-day tasks without a timezone, midnight UTC of their date
+    active_at TIMESTAMPTZ NOT NULL,
+    all_day   BOOLEAN     NOT NULL DEFAULT FALSE,
+    timezone  TEXT        NOT NULL DEFAULT '',
+    remind_at TIMESTAMPTZ[],
+    done_at   TIMESTAMPTZ
+);
+
+-- GetTasks: status filter ordered by start
+CREATE INDEX tasks_status_active_at ON tasks (status, active_at, id);
+-- duplicate check in CreateTask
+CREATE INDEX tasks_title_active_at ON tasks (title, active_at);
@@ -0,0 +1,2 @@
+CREATE INDEX tasks_title_active_at ON tasks (title, active_at);
+DROP INDEX tasks_title_active_at_all_day_timezone;
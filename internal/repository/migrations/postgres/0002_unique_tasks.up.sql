@@ -0,0 +1,5 @@
+-- CreateTask rejects duplicates with WHERE NOT EXISTS, which two concurrent
+-- inserts can both pass; the unique index makes the second one fail.
+-- Fails if the table already holds duplicates: remove them first.
+CREATE UNIQUE INDEX tasks_title_active_at_all_day_timezone ON tasks (title, active_at, all_day, timezone);
+DROP INDEX tasks_title_active_at;
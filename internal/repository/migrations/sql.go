@@ -0,0 +1,513 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var sqlFiles embed.FS
+
+// SQLMigration — пара файлов NNNN_name.up.sql и NNNN_name.down.sql. Down
+// пуст, если файла down нет.
+type SQLMigration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Dialect хранит то, чем различаются SQL-базы.
+type Dialect struct {
+	// Placeholder возвращает n-й (с единицы) параметр запроса.
+	Placeholder func(n int) string
+	// CreateTable создает schema_migrations, если ее нет, а HasTable — запрос,
+	// сообщающий, есть ли она.
+	CreateTable string
+	HasTable    string
+	// TryLock берет блокировку миграций на conn без ожидания и сообщает, удалось
+	// ли. Unlock снимает ее. Nil TryLock значит, что у базы один писатель и
+	// блокировка не нужна.
+	TryLock func(ctx context.Context, conn *sql.Conn) (bool, error)
+	Unlock  func(ctx context.Context, conn *sql.Conn) error
+}
+
+// postgresLockKey — ключ pg_advisory_lock блокировки миграций.
+const postgresLockKey = 7_104_111_068_111
+
+// Postgres — диалект PostgreSQL. Блокировка — сессионная advisory-блокировка,
+// поэтому она снимается вместе с соединением, если процесс упал.
+var Postgres = Dialect{
+	Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	CreateTable: `CREATE TABLE IF NOT EXISTS ` + migrationsCollection + ` (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	HasTable: `SELECT to_regclass('` + migrationsCollection + `') IS NOT NULL`,
+	TryLock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var locked bool
+		err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, postgresLockKey).Scan(&locked)
+		return locked, err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, postgresLockKey)
+		return err
+	},
+}
+
+// SQLite — диалект SQLite. База SQLite принадлежит одному процессу, а ее
+// транзакции и так упорядочивают писателей, поэтому блокировки нет.
+var SQLite = Dialect{
+	Placeholder: func(int) string { return "?" },
+	CreateTable: `CREATE TABLE IF NOT EXISTS ` + migrationsCollection + ` (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+	HasTable: `SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = '` + migrationsCollection + `'`,
+}
+
+// PostgresMigrations возвращает миграции хранилища задач PostgreSQL.
+func PostgresMigrations() []SQLMigration {
+	return embedded("postgres")
+}
+
+// SQLiteMigrations возвращает миграции хранилища задач SQLite.
+func SQLiteMigrations() []SQLMigration {
+	return embedded("sqlite")
+}
+
+// embedded загружает миграции каталога одного диалекта. Файлы вкомпилированы
+// и проверяются тестами, поэтому ошибка — это баг.
+func embedded(dir string) []SQLMigration {
+	sub, err := fs.Sub(sqlFiles, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	migrations, err := LoadSQL(sub)
+	if err != nil {
+		panic(err)
+	}
+
+	return migrations
+}
+
+// LoadSQL читает файлы NNNN_name.up.sql и NNNN_name.down.sql в корне fsys по
+// порядку версий.
+func LoadSQL(fsys fs.FS) ([]SQLMigration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*SQLMigration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, name, direction, err := parseSQLName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &SQLMigration{Version: version, Name: name}
+			byVersion[version] = mig
+		}
+		if mig.Name != name {
+			return nil, fmt.Errorf("migrations: version %d is both %q and %q", version, mig.Name, name)
+		}
+
+		if direction == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]SQLMigration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migrations: %04d %s has no up", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// parseSQLName разбивает "0001_create_tasks.up.sql" на 1, "create_tasks", "up".
+func parseSQLName(file string) (int, string, string, error) {
+	base := strings.TrimSuffix(file, ".sql")
+
+	direction := path.Ext(base)
+	if direction != ".up" && direction != ".down" {
+		return 0, "", "", fmt.Errorf("migrations: %s: want NNNN_name.up.sql or NNNN_name.down.sql", file)
+	}
+	base = strings.TrimSuffix(base, direction)
+
+	number, name, ok := strings.Cut(base, "_")
+	version, err := strconv.Atoi(number)
+	if !ok || err != nil || version <= 0 || name == "" {
+		return 0, "", "", fmt.Errorf("migrations: %s: want NNNN_name.up.sql or NNNN_name.down.sql", file)
+	}
+
+	return version, name, strings.TrimPrefix(direction, "."), nil
+}
+
+// SQLMigrator применяет SQL-миграции через database/sql. У него те же методы,
+// что у Migrator; в возвращаемых Migration заданы только Version и Name.
+type SQLMigrator struct {
+	db          *sql.DB
+	dialect     Dialect
+	migrations  []SQLMigration
+	logger      logger.Interface
+	lockTimeout time.Duration
+	retry       time.Duration
+}
+
+// NewSQL возвращает мигратор для заданных миграций, например PostgresMigrations().
+func NewSQL(db *sql.DB, dialect Dialect, migrations []SQLMigration, cfg config.MigrationsConfig, l logger.Interface) (*SQLMigrator, error) {
+	seen := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migrations: %q has non-positive version %d", m.Name, m.Version)
+		}
+		if seen[m.Version] {
+			return nil, fmt.Errorf("migrations: duplicate version %d", m.Version)
+		}
+		seen[m.Version] = true
+	}
+
+	sorted := append([]SQLMigration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	m := &SQLMigrator{
+		db:          db,
+		dialect:     dialect,
+		migrations:  sorted,
+		logger:      l,
+		lockTimeout: cfg.LockTimeout,
+		retry:       time.Second,
+	}
+
+	if m.lockTimeout <= 0 {
+		m.lockTimeout = time.Minute
+	}
+
+	return m, nil
+}
+
+// Status перечисляет все миграции, известные сборке или записанные в базе.
+func (m *SQLMigrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if rec, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = rec.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+
+	for _, rec := range applied {
+		statuses = append(statuses, Status{
+			Version:   rec.Version,
+			Name:      rec.Name,
+			Applied:   true,
+			AppliedAt: rec.AppliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Pending возвращает миграции, которые применил бы Up, по порядку.
+func (m *SQLMigrator) Pending(ctx context.Context) ([]Migration, error) {
+	pending, err := m.pending(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return describe(pending), nil
+}
+
+// Rollback возвращает последние steps примененных миграций, начиная с новой:
+// те, что отменил бы Down.
+func (m *SQLMigrator) Rollback(ctx context.Context, steps int) ([]Migration, error) {
+	rollback, err := m.rollback(ctx, m.db, steps)
+	if err != nil {
+		return nil, err
+	}
+
+	return describe(rollback), nil
+}
+
+// Up применяет все ожидающие миграции и возвращает примененные. Каждая
+// миграция выполняется в своей транзакции вместе со своей записью.
+func (m *SQLMigrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []SQLMigration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, m.dialect.CreateTable); err != nil {
+			return fmt.Errorf("migrations: create %s: %w", migrationsCollection, err)
+		}
+
+		pending, err := m.pending(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		insert := fmt.Sprintf(`INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)`,
+			migrationsCollection, m.dialect.Placeholder(1), m.dialect.Placeholder(2), m.dialect.Placeholder(3))
+
+		for _, mig := range pending {
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+					return fmt.Errorf("migrations: %04d %s up: %w", mig.Version, mig.Name, err)
+				}
+				if _, err := tx.ExecContext(ctx, insert, mig.Version, mig.Name, time.Now().UTC()); err != nil {
+					return fmt.Errorf("migrations: record %04d: %w", mig.Version, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			m.logger.Info("migrations: applied %04d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+
+		return nil
+	})
+
+	return describe(done), err
+}
+
+// Down откатывает последние steps примененных миграций и возвращает их.
+func (m *SQLMigrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []SQLMigration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		rollback, err := m.rollback(ctx, conn, steps)
+		if err != nil {
+			return err
+		}
+
+		remove := fmt.Sprintf(`DELETE FROM %s WHERE version = %s`, migrationsCollection, m.dialect.Placeholder(1))
+
+		for _, mig := range rollback {
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+					return fmt.Errorf("migrations: %04d %s down: %w", mig.Version, mig.Name, err)
+				}
+				if _, err := tx.ExecContext(ctx, remove, mig.Version); err != nil {
+					return fmt.Errorf("migrations: unrecord %04d: %w", mig.Version, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			m.logger.Info("migrations: rolled back %04d %s", mig.Version, mig.Name)
+			done = append(done, mig)
+		}
+
+		return nil
+	})
+
+	return describe(done), err
+}
+
+// querier — либо пул, либо соединение, держащее блокировку.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// applied читает schema_migrations. Status и пробные запуски не должны менять
+// базу, поэтому отсутствие таблицы значит, что ничего еще не применено.
+func (m *SQLMigrator) applied(ctx context.Context, q querier) (map[int]Record, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, m.dialect.HasTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int]Record{}, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, name, applied_at FROM `+migrationsCollection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]Record)
+	for rows.Next() {
+		var rec Record
+		if err := rows.Scan(&rec.Version, &rec.Name, &rec.AppliedAt); err != nil {
+			return nil, err
+		}
+		rec.AppliedAt = rec.AppliedAt.UTC()
+		applied[rec.Version] = rec
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *SQLMigrator) pending(ctx context.Context, q querier) ([]SQLMigration, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []SQLMigration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *SQLMigrator) rollback(ctx context.Context, q querier, steps int) ([]SQLMigration, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	known := make(map[int]SQLMigration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	var rollback []SQLMigration
+	for _, v := range versions {
+		if len(rollback) == steps {
+			break
+		}
+
+		mig, ok := known[v]
+		if !ok {
+			return nil, fmt.Errorf("migrations: version %d (%s) is not known to this binary", v, applied[v].Name)
+		}
+		if strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("migrations: %04d %s cannot be rolled back", mig.Version, mig.Name)
+		}
+		rollback = append(rollback, mig)
+	}
+
+	return rollback, nil
+}
+
+// withLock выполняет fn на одном соединении, держащем блокировку миграций.
+func (m *SQLMigrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.TryLock == nil {
+		return fn(conn)
+	}
+
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		locked, err := m.dialect.TryLock(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("migrations: lock: %w", err)
+		}
+		if locked {
+			break
+		}
+
+		if time.Now().Add(m.retry).After(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.retry):
+		}
+	}
+	defer func() {
+		// Контекст вызывающего может быть уже отменен; блокировку все равно
+		// нужно снять.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := m.dialect.Unlock(unlockCtx, conn); err != nil {
+			m.logger.Error(fmt.Errorf("migrations: release lock: %w", err))
+		}
+	}()
+
+	return fn(conn)
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// describe превращает SQL-миграции в значения Migration, которые возвращают
+// методы Migrator; заданы только Version и Name.
+func describe(migrations []SQLMigration) []Migration {
+	described := make([]Migration, 0, len(migrations))
+	for _, mig := range migrations {
+		described = append(described, Migration{Version: mig.Version, Name: mig.Name})
+	}
+
+	return described
+}
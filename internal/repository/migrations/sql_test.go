@@ -0,0 +1,218 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/database/postgres/postgrestest"
+	"github.com/yervsil/toDo-microservice/pkg/database/sqlite"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+func TestLoadSQL(t *testing.T) {
+	t.Run("pairs", func(t *testing.T) {
+		migrations, err := LoadSQL(fstest.MapFS{
+			"0002_index.up.sql":    {Data: []byte("CREATE INDEX i ON t (a);")},
+			"0001_create.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+			"0001_create.down.sql": {Data: []byte("DROP TABLE t;")},
+			"README.md":            {Data: []byte("not a migration")},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []SQLMigration{
+			{Version: 1, Name: "create", Up: "CREATE TABLE t (a INT);", Down: "DROP TABLE t;"},
+			{Version: 2, Name: "index", Up: "CREATE INDEX i ON t (a);"},
+		}, migrations)
+	})
+
+	for name, files := range map[string]fstest.MapFS{
+		"no_direction":   {"0001_create.sql": {Data: []byte("x")}},
+		"no_version":     {"create.up.sql": {Data: []byte("x")}},
+		"no_name":        {"0001.up.sql": {Data: []byte("x")}},
+		"no_up":          {"0001_create.down.sql": {Data: []byte("x")}},
+		"different_name": {"0001_create.up.sql": {Data: []byte("x")}, "0001_drop.down.sql": {Data: []byte("x")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadSQL(files)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for name, migrations := range map[string][]SQLMigration{
+		"postgres": PostgresMigrations(),
+		"sqlite":   SQLiteMigrations(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NotEmpty(t, migrations)
+
+			for i, mig := range migrations {
+				assert.Equal(t, i+1, mig.Version)
+				assert.NotEmpty(t, mig.Down, "%04d %s has no down", mig.Version, mig.Name)
+			}
+		})
+	}
+}
+
+func TestSQLMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewSQL(db, SQLite, []SQLMigration{
+		{Version: 2, Name: "second", Up: "CREATE TABLE b (x INT); CREATE INDEX b_x ON b (x);", Down: "DROP TABLE b;"},
+		{Version: 1, Name: "first", Up: "CREATE TABLE a (x INT);", Down: "DROP TABLE a;"},
+		{Version: 3, Name: "third", Up: "CREATE TABLE c (x INT);"},
+	}, config.MigrationsConfig{}, logger.New("error"))
+	require.NoError(t, err)
+
+	tables := func() []string {
+		t.Helper()
+		rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		return names
+	}
+
+	t.Run("dry_run_changes_nothing", func(t *testing.T) {
+		pending, err := m.Pending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{{Version: 1, Name: "first"}, {Version: 2, Name: "second"}, {Version: 3, Name: "third"}}, pending)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.Len(t, statuses, 3)
+		assert.Empty(t, tables())
+	})
+
+	t.Run("up", func(t *testing.T) {
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Len(t, applied, 3)
+		assert.Equal(t, []string{"a", "b", "c", "schema_migrations"}, tables())
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		for _, s := range statuses {
+			assert.True(t, s.Applied)
+			assert.WithinDuration(t, time.Now(), s.AppliedAt, time.Minute)
+		}
+
+		applied, err = m.Up(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("down_stops_at_irreversible", func(t *testing.T) {
+		_, err := m.Rollback(ctx, 1)
+		assert.EqualError(t, err, "migrations: 0003 third cannot be rolled back")
+
+		_, err = m.Down(ctx, 1)
+		assert.Error(t, err)
+		assert.Contains(t, tables(), "c")
+	})
+
+	t.Run("failed_up_is_not_recorded", func(t *testing.T) {
+		broken, err := NewSQL(db, SQLite, []SQLMigration{
+			{Version: 4, Name: "broken", Up: "CREATE TABLE d (x INT); SELECT * FROM missing;"},
+		}, config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+
+		_, err = broken.Up(ctx)
+		assert.Error(t, err)
+		assert.NotContains(t, tables(), "d", "the migration runs in a transaction")
+
+		pending, err := broken.Pending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		_, err := db.Exec(`DELETE FROM schema_migrations WHERE version = 3`)
+		require.NoError(t, err)
+
+		rolledBack, err := m.Down(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{{Version: 2, Name: "second"}, {Version: 1, Name: "first"}}, rolledBack)
+		assert.Equal(t, []string{"c", "schema_migrations"}, tables())
+	})
+}
+
+// TestSQLMigrator_postgres прогоняет миграции Postgres вверх и вниз на
+// временной схеме встроенного сервера или сервера из TODO_TEST_POSTGRES_DSN.
+func TestSQLMigrator_postgres(t *testing.T) {
+	ctx := context.Background()
+	db := openPostgresSchema(t, postgrestest.DSN(t))
+
+	m, err := NewSQL(db, Postgres, PostgresMigrations(), config.MigrationsConfig{}, logger.New("error"))
+	require.NoError(t, err)
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(PostgresMigrations()))
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pending, applied)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "%04d %s", s.Version, s.Name)
+	}
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rolledBack, err := m.Down(ctx, len(pending))
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(pending))
+
+	var tasks sql.NullString
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT to_regclass('tasks')::text`).Scan(&tasks))
+	assert.False(t, tasks.Valid, "tasks should be dropped")
+}
+
+// openPostgresSchema возвращает пул, соединения которого используют новую
+// схему, удаляемую в конце теста.
+func openPostgresSchema(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+
+	cfg, err := pgx.ParseConfig(dsn)
+	require.NoError(t, err)
+
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "todo_migrations_test"
+	_, err = admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE; CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`) })
+
+	schemaCfg := cfg.Copy()
+	schemaCfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*schemaCfg)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,39 @@
+-- Times are Unix milliseconds, so that they compare as numbers.
+CREATE TABLE tasks (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    status    TEXT    NOT NULL,
+    title     TEXT    NOT NULL,
+    -- start of the task; for all-day tasks without a timezone, midnight UTC of their date
+    active_at INTEGER NOT NULL,
+    all_day   INTEGER NOT NULL DEFAULT 0,
+    timezone  TEXT    NOT NULL DEFAULT '',
+    -- JSON array of RFC 3339 times
+    remind_at TEXT,
+    done_at   INTEGER
+);
+
+-- GetTasks: status filter ordered by start
+CREATE INDEX tasks_status_active_at ON tasks (status, active_at, id);
+-- duplicate check in CreateTask
+CREATE INDEX tasks_title_active_at ON tasks (title, active_at);
+
+-- Full-text index of titles for SearchTasks, kept in sync by the triggers.
+CREATE VIRTUAL TABLE tasks_fts USING fts5(
+    title,
+    content = 'tasks',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts (rowid, title) VALUES (new.id, new.title);
+END;
+
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, title) VALUES ('delete', old.id, old.title);
+END;
+
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, title) VALUES ('delete', old.id, old.title);
+    INSERT INTO tasks_fts (rowid, title) VALUES (new.id, new.title);
+END;
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type outboxRepository struct {
+	db *mongo.Collection
+}
+
+func NewOutboxRepository(db *mongo.Database) *outboxRepository {
+	return &outboxRepository{db: db.Collection(outboxCollection)}
+}
+
+// AcquireMessages берет в аренду до limit событий в порядке их записи: у
+// каждой задачи только самое раннее неопубликованное и только если его никто
+// не держит. Пока оно в аренде, следующие события задачи не получит никто,
+// поэтому реплики публикуют события одной задачи по порядку.
+func (r *outboxRepository) AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error) {
+	order := bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}}
+	free := bson.A{
+		bson.M{"leaseuntil": bson.M{"$exists": false}},
+		bson.M{"leaseuntil": bson.M{"$lte": now}},
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$ne": entity.OutboxFailed}}}},
+		{{Key: "$sort", Value: order}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$event.taskid"}, {Key: "head", Value: bson.M{"$first": "$$ROOT"}}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$head"}}},
+		{{Key: "$match", Value: bson.M{"$or": free}}},
+		{{Key: "$sort", Value: order}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.db.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var heads []entity.OutboxMessage
+	if err := cursor.All(ctx, &heads); err != nil {
+		return nil, err
+	}
+
+	var messages []entity.OutboxMessage
+	for _, head := range heads {
+		filter := bson.M{
+			"_id":    head.ID,
+			"status": bson.M{"$ne": entity.OutboxFailed},
+			"$or":    free,
+		}
+		update := bson.M{"$set": bson.M{"leaseowner": owner, "leaseuntil": now.Add(lease)}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+		var msg entity.OutboxMessage
+		err := r.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&msg)
+		if err == mongo.ErrNoDocuments {
+			// Другая реплика взяла событие в аренду или уже опубликовала его.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// DeleteMessage удаляет опубликованное событие из outbox, если аренда еще у owner.
+func (r *outboxRepository) DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error {
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": messageId, "leaseowner": owner})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("outbox message lease lost")
+	}
+
+	return nil
+}
+
+// RetryMessage запоминает неудачную попытку публикации, снимает аренду и
+// откладывает следующую попытку до retryAt.
+func (r *outboxRepository) RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return r.updateLeased(ctx, messageId, owner, bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": bson.M{"lasterror": reason, "leaseowner": "", "leaseuntil": retryAt},
+	})
+}
+
+// FailMessage прекращает попытки опубликовать событие. Оно остается в outbox
+// со статусом failed и больше не задерживает следующие события задачи.
+func (r *outboxRepository) FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error {
+	return r.updateLeased(ctx, messageId, owner, bson.M{
+		"$inc":   bson.M{"attempts": 1},
+		"$set":   bson.M{"status": entity.OutboxFailed, "lasterror": reason},
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	})
+}
+
+func (r *outboxRepository) updateLeased(ctx context.Context, messageId primitive.ObjectID, owner string, update bson.M) error {
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": messageId, "leaseowner": owner}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("outbox message lease lost")
+	}
+
+	return nil
+}
+
+// withOutbox выполняет изменение в транзакции и в той же транзакции записывает
+// в outbox событие, которое вернула функция fn. Пустое событие значит, что
+// fn ничего не изменила, и записывать нечего.
+func withOutbox(ctx context.Context, outbox *mongo.Collection, fn func(sc mongo.SessionContext) (entity.Event, error)) error {
+	session, err := outbox.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	var event entity.Event
+	// Транзакции читают только с primary, какой бы ни была db.readPreference.
+	txnOpts := options.Transaction().SetReadPreference(readpref.Primary())
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// WithTransaction повторяет fn после временных ошибок, не проверяя
+		// контекст: без этой проверки отмененный запрос повторялся бы до
+		// двух минут.
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+
+		event, err = fn(sc)
+		if err != nil || event.Type == "" {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		messageId := primitive.NewObjectID()
+		event.ID = messageId.Hex()
+		event.OccurredAt = now
+		event.TraceContext = tracing.Inject(ctx)
+
+		_, err = outbox.InsertOne(sc, entity.OutboxMessage{
+			ID:        messageId,
+			Event:     event,
+			Status:    entity.OutboxPending,
+			CreatedAt: now,
+		})
+
+		return nil, err
+	}, txnOpts)
+	if err != nil || event.Type == "" {
+		return err
+	}
+
+	logger.FromContext(ctx).Package("repository").With("event", event.Type).With("task_id", event.TaskID).Debug("outbox: event written")
+	return nil
+}
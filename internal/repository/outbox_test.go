@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func commandNames(mt *mtest.T) []string {
+	var names []string
+	for _, e := range mt.GetAllStartedEvents() {
+		names = append(names, e.CommandName)
+	}
+
+	return names
+}
+
+func TestOutboxWrittenInTaskTransaction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	newTask := entity.Task{
+		Title:    "New Task",
+		ActiveAt: "2023-08-15",
+	}
+
+	mt.Run("create_commits_task_and_event", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		repo := &taskRepository{
+			db:     mt.Coll,
+			outbox: mt.Coll,
+			ids:    newTaskIDs(t, entity.TaskIDObjectID),
+		}
+
+		id, err := repo.CreateTask(context.Background(), newTask)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"find", "insert", "insert", "commitTransaction"}, commandNames(mt))
+
+		events := mt.GetAllStartedEvents()
+		taskInsert, outboxInsert := events[1].Command, events[2].Command
+		assert.True(t, taskInsert.Lookup("startTransaction").Boolean())
+		assert.Equal(t, taskInsert.Lookup("txnNumber"), outboxInsert.Lookup("txnNumber"))
+		assert.Equal(t, taskInsert.Lookup("lsid"), outboxInsert.Lookup("lsid"))
+
+		msg := outboxInsert.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, entity.EventTaskCreated, msg.Lookup("event", "type").StringValue())
+		assert.Equal(t, id.String(), msg.Lookup("event", "taskid").StringValue())
+		assert.Equal(t, "New Task", msg.Lookup("event", "task", "title").StringValue())
+		assert.Equal(t, msg.Lookup("_id").ObjectID().Hex(), msg.Lookup("event", "id").StringValue(), "the event is identified by its message")
+		assert.Equal(t, entity.OutboxPending, msg.Lookup("status").StringValue())
+	})
+
+	mt.Run("failed_event_write_aborts_task", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...), mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}), mtest.CreateSuccessResponse())
+		repo := &taskRepository{
+			db:     mt.Coll,
+			outbox: mt.Coll,
+		}
+
+		_, err := repo.StatusUpdate(context.Background(), mustTaskID(primitive.NewObjectID().Hex()))
+		assert.NotNil(t, err)
+		assert.Equal(t, []string{"update", "insert", "abortTransaction"}, commandNames(mt))
+	})
+
+	mt.Run("no_event_for_missing_task", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		repo := &taskRepository{
+			db:     mt.Coll,
+			outbox: mt.Coll,
+		}
+
+		err := repo.DeleteTask(context.Background(), mustTaskID(primitive.NewObjectID().Hex()))
+		assert.Equal(t, "no record found", err.Error())
+		assert.Equal(t, []string{"delete", "abortTransaction"}, commandNames(mt))
+	})
+}
+
+func TestAcquireMessages(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	now := time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC)
+	head := func(id primitive.ObjectID, taskId string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: id},
+			{Key: "event", Value: bson.D{{Key: "type", Value: entity.EventTaskDone}, {Key: "taskid", Value: taskId}}},
+			{Key: "status", Value: entity.OutboxPending},
+			{Key: "attempts", Value: 2},
+		}
+	}
+
+	mt.Run("leases_heads_of_tasks", func(mt *mtest.T) {
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		leased := append(head(first, "64d1c8747124f40af803840b"),
+			bson.E{Key: "leaseowner", Value: "relay-1"},
+			bson.E{Key: "leaseuntil", Value: now.Add(time.Minute)})
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.outbox", mtest.FirstBatch, head(first, "64d1c8747124f40af803840b"), head(second, "64d1c8747124f40af803840c")),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: leased}),
+			// Другая реплика успела взять второе событие.
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+		repo := &outboxRepository{db: mt.Coll}
+
+		got, err := repo.AcquireMessages(context.Background(), now, "relay-1", time.Minute, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []entity.OutboxMessage{{
+			ID:         first,
+			Event:      entity.Event{Type: entity.EventTaskDone, TaskID: "64d1c8747124f40af803840b"},
+			Status:     entity.OutboxPending,
+			Attempts:   2,
+			LeaseOwner: "relay-1",
+			LeaseUntil: now.Add(time.Minute),
+		}}, got)
+		assert.Equal(t, []string{"aggregate", "findAndModify", "findAndModify"}, commandNames(mt))
+
+		aggregate := mt.GetAllStartedEvents()[0].Command
+		stages, err := aggregate.Lookup("pipeline").Array().Values()
+		assert.Nil(t, err)
+		assert.Equal(t, `{"$group": {"_id": "$event.taskid","head": {"$first": "$$ROOT"}}}`, bson.Raw(stages[2].Document()).String())
+		assert.Equal(t, `{"$limit": {"$numberInt":"10"}}`, bson.Raw(stages[6].Document()).String())
+
+		update := mt.GetAllStartedEvents()[1].Command
+		assert.Equal(t, "relay-1", update.Lookup("update", "$set", "leaseowner").StringValue())
+		assert.Equal(t, first, update.Lookup("query", "_id").ObjectID())
+	})
+}
+
+func TestOutboxLeasedUpdates(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("delete_lease_lost", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		repo := &outboxRepository{db: mt.Coll}
+
+		err := repo.DeleteMessage(context.Background(), primitive.NewObjectID(), "relay-1")
+		assert.EqualError(t, err, "outbox message lease lost")
+
+		filter := mt.GetStartedEvent().Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q")
+		assert.Equal(t, "relay-1", filter.Document().Lookup("leaseowner").StringValue())
+	})
+
+	mt.Run("fail", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		repo := &outboxRepository{db: mt.Coll}
+
+		err := repo.FailMessage(context.Background(), primitive.NewObjectID(), "relay-1", "broker unavailable")
+		assert.Nil(t, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		assert.Equal(t, entity.OutboxFailed, update.Lookup("$set", "status").StringValue())
+		assert.Equal(t, "broker unavailable", update.Lookup("$set", "lasterror").StringValue())
+	})
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type reminderRepository struct {
+	db *mongo.Collection
+}
+
+func NewReminderRepository(db *mongo.Database) *reminderRepository {
+	return &reminderRepository{db: db.Collection(remindersCollection)}
+}
+
+// ReplaceReminders приводит ожидающие напоминания задачи к списку times.
+// Уже отправленные напоминания не создаются повторно.
+func (r *reminderRepository) ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error {
+	remindAt := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		// BSON хранит даты с точностью до миллисекунды.
+		remindAt = append(remindAt, t.UTC().Truncate(time.Millisecond))
+	}
+
+	_, err := r.db.DeleteMany(ctx, bson.M{
+		"taskid":   taskId,
+		"status":   entity.ReminderPending,
+		"remindat": bson.M{"$nin": remindAt},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, at := range remindAt {
+		filter := bson.M{"taskid": taskId, "remindat": at}
+		update := bson.M{
+			"$set":         bson.M{"title": title},
+			"$setOnInsert": bson.M{"status": entity.ReminderPending, "attempts": 0},
+		}
+
+		if _, err := r.db.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CancelReminders удаляет ожидающие напоминания задачи.
+func (r *reminderRepository) CancelReminders(ctx context.Context, taskId string) error {
+	_, err := r.db.DeleteMany(ctx, bson.M{"taskid": taskId, "status": entity.ReminderPending})
+	return err
+}
+
+// AcquireDueReminder атомарно берет в аренду самое раннее наступившее напоминание,
+// которое никто не держит. Если таких нет, возвращает nil.
+func (r *reminderRepository) AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error) {
+	filter := bson.M{
+		"status":   entity.ReminderPending,
+		"remindat": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"leaseuntil": bson.M{"$exists": false}},
+			bson.M{"leaseuntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"leaseowner": owner, "leaseuntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "remindat", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var reminder entity.Reminder
+	err := r.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&reminder)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reminder, nil
+}
+
+// CompleteReminder отмечает напоминание отправленным, если аренда еще у owner.
+func (r *reminderRepository) CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error {
+	return r.updateLeased(ctx, reminderId, owner, bson.M{
+		"$set":   bson.M{"status": entity.ReminderSent, "sentat": sentAt},
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	})
+}
+
+// RetryReminder снимает аренду и откладывает следующую попытку до retryAt.
+func (r *reminderRepository) RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return r.updateLeased(ctx, reminderId, owner, bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": bson.M{"lasterror": reason, "leaseowner": "", "leaseuntil": retryAt},
+	})
+}
+
+// FailReminder прекращает попытки отправить напоминание.
+func (r *reminderRepository) FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error {
+	return r.updateLeased(ctx, reminderId, owner, bson.M{
+		"$inc":   bson.M{"attempts": 1},
+		"$set":   bson.M{"status": entity.ReminderFailed, "lasterror": reason},
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	})
+}
+
+func (r *reminderRepository) updateLeased(ctx context.Context, reminderId primitive.ObjectID, owner string, update bson.M) error {
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": reminderId, "leaseowner": owner}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("reminder lease lost")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAcquireDueReminder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	now := time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC)
+
+	mt.Run("success", func(mt *mtest.T) {
+		reminderId := primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: reminderId},
+				{Key: "taskid", Value: "64d1c8747124f40af803840b"},
+				{Key: "title", Value: "Позвонить"},
+				{Key: "remindat", Value: now},
+				{Key: "status", Value: entity.ReminderPending},
+				{Key: "leaseowner", Value: "replica-1"},
+				{Key: "leaseuntil", Value: now.Add(time.Minute)},
+			}},
+		})
+		repo := &reminderRepository{db: mt.Coll}
+
+		got, err := repo.AcquireDueReminder(context.Background(), now, "replica-1", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, &entity.Reminder{
+			ID:         reminderId,
+			TaskID:     "64d1c8747124f40af803840b",
+			Title:      "Позвонить",
+			RemindAt:   now,
+			Status:     entity.ReminderPending,
+			LeaseOwner: "replica-1",
+			LeaseUntil: now.Add(time.Minute),
+		}, got)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, entity.ReminderPending, cmd.Lookup("query", "status").StringValue())
+		assert.Equal(t, now, cmd.Lookup("query", "remindat", "$lte").Time().UTC())
+		assert.Equal(t, "replica-1", cmd.Lookup("update", "$set", "leaseowner").StringValue())
+		assert.Equal(t, now.Add(time.Minute), cmd.Lookup("update", "$set", "leaseuntil").Time().UTC())
+	})
+
+	mt.Run("nothing_due", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		repo := &reminderRepository{db: mt.Coll}
+
+		got, err := repo.AcquireDueReminder(context.Background(), now, "replica-1", time.Minute)
+		assert.Nil(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestCompleteReminder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("lease_lost", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		repo := &reminderRepository{db: mt.Coll}
+
+		err := repo.CompleteReminder(context.Background(), primitive.NewObjectID(), "replica-1", time.Now())
+		assert.Equal(t, "reminder lease lost", err.Error())
+		assert.Equal(t, "replica-1", mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q", "leaseowner").StringValue())
+	})
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/yervsil/toDo-microservice/internal/entity"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,18 +11,166 @@ import (
 )
 
 //go:generate mockgen -source=repository.go -destination=mocks/mock.go
+
+// Task хранит задачи. StatusUpdate возвращает false, если задача уже была
+// выполнена: тогда она не меняется.
 type Task interface {
-	CreateTask(ctx context.Context, task entity.Task) (primitive.ObjectID, error)
-	UpdateTask(ctx context.Context, task entity.Task, taskId primitive.ObjectID) error
-	DeleteTask(ctx context.Context, taskId primitive.ObjectID) error
-	StatusUpdate(ctx context.Context, taskId primitive.ObjectID) error
-	GetTasks(ctx context.Context, status string) ([]entity.Task, error)
+	CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error)
+	UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error
+	DeleteTask(ctx context.Context, taskId entity.TaskID) error
+	StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error)
+	GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error)
+	CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error)
+}
+
+// Search ищет задачи по заголовку. Его поддерживает только SQLite.
+type Search interface {
+	SearchTasks(ctx context.Context, query string) ([]entity.Task, error)
+}
+
+type Outbox interface {
+	AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error)
+	DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error
+	RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error
+	FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error
+}
+
+type Webhook interface {
+	CreateWebhook(ctx context.Context, webhook entity.Webhook) (primitive.ObjectID, error)
+	GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]entity.Webhook, error)
+	GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId primitive.ObjectID) error
+	CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error)
+	GetDelivery(ctx context.Context, deliveryId primitive.ObjectID) (entity.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookId primitive.ObjectID) ([]entity.WebhookDelivery, error)
+	AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error)
+	AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error
+	ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error
+}
+
+type Reminder interface {
+	ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error
+	CancelReminders(ctx context.Context, taskId string) error
+	AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error)
+	CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error
+	RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error
+	FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error
+}
+
+type Digest interface {
+	CreateSubscription(ctx context.Context, subscription entity.DigestSubscription) (primitive.ObjectID, error)
+	GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error)
+	GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription entity.DigestSubscription, subscriptionId primitive.ObjectID) error
+	DeleteSubscription(ctx context.Context, subscriptionId primitive.ObjectID) error
+	ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error)
+	CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error
+	ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error
+	GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error)
+	GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error)
+}
+
+// ObjectID возвращает идентификатор вебхука, доставки или подписки на сводку
+// по его строке из API. Они хранятся только в MongoDB, поэтому остаются
+// ObjectID. Для строки, которая не может быть ObjectID, возвращается нулевой
+// идентификатор: такой записи нет, и репозиторий ответит "no record found",
+// как на неизвестный идентификатор задачи.
+func ObjectID(id string) primitive.ObjectID {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+
+	return objectId
 }
 
 type Repository struct {
 	Task
+	Search
+	Outbox
+	Webhook
+	Reminder
+	Digest
 }
 
-func NewRepository(db *mongo.Database) *Repository {
-	return &Repository{Task: NewTaskRepoistory(db)}
-}
\ No newline at end of file
+// NewRepository хранит все в MongoDB; ids выдает идентификаторы новых задач.
+// Декораторы оборачивают репозиторий задач, первый из них внешний (см.
+// TaskDecorators). Все конструкторы ниже тоже их принимают и возвращают
+// CanceledError для вызовов, прерванных их контекстом.
+func NewRepository(db *mongo.Database, ids entity.TaskIDGenerator, decorators ...TaskDecorator) *Repository {
+	return &Repository{
+		Task:     decorateTask(NewTaskRepoistory(db, ids), decorators),
+		Search:   unsupportedRepository{},
+		Outbox:   NewOutboxRepository(db),
+		Webhook:  NewWebhookRepository(db),
+		Reminder: NewReminderRepository(db),
+		Digest:   NewDigestRepository(db),
+	}
+}
+
+// NewMemoryRepository хранит задачи в памяти процесса, для локальной
+// разработки и тестов. События задач, вебхуки, напоминания и дайджесты
+// требуют MongoDB и возвращают ErrUnsupported.
+func NewMemoryRepository(ids entity.TaskIDGenerator, decorators ...TaskDecorator) *Repository {
+	unsupported := unsupportedRepository{}
+
+	return &Repository{
+		Task:     decorateTask(NewMemoryTaskRepository(ids), decorators),
+		Search:   unsupported,
+		Outbox:   unsupported,
+		Webhook:  unsupported,
+		Reminder: unsupported,
+		Digest:   unsupported,
+	}
+}
+
+// NewPostgresRepository хранит задачи в PostgreSQL, которая сама их нумерует.
+// Как и в драйвере memory, события задач, вебхуки, напоминания и дайджесты
+// возвращают ErrUnsupported.
+func NewPostgresRepository(db *sql.DB, decorators ...TaskDecorator) *Repository {
+	unsupported := unsupportedRepository{}
+
+	return &Repository{
+		Task:     decorateTask(NewPostgresTaskRepository(db), decorators),
+		Search:   unsupported,
+		Outbox:   unsupported,
+		Webhook:  unsupported,
+		Reminder: unsupported,
+		Digest:   unsupported,
+	}
+}
+
+// NewSQLiteRepository хранит задачи в файле SQLite, которая сама их нумерует,
+// и умеет искать их. События задач, вебхуки, напоминания и дайджесты
+// возвращают ErrUnsupported.
+func NewSQLiteRepository(db *sql.DB, decorators ...TaskDecorator) *Repository {
+	unsupported := unsupportedRepository{}
+	tasks := decorateTask(NewSQLiteTaskRepository(db), decorators)
+
+	return &Repository{
+		Task:     tasks,
+		Search:   asSearch(tasks),
+		Outbox:   unsupported,
+		Webhook:  unsupported,
+		Reminder: unsupported,
+		Digest:   unsupported,
+	}
+}
+
+// asSearch возвращает поиск репозитория задач task. Декораторы ищут через
+// обернутый ими репозиторий, поэтому поиск проходит те же декораторы, что и
+// остальные вызовы.
+func asSearch(task Task) Search {
+	if search, ok := task.(Search); ok {
+		return search
+	}
+
+	return unsupportedRepository{}
+}
+
+// decorateTask оборачивает task декораторами и, самым внутренним,
+// surfaceCancellation.
+func decorateTask(task Task, decorators []TaskDecorator) Task {
+	return DecorateTask(task, append(decorators[:len(decorators):len(decorators)], surfaceCancellation)...)
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+)
+
+// sqlTaskID переводит идентификатор задачи в ключ строки таблицы tasks.
+// Такой задачи не может быть в базе, поэтому неверный идентификатор - это
+// "no record found".
+func sqlTaskID(taskId entity.TaskID) (int64, error) {
+	id, err := strconv.ParseInt(taskId.String(), 10, 64)
+	if err != nil {
+		return 0, errors.New("no record found")
+	}
+
+	return id, nil
+}
+
+// affectedOne возвращает "no record found", если запрос не затронул ни одной строки.
+func affectedOne(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("no record found")
+	}
+
+	return nil
+}
+
+// sqlTaskIDFrom - идентификатор задачи для ключа строки, назначенного базой.
+// changedOne сообщает, изменил ли условный UPDATE строку id. Если не изменил,
+// запрос exists проверяет, есть ли она вообще: нет — "no record found".
+func changedOne(ctx context.Context, db *sql.DB, exists string, id int64, res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n == 1 {
+		return n == 1, err
+	}
+
+	var found bool
+	if err := db.QueryRowContext(ctx, exists, id).Scan(&found); err != nil {
+		return false, err
+	}
+	if !found {
+		return false, errors.New("no record found")
+	}
+
+	return false, nil
+}
+
+func sqlTaskIDFrom(id int64) entity.TaskID {
+	taskId, _ := entity.ParseTaskID(strconv.FormatInt(id, 10))
+	return taskId
+}
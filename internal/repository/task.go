@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,67 +20,201 @@ const (
 
 type taskRepository struct {
 	db *mongo.Collection
+	outbox *mongo.Collection
+	ids entity.TaskIDGenerator
 }
 
-func NewTaskRepoistory(db *mongo.Database) *taskRepository {
-	return &taskRepository{db: db.Collection(tasksCollection)}
+func NewTaskRepoistory(db *mongo.Database, ids entity.TaskIDGenerator) *taskRepository {
+	return &taskRepository{
+		db: db.Collection(tasksCollection),
+		outbox: db.Collection(outboxCollection),
+		ids: ids,
+	}
+}
+
+// taskDocument - задача в том виде, в котором она хранится в базе:
+// ActiveAt хранится датой BSON.
+type taskDocument struct {
+	// ID - _id задачи, см. mongoTaskID. При чтении не используется.
+	ID       interface{} `bson:"_id,omitempty"`
+	Status   string      `bson:"status"`
+	Title    string      `bson:"title"`
+	// ActiveAt - момент начала задачи. Для задач на весь день без часового
+	// пояса это полночь UTC их даты, и сравнивать ее нужно с датой пользователя.
+	ActiveAt time.Time   `bson:"activeat"`
+	AllDay   bool        `bson:"allday"`
+	Timezone string      `bson:"timezone"`
+	RemindAt []time.Time `bson:"remindat,omitempty"`
+	DoneAt   *time.Time  `bson:"doneat,omitempty"`
+}
+
+func newTaskDocument(task entity.Task) (taskDocument, error) {
+	_, allDay, err := entity.ParseActiveAt(task.ActiveAt)
+	if err != nil {
+		return taskDocument{}, err
+	}
+
+	// Плавающая дата хранится полуночью UTC, остальные - моментом начала.
+	activeAt, err := task.Start(time.UTC)
+	if err != nil {
+		return taskDocument{}, err
+	}
+
+	return taskDocument{
+		Status:   task.Status,
+		Title:    task.Title,
+		ActiveAt: activeAt.UTC(),
+		AllDay:   allDay,
+		Timezone: task.Timezone,
+		RemindAt: task.RemindAt,
+		DoneAt:   task.DoneAt,
+	}, nil
+}
+
+func (d taskDocument) task() entity.Task {
+	return entity.Task{
+		Status:   d.Status,
+		Title:    d.Title,
+		ActiveAt: entity.FormatActiveAt(d.ActiveAt, d.AllDay, d.Timezone),
+		Timezone: d.Timezone,
+		RemindAt: d.RemindAt,
+		DoneAt:   d.DoneAt,
+	}
+}
+
+// mongoTaskID переводит идентификатор задачи в _id. Идентификаторы в виде
+// ObjectID хранятся как ObjectID, как и задачи, созданные до TaskID; все
+// остальные (UUIDv7, ULID) - строками.
+func mongoTaskID(taskId entity.TaskID) interface{} {
+	if id, err := primitive.ObjectIDFromHex(taskId.String()); err == nil {
+		return id
+	}
+
+	return taskId.String()
 }
 
 // CreateTask создает новую задачу в базе данных.
-func (r *taskRepository) CreateTask(ctx context.Context, task entity.Task) (primitive.ObjectID, error){
-	if isDuplicate(task, r.db){
-		return primitive.ObjectID{}, errors.New("this document already exists")
+func (r *taskRepository) CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error){
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return entity.TaskID{}, err
 	}
-	res, err := r.db.InsertOne(ctx, task)
-	
-	return res.InsertedID.(primitive.ObjectID), err
+
+	duplicate, err := isDuplicate(ctx, doc, r.db, nil)
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+	if duplicate {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return entity.TaskID{}, errors.New("this document already exists")
+	}
+
+	id := r.ids.NewTaskID()
+	doc.ID = mongoTaskID(id)
+
+	err = withOutbox(ctx, r.outbox, func(sc mongo.SessionContext) (entity.Event, error) {
+		if _, err := r.db.InsertOne(sc, doc); err != nil {
+			return entity.Event{}, err
+		}
+
+		return entity.Event{Type: entity.EventTaskCreated, TaskID: id.String(), Task: &task}, nil
+	})
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+
+	return id, nil
 }
 
 // UpdateTask обновляет существующую задачу в базе данных по ее идентификатору.
-func (r *taskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId primitive.ObjectID) error{
-	filter := bson.M{"_id": taskId}
-	res, err := r.db.ReplaceOne(context.Background(), filter, task)
-	if res.MatchedCount == 0 {
-		return errors.New("no record found")
+// Как и CreateTask, отклоняет изменение, после которого задача совпала бы с
+// другой.
+func (r *taskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error{
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return err
+	}
+
+	duplicate, err := isDuplicate(ctx, doc, r.db, mongoTaskID(taskId))
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return errors.New("this document already exists")
 	}
 
-	return err
+	filter := bson.M{"_id": mongoTaskID(taskId)}
+
+	return withOutbox(ctx, r.outbox, func(sc mongo.SessionContext) (entity.Event, error) {
+		res, err := r.db.ReplaceOne(sc, filter, doc)
+		if err != nil {
+			return entity.Event{}, err
+		}
+		if res.MatchedCount == 0 {
+			return entity.Event{}, errors.New("no record found")
+		}
+
+		return entity.Event{Type: entity.EventTaskUpdated, TaskID: taskId.String(), Task: &task}, nil
+	})
 }
 
 // DeleteTask удаляет задачу из базы данных по ее идентификатору.
-func (r *taskRepository) DeleteTask(ctx context.Context, taskId primitive.ObjectID) error{
-	filter := bson.M{"_id": taskId}
-	res, err := r.db.DeleteOne(context.Background(), filter)
-    if res.DeletedCount == 0 {
-		return errors.New("no record found")
-	}
+func (r *taskRepository) DeleteTask(ctx context.Context, taskId entity.TaskID) error{
+	filter := bson.M{"_id": mongoTaskID(taskId)}
+
+	return withOutbox(ctx, r.outbox, func(sc mongo.SessionContext) (entity.Event, error) {
+		res, err := r.db.DeleteOne(sc, filter)
+		if err != nil {
+			return entity.Event{}, err
+		}
+		if res.DeletedCount == 0 {
+			return entity.Event{}, errors.New("no record found")
+		}
 
-	return err
+		return entity.Event{Type: entity.EventTaskDeleted, TaskID: taskId.String()}, nil
+	})
 }
 
 // StatusUpdate обновляет статус задачи в базе данных по ее идентификатору.
-func (r *taskRepository) StatusUpdate(ctx context.Context, taskId primitive.ObjectID) error{
-	update := bson.M{"$set": bson.M{"status": done}}
+// Уже выполненная задача не меняется, и событие о ней не пишется.
+func (r *taskRepository) StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error){
+	update := bson.M{"$set": bson.M{"status": done, "doneat": time.Now().UTC()}}
 
-	filter := bson.M{"_id": taskId}
+	filter := bson.M{"_id": mongoTaskID(taskId), "status": bson.M{"$ne": done}}
 
-	res, err := r.db.UpdateOne(ctx, filter, update)
-	if res.MatchedCount == 0 {
-		return errors.New("no record found")
-	}
+	var changed bool
+	err := withOutbox(ctx, r.outbox, func(sc mongo.SessionContext) (entity.Event, error) {
+		res, err := r.db.UpdateOne(sc, filter, update)
+		if err != nil {
+			return entity.Event{}, err
+		}
+		if res.ModifiedCount == 0 {
+			n, err := r.db.CountDocuments(sc, bson.M{"_id": mongoTaskID(taskId)})
+			if err != nil {
+				return entity.Event{}, err
+			}
+			if n == 0 {
+				return entity.Event{}, errors.New("no record found")
+			}
+
+			return entity.Event{}, nil
+		}
+
+		changed = true
+		return entity.Event{Type: entity.EventTaskDone, TaskID: taskId.String()}, nil
+	})
 
-	return err
+	return changed, err
 }
 
-// GetTasks возвращает список задач с определенным статусом.
-func (r *taskRepository) GetTasks(ctx context.Context, status string) ([]entity.Task, error){
+// GetTasks возвращает список задач с определенным статусом. Активными
+// считаются задачи, которые уже начались для пользователя в часовом поясе loc.
+func (r *taskRepository) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error){
 	var filter primitive.M
 
 	if status == active{
-		filter = bson.M{
-			"status":   status,
-			"activeat": bson.M{"$lte": time.Now().Format("2006-01-02")},
-		}
+		filter = activeBy(time.Now(), loc)
 	}else if status == done{
 		filter = bson.M{
 			"status":   status,
@@ -92,37 +227,100 @@ func (r *taskRepository) GetTasks(ctx context.Context, status string) ([]entity.
 		"status": 0,
 	})
 
-	sortOptions := options.Find().SetSort(bson.D{{"activeat", 1}})
+	sortOptions := options.Find().SetSort(bson.D{{Key: "activeat", Value: 1}})
+
+	return findTasks(ctx, r.db, filter, projection, sortOptions)
+}
+
+// CountTasks считает активные и просроченные задачи на момент now.
+func (r *taskRepository) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	var counts entity.TaskCounts
+
+	activeCount, err := r.db.CountDocuments(ctx, activeBy(now, time.UTC))
+	if err != nil {
+		return counts, err
+	}
+
+	overdueCount, err := r.db.CountDocuments(ctx, bson.M{
+		"status":   active,
+		"activeat": bson.M{"$lt": startOfDay(now)},
+	})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.Active = activeCount
+	counts.Overdue = overdueCount
+
+	return counts, nil
+}
+
+// startOfDay возвращает полночь UTC дня, в который попадает at.
+func startOfDay(at time.Time) time.Time {
+	at = at.UTC()
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// activeBy возвращает фильтр невыполненных задач, начавшихся к моменту at
+// для пользователя в часовом поясе loc. Задачи на весь день без часового
+// пояса сравниваются с датой пользователя, остальные - с моментом at.
+func activeBy(at time.Time, loc *time.Location) bson.M {
+	local := at.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	return bson.M{
+		"status": active,
+		"$or": bson.A{
+			bson.M{"allday": false, "activeat": bson.M{"$lte": at}},
+			bson.M{"allday": true, "timezone": bson.M{"$ne": ""}, "activeat": bson.M{"$lte": at}},
+			bson.M{"allday": true, "timezone": "", "activeat": bson.M{"$lte": today}},
+		},
+	}
+}
 
-	cursor, err := r.db.Find(ctx, filter, projection, sortOptions)
+// findTasks ищет задачи и переводит их из вида, в котором они хранятся.
+func findTasks(ctx context.Context, collection *mongo.Collection, filter interface{}, opts ...*options.FindOptions) ([]entity.Task, error) {
+	cursor, err := collection.Find(ctx, filter, opts...)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var tasks []entity.Task
-	if err := cursor.All(ctx, &tasks); err != nil {
+	var docs []taskDocument
+	if err := cursor.All(ctx, &docs); err != nil {
 		return nil, err
 	}
 
+	var tasks []entity.Task
+	for _, doc := range docs {
+		tasks = append(tasks, doc.task())
+	}
+
 	return tasks, nil
 }
 
 // isDuplicate проверяет, существует ли уже такая задача в базе данных.
-func isDuplicate(task entity.Task, collection *mongo.Collection) bool {
+// isDuplicate ищет другую задачу с теми же названием и началом; задача с
+// идентификатором except, если он задан, не учитывается.
+func isDuplicate(ctx context.Context, task taskDocument, collection *mongo.Collection, except interface{}) (bool, error) {
 	filter := bson.M{
 		"title":     task.Title,
 		"activeat":  task.ActiveAt,
+		"allday":    task.AllDay,
+		"timezone":  task.Timezone,
+	}
+	if except != nil {
+		filter["_id"] = bson.M{"$ne": except}
 	}
 
-	var existingTask entity.Task
+	var existingTask taskDocument
 
-	err := collection.FindOne(context.Background(), filter).Decode(&existingTask)
+	err := collection.FindOne(ctx, filter).Decode(&existingTask)
 	if err == mongo.ErrNoDocuments {
-		return false
+		return false, nil
 	} else if err != nil {
-		return true
+		return false, err
 	}
 	
-	return true
+	return true, nil
 }
\ No newline at end of file
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
+	"github.com/yervsil/toDo-microservice/pkg/cache"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// cacheClearTimeout ограничивает очистку кэша после изменения.
+const cacheClearTimeout = time.Second
+
+// cachedTaskRepository кэширует списки задач GetTasks по статусу и часовому
+// поясу. Задачи общие, поэтому фильтр и есть ключ. Любое изменение через
+// этот репозиторий очищает кэш. Ошибки кэша только пишутся в лог: запрос
+// тогда идет в базу.
+type cachedTaskRepository struct {
+	next  Task
+	store cache.Store
+	ttl   time.Duration
+
+	// mu и generation не дают чтению, начатому до изменения, положить в
+	// кэш старый список после его очистки.
+	mu         sync.RWMutex
+	generation uint64
+}
+
+// NewCachedTaskRepository кэширует списки задач next в store на ttl.
+func NewCachedTaskRepository(next Task, store cache.Store, ttl time.Duration) Task {
+	return &cachedTaskRepository{next: next, store: store, ttl: ttl}
+}
+
+// newCacheStore возвращает хранилище кэша задач, названное в taskCache.store.
+func newCacheStore(cfg config.TaskCacheConfig) (cache.Store, error) {
+	switch cfg.Store {
+	case cache.StoreMemory:
+		return cache.NewLRU(cfg.Size, clock.New()), nil
+	default:
+		return nil, fmt.Errorf("unknown taskCache.store %q", cfg.Store)
+	}
+}
+
+func (r *cachedTaskRepository) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	key := "tasks|" + status + "|" + loc.String()
+	log := logger.FromContext(ctx).Package("repository")
+
+	value, ok, err := r.store.Get(ctx, key)
+	if err != nil {
+		log.Warn("task cache: get %s: %s", key, err)
+	}
+	if ok {
+		var tasks []entity.Task
+		if err = json.Unmarshal(value, &tasks); err == nil {
+			metrics.TaskCacheRequests.WithLabelValues("hit").Inc()
+			return tasks, nil
+		}
+		log.Warn("task cache: decode %s: %s", key, err)
+	}
+	metrics.TaskCacheRequests.WithLabelValues("miss").Inc()
+
+	r.mu.RLock()
+	generation := r.generation
+	r.mu.RUnlock()
+
+	tasks, err := r.next.GetTasks(ctx, status, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = json.Marshal(tasks)
+	if err != nil {
+		log.Warn("task cache: encode %s: %s", key, err)
+		return tasks, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if generation == r.generation {
+		if err := r.store.Set(ctx, key, value, r.ttl); err != nil {
+			log.Warn("task cache: set %s: %s", key, err)
+		}
+	}
+
+	return tasks, nil
+}
+
+func (r *cachedTaskRepository) CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error) {
+	defer r.invalidate(ctx)
+
+	return r.next.CreateTask(ctx, task)
+}
+
+func (r *cachedTaskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error {
+	defer r.invalidate(ctx)
+
+	return r.next.UpdateTask(ctx, task, taskId)
+}
+
+func (r *cachedTaskRepository) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	defer r.invalidate(ctx)
+
+	return r.next.DeleteTask(ctx, taskId)
+}
+
+func (r *cachedTaskRepository) StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error) {
+	defer r.invalidate(ctx)
+
+	return r.next.StatusUpdate(ctx, taskId)
+}
+
+func (r *cachedTaskRepository) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	return r.next.CountTasks(ctx, now)
+}
+
+// SearchTasks не кэшируется: запросы поиска почти не повторяются.
+func (r *cachedTaskRepository) SearchTasks(ctx context.Context, query string) ([]entity.Task, error) {
+	return asSearch(r.next).SearchTasks(ctx, query)
+}
+
+// invalidate очищает кэш и после неудачных изменений: они могли дойти до
+// базы. Очистка не зависит от контекста запроса, который мог уже истечь.
+func (r *cachedTaskRepository) invalidate(ctx context.Context) {
+	clearCtx, cancel := context.WithTimeout(context.Background(), cacheClearTimeout)
+	defer cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	if err := r.store.Clear(clearCtx); err != nil {
+		logger.FromContext(ctx).Package("repository").Warn("task cache: clear: %s", err)
+	}
+}
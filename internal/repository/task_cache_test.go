@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
+	"github.com/yervsil/toDo-microservice/pkg/cache"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+// countingTasks считает вызовы GetTasks, дошедшие до базы.
+type countingTasks struct {
+	Task
+	reads int
+}
+
+func (r *countingTasks) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	r.reads++
+	return r.Task.GetTasks(ctx, status, loc)
+}
+
+// failingStore завершает ошибкой каждый вызов.
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("store down")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("store down")
+}
+
+func (failingStore) Clear(context.Context) error {
+	return errors.New("store down")
+}
+
+func TestCachedTaskContract(t *testing.T) {
+	testTaskContract(t, func(t *testing.T) Task {
+		return NewCachedTaskRepository(NewMemoryTaskRepository(newTaskIDs(t, entity.TaskIDObjectID)), cache.NewLRU(100, clock.New()), time.Minute)
+	})
+}
+
+func TestCachedTaskRepository(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	db := &countingTasks{Task: NewMemoryTaskRepository(newTaskIDs(t, entity.TaskIDObjectID))}
+	repo := NewCachedTaskRepository(db, cache.NewLRU(100, clk), 10*time.Second)
+	hits := testutil.ToFloat64(metrics.TaskCacheRequests.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(metrics.TaskCacheRequests.WithLabelValues("miss"))
+
+	id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: "2023-08-04"})
+	require.NoError(t, err)
+
+	first, err := repo.GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	// Вызывающие могут менять полученный список, не меняя кэш.
+	first[0].Title = "ВЫХОДНОЙ - " + first[0].Title
+	second, err := repo.GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, "Купить книгу", second[0].Title)
+	assert.Equal(t, 1, db.reads)
+
+	// Ключ включает фильтр.
+	_, err = repo.GetTasks(ctx, "done", time.UTC)
+	require.NoError(t, err)
+	_, err = repo.GetTasks(ctx, "active", time.FixedZone("UTC+5", 5*60*60))
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.reads)
+
+	// Запись очищает все списки.
+	_, err = repo.StatusUpdate(ctx, id)
+	require.NoError(t, err)
+	tasks, err := repo.GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+	assert.Equal(t, 4, db.reads)
+
+	// Как и TTL.
+	clk.Advance(10 * time.Second)
+	_, err = repo.GetTasks(ctx, "active", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 5, db.reads)
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.TaskCacheRequests.WithLabelValues("hit")))
+	assert.Equal(t, misses+5, testutil.ToFloat64(metrics.TaskCacheRequests.WithLabelValues("miss")))
+}
+
+func TestCachedTaskRepository_storeErrors(t *testing.T) {
+	ctx := context.Background()
+	db := &countingTasks{Task: NewMemoryTaskRepository(newTaskIDs(t, entity.TaskIDObjectID))}
+	repo := NewCachedTaskRepository(db, failingStore{}, time.Minute)
+
+	_, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: "2023-08-04"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		tasks, err := repo.GetTasks(ctx, "active", time.UTC)
+		require.NoError(t, err)
+		assert.Len(t, tasks, 1)
+	}
+	assert.Equal(t, 2, db.reads)
+}
@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+	"github.com/yervsil/toDo-microservice/pkg/database/mongodb/mongotest"
+	"github.com/yervsil/toDo-microservice/pkg/database/postgres/postgrestest"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testTaskContract проверяет поведение, общее для всех хранилищ Task.
+// newRepo возвращает пустой репозиторий для каждого подтеста.
+func testTaskContract(t *testing.T, newRepo func(t *testing.T) Task) {
+	ctx := context.Background()
+	now := time.Now()
+	past := now.AddDate(0, 0, -2).UTC().Format(entity.DateLayout)
+	future := now.AddDate(0, 0, 2).UTC().Format(entity.DateLayout)
+
+	active := func(t *testing.T, repo Task, loc *time.Location) []string {
+		t.Helper()
+		tasks, err := repo.GetTasks(ctx, "active", loc)
+		require.NoError(t, err)
+
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	t.Run("create_and_get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past})
+		require.NoError(t, err)
+		assert.False(t, id.IsZero())
+
+		tasks, err := repo.GetTasks(ctx, "active", time.UTC)
+		require.NoError(t, err)
+		assert.Equal(t, []entity.Task{{Title: "Купить книгу", ActiveAt: past}}, tasks)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past})
+		require.NoError(t, err)
+
+		_, err = repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past})
+		assert.EqualError(t, err, "this document already exists")
+
+		_, err = repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past, Timezone: "Europe/Moscow"})
+		assert.NoError(t, err, "same date pinned to a zone is a different task")
+	})
+
+	t.Run("update_duplicate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past})
+		require.NoError(t, err)
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "Оплатить счет", ActiveAt: past})
+		require.NoError(t, err)
+
+		err = repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "Купить книгу", ActiveAt: past}, id)
+		assert.EqualError(t, err, "this document already exists", "an update may not turn a task into a duplicate")
+
+		err = repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "Оплатить счет", ActiveAt: future}, id)
+		assert.NoError(t, err)
+		err = repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "Оплатить счет", ActiveAt: future}, id)
+		assert.NoError(t, err, "saving a task unchanged is not a duplicate of itself")
+
+		assert.Equal(t, []string{"Купить книгу"}, active(t, repo, time.UTC))
+	})
+
+	t.Run("count", func(t *testing.T) {
+		repo := newRepo(t)
+		at := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+		for _, task := range []entity.Task{
+			{Status: "active", Title: "overdue", ActiveAt: "2023-08-01"},
+			{Status: "active", Title: "overdue timed", ActiveAt: "2023-08-09T23:00:00Z"},
+			{Status: "active", Title: "today", ActiveAt: "2023-08-10"},
+			{Status: "active", Title: "later today", ActiveAt: "2023-08-10T15:00:00Z"},
+			{Status: "active", Title: "tomorrow", ActiveAt: "2023-08-11"},
+		} {
+			_, err := repo.CreateTask(ctx, task)
+			require.NoError(t, err)
+		}
+
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "done", ActiveAt: "2023-08-02"})
+		require.NoError(t, err)
+		_, err = repo.StatusUpdate(ctx, id)
+		require.NoError(t, err)
+
+		counts, err := repo.CountTasks(ctx, at)
+		require.NoError(t, err)
+		assert.Equal(t, entity.TaskCounts{Active: 3, Overdue: 2}, counts)
+	})
+
+	t.Run("invalid_date", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "a", ActiveAt: "15.08.2023"})
+		assert.Error(t, err)
+	})
+
+	t.Run("no_record_found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, missing := range []entity.TaskID{mustTaskID(primitive.NewObjectID().Hex()), mustTaskID("42"), mustTaskID("not-an-id"), {}} {
+			assert.EqualError(t, repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "a", ActiveAt: past}, missing), "no record found", missing)
+			assert.EqualError(t, repo.DeleteTask(ctx, missing), "no record found", missing)
+			_, err := repo.StatusUpdate(ctx, missing)
+			assert.EqualError(t, err, "no record found", missing)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "a", ActiveAt: past})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "b", ActiveAt: future}, id))
+		assert.Empty(t, active(t, repo, time.UTC))
+
+		require.NoError(t, repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "c", ActiveAt: past}, id))
+		assert.Equal(t, []string{"c"}, active(t, repo, time.UTC))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "a", ActiveAt: past})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.DeleteTask(ctx, id))
+		assert.Empty(t, active(t, repo, time.UTC))
+		assert.EqualError(t, repo.DeleteTask(ctx, id), "no record found")
+	})
+
+	t.Run("status_update", func(t *testing.T) {
+		repo := newRepo(t)
+
+		id, err := repo.CreateTask(ctx, entity.Task{Status: "active", Title: "a", ActiveAt: past})
+		require.NoError(t, err)
+		_, err = repo.CreateTask(ctx, entity.Task{Status: "active", Title: "b", ActiveAt: past})
+		require.NoError(t, err)
+
+		changed, err := repo.StatusUpdate(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, []string{"b"}, active(t, repo, time.UTC))
+
+		tasks, err := repo.GetTasks(ctx, "done", time.UTC)
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, "a", tasks[0].Title)
+		assert.Empty(t, tasks[0].Status)
+		require.NotNil(t, tasks[0].DoneAt)
+		assert.WithinDuration(t, time.Now(), *tasks[0].DoneAt, time.Minute)
+
+		changed, err = repo.StatusUpdate(ctx, id)
+		require.NoError(t, err)
+		assert.False(t, changed, "a done task is not done again")
+
+		again, err := repo.GetTasks(ctx, "done", time.UTC)
+		require.NoError(t, err)
+		assert.Equal(t, tasks[0].DoneAt, again[0].DoneAt, "the first completion time is kept")
+	})
+
+	t.Run("incorrect_status", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetTasks(ctx, "Active", time.UTC)
+		assert.EqualError(t, err, "incorrect url query")
+	})
+
+	t.Run("active_filtering", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, task := range []entity.Task{
+			{Status: "active", Title: "past", ActiveAt: past},
+			{Status: "active", Title: "future", ActiveAt: future},
+			{Status: "active", Title: "started", ActiveAt: now.Add(-time.Hour).UTC().Format(time.RFC3339)},
+			{Status: "active", Title: "later", ActiveAt: now.Add(time.Hour).UTC().Format(time.RFC3339)},
+		} {
+			_, err := repo.CreateTask(ctx, task)
+			require.NoError(t, err)
+		}
+
+		assert.ElementsMatch(t, []string{"past", "started"}, active(t, repo, time.UTC))
+	})
+
+	t.Run("active_in_user_timezone", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Киритимати (UTC+14) всегда хотя бы на день впереди UTC-12.
+		ahead, err := time.LoadLocation("Pacific/Kiritimati")
+		require.NoError(t, err)
+		behind, err := time.LoadLocation("Etc/GMT+12")
+		require.NoError(t, err)
+
+		today := now.In(ahead).Format(entity.DateLayout)
+
+		_, err = repo.CreateTask(ctx, entity.Task{Status: "active", Title: "floating", ActiveAt: today})
+		require.NoError(t, err)
+		_, err = repo.CreateTask(ctx, entity.Task{Status: "active", Title: "pinned", ActiveAt: today, Timezone: "Pacific/Kiritimati"})
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"floating", "pinned"}, active(t, repo, ahead))
+		// Привязанная задача началась в полночь по Киритимати для всех.
+		assert.ElementsMatch(t, []string{"pinned"}, active(t, repo, behind))
+	})
+
+	t.Run("sorted_by_start", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, task := range []entity.Task{
+			{Status: "active", Title: "third", ActiveAt: now.AddDate(0, 0, -1).UTC().Format(entity.DateLayout)},
+			{Status: "active", Title: "first", ActiveAt: now.AddDate(0, 0, -5).UTC().Format(entity.DateLayout)},
+			{Status: "active", Title: "second", ActiveAt: now.AddDate(0, 0, -3).UTC().Format(entity.DateLayout)},
+			{Status: "active", Title: "second too", ActiveAt: now.AddDate(0, 0, -3).UTC().Format(entity.DateLayout)},
+		} {
+			_, err := repo.CreateTask(ctx, task)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, []string{"first", "second", "second too", "third"}, active(t, repo, time.UTC))
+	})
+
+	t.Run("round_trip", func(t *testing.T) {
+		repo := newRepo(t)
+
+		task := entity.Task{
+			Status:   "active",
+			Title:    "Созвон",
+			ActiveAt: "2023-08-15T09:30:00+03:00",
+			Timezone: "Europe/Moscow",
+			RemindAt: []time.Time{time.Date(2023, 8, 15, 6, 0, 0, 0, time.UTC)},
+		}
+		_, err := repo.CreateTask(ctx, task)
+		require.NoError(t, err)
+
+		tasks, err := repo.GetTasks(ctx, "active", time.UTC)
+		require.NoError(t, err)
+		task.Status = ""
+		assert.Equal(t, []entity.Task{task}, tasks)
+	})
+}
+
+func TestMemoryTaskContract(t *testing.T) {
+	for _, kind := range []string{entity.TaskIDObjectID, entity.TaskIDUUIDv7, entity.TaskIDULID} {
+		t.Run(kind, func(t *testing.T) {
+			testTaskContract(t, func(t *testing.T) Task {
+				return NewMemoryTaskRepository(newTaskIDs(t, kind))
+			})
+		})
+	}
+}
+
+// TestMongoTaskContract проверяет контракт на настоящем наборе реплик
+// MongoDB: локальном mongod, запущенном для теста, или на указанном в
+// TODO_TEST_MONGO_URI (make test-integration берет его из docker-compose).
+func TestMongoTaskContract(t *testing.T) {
+	uri := mongotest.URI(t)
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	n := 0
+	testTaskContract(t, func(t *testing.T) Task {
+		n++
+		db := client.Database(fmt.Sprintf("todo_contract_%d_%d", time.Now().UnixNano(), n))
+		t.Cleanup(func() { _ = db.Drop(ctx) })
+
+		// На старых серверах транзакции не могут создавать коллекции.
+		require.NoError(t, db.CreateCollection(ctx, tasksCollection))
+		require.NoError(t, db.CreateCollection(ctx, outboxCollection))
+
+		return NewTaskRepoistory(db, newTaskIDs(t, entity.TaskIDObjectID))
+	})
+}
+
+// TestPostgresTaskContract проверяет контракт на PostgreSQL, каждый подтест в
+// своей схеме, мигрированной встроенными SQL-миграциями: на встроенном
+// сервере, запущенном для теста, или на указанном в TODO_TEST_POSTGRES_DSN.
+func TestPostgresTaskContract(t *testing.T) {
+	testTaskContract(t, postgresTasks(t, postgrestest.DSN(t)))
+}
+
+// TestPostgresTaskRepository_concurrentDuplicates проверяет, что уникальный
+// индекс отклоняет дубликаты, одновременно прошедшие проверку NOT EXISTS.
+func TestPostgresTaskRepository_concurrentDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := postgresTasks(t, postgrestest.DSN(t))(t)
+	task := entity.Task{Title: "Купить книгу", ActiveAt: "2023-08-15", Status: "active"}
+
+	const n = 8
+	errs := make(chan error, n)
+	var start sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < n; i++ {
+		go func() {
+			start.Wait()
+			_, err := repo.CreateTask(ctx, task)
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	created := 0
+	for i := 0; i < n; i++ {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			assert.EqualError(t, err, "this document already exists")
+		}
+	}
+	assert.Equal(t, 1, created)
+
+	other := task
+	other.Title = "Позвонить"
+	id, err := repo.CreateTask(ctx, other)
+	require.NoError(t, err)
+	assert.EqualError(t, repo.UpdateTask(ctx, task, id), "this document already exists")
+}
+
+// postgresTasks возвращает конструктор репозиториев задач, каждый в своей
+// схеме сервера dsn, мигрированной встроенными SQL-миграциями.
+func postgresTasks(t *testing.T, dsn string) func(t *testing.T) Task {
+	ctx := context.Background()
+	cfg, err := pgx.ParseConfig(dsn)
+	require.NoError(t, err)
+
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+
+	n := 0
+	return func(t *testing.T) Task {
+		n++
+		schema := fmt.Sprintf("todo_contract_%d_%d", time.Now().UnixNano(), n)
+		_, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema)
+		require.NoError(t, err)
+		t.Cleanup(func() { _, _ = admin.ExecContext(ctx, `DROP SCHEMA `+schema+` CASCADE`) })
+
+		schemaCfg := cfg.Copy()
+		schemaCfg.RuntimeParams["search_path"] = schema
+		db := stdlib.OpenDB(*schemaCfg)
+		t.Cleanup(func() { db.Close() })
+
+		m, err := migrations.NewSQL(db, migrations.Postgres, migrations.PostgresMigrations(), config.MigrationsConfig{}, logger.New("error"))
+		require.NoError(t, err)
+		_, err = m.Up(ctx)
+		require.NoError(t, err)
+
+		return NewPostgresTaskRepository(db)
+	}
+}
+
+func TestSQLiteTaskContract(t *testing.T) {
+	testTaskContract(t, func(t *testing.T) Task {
+		return newTestSQLite(t)
+	})
+}
+
+func newTaskIDs(t *testing.T, kind string) entity.TaskIDGenerator {
+	t.Helper()
+
+	ids, err := entity.NewTaskIDGenerator(kind)
+	require.NoError(t, err)
+	return ids
+}
+
+func mustTaskID(s string) entity.TaskID {
+	id, err := entity.ParseTaskID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
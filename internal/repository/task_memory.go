@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+type memoryTask struct {
+	id  entity.TaskID
+	seq int
+	doc taskDocument
+}
+
+// memoryTaskRepository хранит задачи в памяти процесса. Поведение совпадает
+// с taskRepository, кроме событий задач: в outbox они не пишутся.
+type memoryTaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[entity.TaskID]*memoryTask
+	seq   int
+	ids   entity.TaskIDGenerator
+	now   func() time.Time
+}
+
+func NewMemoryTaskRepository(ids entity.TaskIDGenerator) *memoryTaskRepository {
+	return &memoryTaskRepository{
+		tasks: make(map[entity.TaskID]*memoryTask),
+		ids:   ids,
+		now:   time.Now,
+	}
+}
+
+// CreateTask создает новую задачу.
+func (r *memoryTaskRepository) CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error) {
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+	doc = doc.truncate()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.duplicate(doc, entity.TaskID{}) {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return entity.TaskID{}, errors.New("this document already exists")
+	}
+
+	r.seq++
+	id := r.ids.NewTaskID()
+	r.tasks[id] = &memoryTask{id: id, seq: r.seq, doc: doc}
+
+	return id, nil
+}
+
+// UpdateTask заменяет задачу по ее идентификатору. Как и CreateTask,
+// отклоняет изменение, после которого задача совпала бы с другой.
+func (r *memoryTaskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error {
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[taskId]
+	if !ok {
+		return errors.New("no record found")
+	}
+	doc = doc.truncate()
+	if r.duplicate(doc, taskId) {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return errors.New("this document already exists")
+	}
+	t.doc = doc
+
+	return nil
+}
+
+// duplicate сообщает, есть ли кроме задачи except другая с теми же названием
+// и началом. Вызывается под r.mu.
+func (r *memoryTaskRepository) duplicate(doc taskDocument, except entity.TaskID) bool {
+	for id, t := range r.tasks {
+		if id != except && t.doc.Title == doc.Title && t.doc.ActiveAt.Equal(doc.ActiveAt) && t.doc.AllDay == doc.AllDay && t.doc.Timezone == doc.Timezone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteTask удаляет задачу по ее идентификатору.
+func (r *memoryTaskRepository) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[taskId]; !ok {
+		return errors.New("no record found")
+	}
+	delete(r.tasks, taskId)
+
+	return nil
+}
+
+// StatusUpdate отмечает задачу выполненной, если она еще не выполнена.
+func (r *memoryTaskRepository) StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[taskId]
+	if !ok {
+		return false, errors.New("no record found")
+	}
+	if t.doc.Status == done {
+		return false, nil
+	}
+
+	doneAt := r.now().UTC().Truncate(time.Millisecond)
+	t.doc.Status = done
+	t.doc.DoneAt = &doneAt
+
+	return true, nil
+}
+
+// CountTasks считает активные и просроченные задачи на момент now.
+func (r *memoryTaskRepository) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	today := startOfDay(now)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var counts entity.TaskCounts
+	for _, t := range r.tasks {
+		if t.doc.activeBy(now, time.UTC) {
+			counts.Active++
+		}
+		if t.doc.Status == active && t.doc.ActiveAt.Before(today) {
+			counts.Overdue++
+		}
+	}
+
+	return counts, nil
+}
+
+// GetTasks возвращает список задач с определенным статусом, отсортированный
+// по дате начала. Задачи с одинаковой датой идут в порядке создания.
+func (r *memoryTaskRepository) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	var match func(taskDocument) bool
+
+	if status == active {
+		now := r.now()
+		match = func(doc taskDocument) bool { return doc.activeBy(now, loc) }
+	} else if status == done {
+		match = func(doc taskDocument) bool { return doc.Status == done }
+	} else {
+		return nil, errors.New("incorrect url query")
+	}
+
+	r.mu.RLock()
+	var found []*memoryTask
+	for _, t := range r.tasks {
+		if match(t.doc) {
+			found = append(found, t)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].doc.ActiveAt.Equal(found[j].doc.ActiveAt) {
+			return found[i].doc.ActiveAt.Before(found[j].doc.ActiveAt)
+		}
+		return found[i].seq < found[j].seq
+	})
+
+	var tasks []entity.Task
+	for _, t := range found {
+		task := t.doc.task()
+		task.Status = ""
+		tasks = append(tasks, task)
+	}
+	r.mu.RUnlock()
+
+	return tasks, nil
+}
+
+// activeBy повторяет фильтр activeBy для одной задачи.
+func (d taskDocument) activeBy(at time.Time, loc *time.Location) bool {
+	if d.Status != active {
+		return false
+	}
+
+	if d.AllDay && d.Timezone == "" {
+		local := at.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		return !d.ActiveAt.After(today)
+	}
+
+	return !d.ActiveAt.After(at)
+}
+
+// truncate приводит даты к точности BSON, чтобы задачи в памяти сравнивались
+// так же, как в базе.
+func (d taskDocument) truncate() taskDocument {
+	d.ActiveAt = d.ActiveAt.Truncate(time.Millisecond)
+	if d.DoneAt != nil {
+		doneAt := d.DoneAt.UTC().Truncate(time.Millisecond)
+		d.DoneAt = &doneAt
+	}
+	if d.RemindAt != nil {
+		remindAt := make([]time.Time, len(d.RemindAt))
+		for i, t := range d.RemindAt {
+			remindAt[i] = t.UTC().Truncate(time.Millisecond)
+		}
+		d.RemindAt = remindAt
+	}
+
+	return d
+}
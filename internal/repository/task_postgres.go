@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// postgresTaskRepository хранит задачи в таблице tasks PostgreSQL (см.
+// migrations/postgres). Идентификаторы задач - числа из столбца id.
+type postgresTaskRepository struct {
+	db    *sql.DB
+	types *pgtype.Map
+	now   func() time.Time
+}
+
+func NewPostgresTaskRepository(db *sql.DB) *postgresTaskRepository {
+	return &postgresTaskRepository{
+		db:    db,
+		types: pgtype.NewMap(),
+		now:   time.Now,
+	}
+}
+
+// CreateTask создает новую задачу. Проверка на дубликат и вставка сделаны
+// одним запросом; дубликат, вставленный параллельным запросом, отклоняет
+// уникальный индекс (migrations/postgres/0002_unique_tasks).
+func (r *postgresTaskRepository) CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error) {
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+
+	var id int64
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO tasks (status, title, active_at, all_day, timezone, remind_at, done_at)
+		SELECT $1::text, $2::text, $3::timestamptz, $4::boolean, $5::text, $6::timestamptz[], $7::timestamptz
+		WHERE NOT EXISTS (
+			SELECT 1 FROM tasks
+			WHERE title = $2 AND active_at = $3 AND all_day = $4 AND timezone = $5
+		)
+		RETURNING id`,
+		doc.Status, doc.Title, doc.ActiveAt, doc.AllDay, doc.Timezone, doc.RemindAt, doc.DoneAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) || uniqueViolation(err) {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return entity.TaskID{}, errors.New("this document already exists")
+	}
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+
+	return sqlTaskIDFrom(id), nil
+}
+
+// UpdateTask заменяет задачу по ее идентификатору. Изменение, после которого
+// задача совпала бы с другой, отклоняет уникальный индекс.
+func (r *postgresTaskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error {
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return err
+	}
+
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = $2, title = $3, active_at = $4, all_day = $5, timezone = $6, remind_at = $7, done_at = $8
+		WHERE id = $1`,
+		id, doc.Status, doc.Title, doc.ActiveAt, doc.AllDay, doc.Timezone, doc.RemindAt, doc.DoneAt,
+	)
+	if uniqueViolation(err) {
+		return errors.New("this document already exists")
+	}
+
+	return affectedOne(res, err)
+}
+
+// DeleteTask удаляет задачу по ее идентификатору.
+func (r *postgresTaskRepository) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = $1`, id)
+
+	return affectedOne(res, err)
+}
+
+// StatusUpdate отмечает задачу выполненной, если она еще не выполнена.
+func (r *postgresTaskRepository) StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error) {
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx, `UPDATE tasks SET status = $2, done_at = $3 WHERE id = $1 AND status <> $2`, id, done, r.now().UTC())
+
+	return changedOne(ctx, r.db, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1)`, id, res, err)
+}
+
+// CountTasks считает активные и просроченные задачи на момент now.
+func (r *postgresTaskRepository) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	today := startOfDay(now)
+
+	var counts entity.TaskCounts
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE active_at <= $2),
+			COUNT(*) FILTER (WHERE active_at < $3)
+		FROM tasks
+		WHERE status = $1`,
+		active, now.UTC(), today,
+	).Scan(&counts.Active, &counts.Overdue)
+
+	return counts, err
+}
+
+// GetTasks возвращает список задач с определенным статусом, отсортированный
+// по дате начала. Условие для активных задач повторяет activeBy.
+func (r *postgresTaskRepository) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	var (
+		where string
+		args  []interface{}
+	)
+
+	if status == active {
+		now := r.now()
+		local := now.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+		where = `status = $1 AND (
+			(NOT (all_day AND timezone = '') AND active_at <= $2) OR
+			(all_day AND timezone = '' AND active_at <= $3)
+		)`
+		args = []interface{}{active, now.UTC(), today}
+	} else if status == done {
+		where = `status = $1`
+		args = []interface{}{done}
+	} else {
+		return nil, errors.New("incorrect url query")
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT title, active_at, all_day, timezone, remind_at, done_at
+		FROM tasks
+		WHERE `+where+`
+		ORDER BY active_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []entity.Task
+	for rows.Next() {
+		var (
+			doc    taskDocument
+			doneAt sql.NullTime
+		)
+		if err := rows.Scan(&doc.Title, &doc.ActiveAt, &doc.AllDay, &doc.Timezone, r.types.SQLScanner(&doc.RemindAt), &doneAt); err != nil {
+			return nil, err
+		}
+
+		doc.ActiveAt = doc.ActiveAt.UTC()
+		for i, t := range doc.RemindAt {
+			doc.RemindAt[i] = t.UTC()
+		}
+		if doneAt.Valid {
+			t := doneAt.Time.UTC()
+			doc.DoneAt = &t
+		}
+
+		tasks = append(tasks, doc.task())
+	}
+
+	return tasks, rows.Err()
+}
+
+// uniqueViolation сообщает, что запись нарушила уникальный индекс задач.
+func uniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
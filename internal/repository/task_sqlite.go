@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// searchLimit ограничивает число задач в результатах поиска.
+const searchLimit = 100
+
+// sqliteTaskRepository хранит задачи в таблице tasks SQLite (см.
+// migrations/sqlite). Даты хранятся в миллисекундах Unix, а заголовки
+// индексируются FTS5 для поиска.
+type sqliteTaskRepository struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewSQLiteTaskRepository(db *sql.DB) *sqliteTaskRepository {
+	return &sqliteTaskRepository{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// sqliteRow - столбцы задачи в том виде, в котором их принимает SQLite.
+type sqliteRow struct {
+	Status   string
+	Title    string
+	ActiveAt int64
+	AllDay   bool
+	Timezone string
+	RemindAt sql.NullString
+	DoneAt   sql.NullInt64
+}
+
+func newSQLiteRow(task entity.Task) (sqliteRow, error) {
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return sqliteRow{}, err
+	}
+	doc = doc.truncate()
+
+	row := sqliteRow{
+		Status:   doc.Status,
+		Title:    doc.Title,
+		ActiveAt: doc.ActiveAt.UnixMilli(),
+		AllDay:   doc.AllDay,
+		Timezone: doc.Timezone,
+	}
+
+	if len(doc.RemindAt) > 0 {
+		remindAt, err := json.Marshal(doc.RemindAt)
+		if err != nil {
+			return sqliteRow{}, err
+		}
+		row.RemindAt = sql.NullString{String: string(remindAt), Valid: true}
+	}
+
+	if doc.DoneAt != nil {
+		row.DoneAt = sql.NullInt64{Int64: doc.DoneAt.UnixMilli(), Valid: true}
+	}
+
+	return row, nil
+}
+
+func (r sqliteRow) task() (entity.Task, error) {
+	doc := taskDocument{
+		Status:   r.Status,
+		Title:    r.Title,
+		ActiveAt: time.UnixMilli(r.ActiveAt).UTC(),
+		AllDay:   r.AllDay,
+		Timezone: r.Timezone,
+	}
+
+	if r.RemindAt.Valid {
+		if err := json.Unmarshal([]byte(r.RemindAt.String), &doc.RemindAt); err != nil {
+			return entity.Task{}, err
+		}
+		for i, t := range doc.RemindAt {
+			doc.RemindAt[i] = t.UTC()
+		}
+	}
+
+	if r.DoneAt.Valid {
+		doneAt := time.UnixMilli(r.DoneAt.Int64).UTC()
+		doc.DoneAt = &doneAt
+	}
+
+	return doc.task(), nil
+}
+
+// CreateTask создает новую задачу. Проверка на дубликат и вставка сделаны
+// одним запросом.
+func (r *sqliteTaskRepository) CreateTask(ctx context.Context, task entity.Task) (entity.TaskID, error) {
+	row, err := newSQLiteRow(task)
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+
+	var id int64
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO tasks (status, title, active_at, all_day, timezone, remind_at, done_at)
+		SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7
+		WHERE NOT EXISTS (
+			SELECT 1 FROM tasks
+			WHERE title = ?2 AND active_at = ?3 AND all_day = ?4 AND timezone = ?5
+		)
+		RETURNING id`,
+		row.Status, row.Title, row.ActiveAt, row.AllDay, row.Timezone, row.RemindAt, row.DoneAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+		return entity.TaskID{}, errors.New("this document already exists")
+	}
+	if err != nil {
+		return entity.TaskID{}, err
+	}
+
+	return sqlTaskIDFrom(id), nil
+}
+
+// UpdateTask заменяет задачу по ее идентификатору. Как и CreateTask,
+// отклоняет изменение, после которого задача совпала бы с другой.
+func (r *sqliteTaskRepository) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) error {
+	row, err := newSQLiteRow(task)
+	if err != nil {
+		return err
+	}
+
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = ?2, title = ?3, active_at = ?4, all_day = ?5, timezone = ?6, remind_at = ?7, done_at = ?8
+		WHERE id = ?1 AND NOT EXISTS (
+			SELECT 1 FROM tasks
+			WHERE title = ?3 AND active_at = ?4 AND all_day = ?5 AND timezone = ?6 AND id <> ?1
+		)`,
+		id, row.Status, row.Title, row.ActiveAt, row.AllDay, row.Timezone, row.RemindAt, row.DoneAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n == 1 {
+		return err
+	}
+
+	// Ничего не изменилось: задачи нет или изменение сделало бы ее дубликатом.
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("no record found")
+	}
+
+	logger.FromContext(ctx).Package("repository").Info("duplicate task rejected")
+	return errors.New("this document already exists")
+}
+
+// DeleteTask удаляет задачу по ее идентификатору.
+func (r *sqliteTaskRepository) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id)
+
+	return affectedOne(res, err)
+}
+
+// StatusUpdate отмечает задачу выполненной, если она еще не выполнена.
+func (r *sqliteTaskRepository) StatusUpdate(ctx context.Context, taskId entity.TaskID) (bool, error) {
+	id, err := sqlTaskID(taskId)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx, `UPDATE tasks SET status = ?1, done_at = ?2 WHERE id = ?3 AND status <> ?1`, done, r.now().UnixMilli(), id)
+
+	return changedOne(ctx, r.db, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ?)`, id, res, err)
+}
+
+// GetTasks возвращает список задач с определенным статусом, отсортированный
+// по дате начала. Условие для активных задач повторяет activeBy.
+func (r *sqliteTaskRepository) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
+	var (
+		where string
+		args  []interface{}
+	)
+
+	if status == active {
+		now := r.now()
+		local := now.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+		where = `status = ?1 AND (
+			(NOT (all_day AND timezone = '') AND active_at <= ?2) OR
+			(all_day AND timezone = '' AND active_at <= ?3)
+		)`
+		args = []interface{}{active, now.UnixMilli(), today.UnixMilli()}
+	} else if status == done {
+		where = `status = ?1`
+		args = []interface{}{done}
+	} else {
+		return nil, errors.New("incorrect url query")
+	}
+
+	tasks, err := r.query(ctx, `
+		SELECT status, title, active_at, all_day, timezone, remind_at, done_at
+		FROM tasks
+		WHERE `+where+`
+		ORDER BY active_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		tasks[i].Status = ""
+	}
+
+	return tasks, nil
+}
+
+// CountTasks считает активные и просроченные задачи на момент now.
+func (r *sqliteTaskRepository) CountTasks(ctx context.Context, now time.Time) (entity.TaskCounts, error) {
+	today := startOfDay(now)
+
+	var counts entity.TaskCounts
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(active_at <= ?2), 0),
+			COALESCE(SUM(active_at < ?3), 0)
+		FROM tasks
+		WHERE status = ?1`,
+		active, now.UnixMilli(), today.UnixMilli(),
+	).Scan(&counts.Active, &counts.Overdue)
+
+	return counts, err
+}
+
+// SearchTasks ищет задачи по словам заголовка, самые подходящие первыми.
+// Каждое слово запроса ищется как префикс: "кни" находит "Купить книгу".
+func (r *sqliteTaskRepository) SearchTasks(ctx context.Context, query string) ([]entity.Task, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	return r.query(ctx, `
+		SELECT t.status, t.title, t.active_at, t.all_day, t.timezone, t.remind_at, t.done_at
+		FROM tasks_fts
+		JOIN tasks t ON t.id = tasks_fts.rowid
+		WHERE tasks_fts MATCH ?
+		ORDER BY tasks_fts.rank, t.active_at, t.id
+		LIMIT ?`, match, searchLimit)
+}
+
+func (r *sqliteTaskRepository) query(ctx context.Context, query string, args ...interface{}) ([]entity.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []entity.Task
+	for rows.Next() {
+		var row sqliteRow
+		if err := rows.Scan(&row.Status, &row.Title, &row.ActiveAt, &row.AllDay, &row.Timezone, &row.RemindAt, &row.DoneAt); err != nil {
+			return nil, err
+		}
+
+		task, err := row.task()
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// ftsQuery превращает введенный пользователем текст в запрос FTS5: слова
+// берутся в кавычки, чтобы операторы FTS5 в них не работали, и ищутся по префиксу.
+func ftsQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
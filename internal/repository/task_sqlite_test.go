@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/repository/migrations"
+	"github.com/yervsil/toDo-microservice/pkg/database/sqlite"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// newTestSQLite возвращает репозиторий на новом мигрированном файле базы.
+func newTestSQLite(t *testing.T) *sqliteTaskRepository {
+	t.Helper()
+
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrations.NewSQL(db, migrations.SQLite, migrations.SQLiteMigrations(), config.MigrationsConfig{}, logger.New("error"))
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	return NewSQLiteTaskRepository(db)
+}
+
+func TestSQLiteSearchTasks(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLite(t)
+
+	ids := map[string]entity.TaskID{}
+	for _, task := range []entity.Task{
+		{Status: "active", Title: "Купить книгу", ActiveAt: "2023-08-01"},
+		{Status: "active", Title: "Прочитать книгу", ActiveAt: "2023-08-02"},
+		{Status: "active", Title: "Позвонить маме", ActiveAt: "2023-08-03"},
+		{Status: "active", Title: `Release "v2" notes`, ActiveAt: "2023-08-04"},
+	} {
+		id, err := repo.CreateTask(ctx, task)
+		require.NoError(t, err)
+		ids[task.Title] = id
+	}
+
+	search := func(query string) []string {
+		t.Helper()
+		tasks, err := repo.SearchTasks(ctx, query)
+		require.NoError(t, err)
+
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	assert.ElementsMatch(t, []string{"Купить книгу", "Прочитать книгу"}, search("книгу"))
+	assert.ElementsMatch(t, []string{"Купить книгу", "Прочитать книгу"}, search("КНИ"), "prefix, any case")
+	assert.Equal(t, []string{"Купить книгу"}, search("купить книгу"), "all words must match")
+	assert.Equal(t, []string{`Release "v2" notes`}, search(`"v2`), "quotes are not operators")
+	assert.Empty(t, search("NOT OR AND"))
+	assert.Empty(t, search("   "))
+
+	t.Run("index_follows_writes", func(t *testing.T) {
+		require.NoError(t, repo.UpdateTask(ctx, entity.Task{Status: "active", Title: "Позвонить папе", ActiveAt: "2023-08-03"}, ids["Позвонить маме"]))
+		assert.Empty(t, search("маме"))
+		assert.Equal(t, []string{"Позвонить папе"}, search("папе"))
+
+		_, err := repo.StatusUpdate(ctx, ids["Купить книгу"])
+		require.NoError(t, err)
+		tasks, err := repo.SearchTasks(ctx, "купить")
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, "done", tasks[0].Status)
+
+		require.NoError(t, repo.DeleteTask(ctx, ids["Прочитать книгу"]))
+		assert.Equal(t, []string{"Купить книгу"}, search("книгу"))
+	})
+}
+
+// TestSQLiteRepository_searchCanceled проверяет, что поиск проходит через
+// декораторы задач: их таймауты прерывают его, и об отмене он сообщает, как
+// остальные операции.
+func TestSQLiteRepository_searchCanceled(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrations.NewSQL(db, migrations.SQLite, migrations.SQLiteMigrations(), config.MigrationsConfig{}, logger.New("error"))
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo := NewSQLiteRepository(db)
+	_, err = repo.SearchTasks(ctx, "книгу")
+
+	var canceledErr *CanceledError
+	require.ErrorAs(t, err, &canceledErr)
+	assert.Equal(t, "SearchTasks", canceledErr.Op)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Таймаут searchTasks доходит до вызова базы.
+	search := asSearch(decorateTask(blockingTasks{}, []TaskDecorator{WithTimeouts(0, config.RepositoryTimeoutsConfig{SearchTasks: 10 * time.Millisecond})}))
+	_, err = search.SearchTasks(context.Background(), "книгу")
+	require.ErrorAs(t, err, &canceledErr)
+	assert.Equal(t, "SearchTasks", canceledErr.Op)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"testing"
+	"time"
 
 
 	"github.com/stretchr/testify/assert"
@@ -30,20 +31,49 @@ func TestCreateTask(t *testing.T) {
 		first := mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch)
 		killCursors := mtest.CreateCursorResponse(0, "test.task", mtest.NextBatch)
 		mt.AddMockResponses(first, killCursors)
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		repo := &taskRepository{
+			db: mt.Coll,
+			outbox: mt.Coll,
+			ids: newTaskIDs(t, entity.TaskIDObjectID),
+		}
+
+		insertedID, err := repo.CreateTask(context.Background(), newTask)
+		assert.Nil(t, err)
+		assert.False(t, insertedID.IsZero())
+
+		// Идентификаторы в виде ObjectID хранятся как ObjectID, как у задач,
+		// созданных до TaskID.
+		stored := insertedTaskID(mt)
+		assert.Equal(t, insertedID.String(), stored.ObjectID().Hex())
+	})
+
+	mt.Run("string_ids", func(mt *mtest.T) {
+		first := mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch)
+		killCursors := mtest.CreateCursorResponse(0, "test.task", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursors)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
+			ids: newTaskIDs(t, entity.TaskIDUUIDv7),
 		}
 
 		insertedID, err := repo.CreateTask(context.Background(), newTask)
 		assert.Nil(t, err)
-		assert.NotEqual(t, primitive.ObjectID{}, insertedID)
+
+		stored := insertedTaskID(mt)
+		assert.Equal(t, insertedID.String(), stored.StringValue())
 	})
 
 	mt.Run("duplicate_document", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateWriteErrorsResponse())
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "title", Value: newTask.Title},
+		}))
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		_, err := repo.CreateTask(context.Background(), newTask)
@@ -54,6 +84,7 @@ func TestCreateTask(t *testing.T) {
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		_, err := repo.CreateTask(context.Background(), newTask)
@@ -66,16 +97,20 @@ func TestUpdateTask(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	defer mt.Close()
 
-	taskID := primitive.NewObjectID()
+	taskID := mustTaskID(primitive.NewObjectID().Hex())
 	taskToUpdate := entity.Task{
 		Title:    "Updated Title",
 		ActiveAt: "2023-08-10",
 	}
 
 	mt.Run("success", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}}...))
+		first := mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch)
+		killCursors := mtest.CreateCursorResponse(0, "test.task", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursors)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}}...), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.UpdateTask(context.Background(), taskToUpdate, taskID)
@@ -83,19 +118,41 @@ func TestUpdateTask(t *testing.T) {
 	})
 
 	mt.Run("no_record_found", func(mt *mtest.T) {
+		first := mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch)
+		killCursors := mtest.CreateCursorResponse(0, "test.task", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursors)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.UpdateTask(context.Background(), taskToUpdate, taskID)
 		assert.Equal(t, "no record found", err.Error())
 	})
 
+	mt.Run("duplicate_document", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "title", Value: taskToUpdate.Title},
+		}))
+		repo := &taskRepository{
+			db: mt.Coll,
+			outbox: mt.Coll,
+		}
+
+		err := repo.UpdateTask(context.Background(), taskToUpdate, taskID)
+		assert.Equal(t, "this document already exists", err.Error())
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, mongoTaskID(taskID), filter.Lookup("_id", "$ne").ObjectID(), "the task itself is not a duplicate")
+	})
+
 	mt.Run("error", func(mt *mtest.T) {
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.UpdateTask(context.Background(), taskToUpdate, taskID)
@@ -107,12 +164,13 @@ func TestDeleteTask(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	defer mt.Close()
 
-	taskID := primitive.NewObjectID()
+	taskID := mustTaskID(primitive.NewObjectID().Hex())
 
 	mt.Run("success", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}}...))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}}...), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.DeleteTask(context.Background(), taskID)
@@ -123,6 +181,7 @@ func TestDeleteTask(t *testing.T) {
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.DeleteTask(context.Background(), taskID)
@@ -133,6 +192,7 @@ func TestDeleteTask(t *testing.T) {
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse())
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		err := repo.DeleteTask(context.Background(), taskID)
@@ -145,33 +205,53 @@ func TestStatusUpdate(t *testing.T) {
 	defer mt.Close()
 
 
-	taskID := primitive.NewObjectID()
+	taskID := mustTaskID(primitive.NewObjectID().Hex())
 
 	mt.Run("success", func(mt *mtest.T) {
-		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}}...))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 1}, {Key: "nModified", Value: 1}}...), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
 			
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 	
-		err := repo.StatusUpdate(context.Background(), taskID)
+		changed, err := repo.StatusUpdate(context.Background(), taskID)
 	
 		assert.Equal(t, nil, err)
+		assert.True(t, changed)
 	})
 
 	mt.Run("no_record_found", func(mt *mtest.T) {
 		
 
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch))
 			
 		repo := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 	
-		err := repo.StatusUpdate(context.Background(), taskID)
+		_, err := repo.StatusUpdate(context.Background(), taskID)
 
 		assert.Equal(t, "no record found", err.Error())
 	})
+
+	mt.Run("already_done", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.D{{Key: "n", Value: 0}, {Key: "nModified", Value: 0}}...),
+			mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateSuccessResponse(),
+		)
+		repo := &taskRepository{
+			db: mt.Coll,
+			outbox: mt.Coll,
+		}
+
+		changed, err := repo.StatusUpdate(context.Background(), taskID)
+		assert.Nil(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, []string{"update", "aggregate", "commitTransaction"}, commandNames(mt), "no event is written")
+	})
 }
 
 func TestGetTasks(t *testing.T) {
@@ -181,28 +261,33 @@ func TestGetTasks(t *testing.T) {
 	mt.Run("success", func(mt *mtest.T) {
 		want := []entity.Task{
 			{Title: "купить telephone", ActiveAt: "2022-07-30"},
-			{Title: "купить iphone", ActiveAt: "2023-07-30"},
+			{Title: "купить iphone", ActiveAt: "2023-07-30T12:00:00+06:00", Timezone: "Asia/Almaty"},
 		}
 
 		tr := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 	
 		first := mtest.CreateCursorResponse(1, "test.task", mtest.FirstBatch, bson.D{
 			{Key: "title", Value: "купить telephone"},
-			{Key: "activeat", Value: "2022-07-30"},
+			{Key: "activeat", Value: time.Date(2022, 7, 30, 0, 0, 0, 0, time.UTC)},
+			{Key: "allday", Value: true},
+			{Key: "timezone", Value: ""},
 		})
 
 		second := mtest.CreateCursorResponse(1, "test.task", mtest.NextBatch, bson.D{
 			{Key: "title", Value: "купить iphone"},
-			{Key: "activeat", Value: "2023-07-30"},
+			{Key: "activeat", Value: time.Date(2023, 7, 30, 6, 0, 0, 0, time.UTC)},
+			{Key: "allday", Value: false},
+			{Key: "timezone", Value: "Asia/Almaty"},
 		})
 		
 		killCursors := mtest.CreateCursorResponse(0, "test.task", mtest.NextBatch)
 		mt.AddMockResponses(first, second, killCursors)
 		status := "done"
 
-		got, err := tr.GetTasks(context.Background(), status)
+		got, err := tr.GetTasks(context.Background(), status, time.UTC)
 		if err != nil {
 			t.Fatalf("expected: no error, got: %v", err)
 		}
@@ -217,14 +302,132 @@ func TestGetTasks(t *testing.T) {
 		
 		tr := &taskRepository{
 			db: mt.Coll,
+			outbox: mt.Coll,
 		}
 
 		status := "Active"
 
-		_, got := tr.GetTasks(context.Background(), status)
+		_, got := tr.GetTasks(context.Background(), status, time.UTC)
 		
 		if !assert.Equal(t, got, want){
 			t.Fatalf("expected: %v, got: %v", want, got)
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestGetTasks_activeInUserTimezone(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("filter", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch))
+		tr := &taskRepository{db: mt.Coll}
+
+		// В Окленде уже завтра, поэтому плавающие задачи на завтра активны.
+		loc, err := time.LoadLocation("Pacific/Auckland")
+		assert.Nil(t, err)
+
+		before := time.Now()
+		_, err = tr.GetTasks(context.Background(), "active", loc)
+		assert.Nil(t, err)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "active", filter.Lookup("status").StringValue())
+
+		clauses, err := filter.Lookup("$or").Array().Values()
+		assert.Nil(t, err)
+		assert.Len(t, clauses, 3)
+
+		instant := clauses[0].Document().Lookup("activeat", "$lte").Time()
+		assert.WithinDuration(t, before, instant, time.Second)
+
+		local := instant.In(loc)
+		floating := clauses[2].Document()
+		assert.Equal(t, "", floating.Lookup("timezone").StringValue())
+		assert.Equal(t, time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC), floating.Lookup("activeat", "$lte").Time().UTC())
+	})
+}
+
+func TestCountTasks(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(3)}}),
+			mtest.CreateCursorResponse(0, "test.task", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(2)}}),
+		)
+		tr := &taskRepository{db: mt.Coll}
+
+		now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+		counts, err := tr.CountTasks(context.Background(), now)
+		assert.Nil(t, err)
+		assert.Equal(t, entity.TaskCounts{Active: 3, Overdue: 2}, counts)
+
+		// Просроченные - активные задачи, начавшиеся до полуночи UTC.
+		pipeline := mt.GetAllStartedEvents()[1].Command.Lookup("pipeline").Array()
+		match := pipeline.Index(0).Value().Document().Lookup("$match", "activeat", "$lt")
+		assert.Equal(t, time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC), match.Time().UTC())
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		tr := &taskRepository{db: mt.Coll}
+
+		_, err := tr.CountTasks(context.Background(), time.Now())
+		assert.Error(t, err)
+	})
+}
+
+func TestTaskDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		task entity.Task
+		want taskDocument
+	}{
+		{
+			name: "floating_all_day",
+			task: entity.Task{Title: "a", ActiveAt: "2023-08-15"},
+			want: taskDocument{Title: "a", ActiveAt: time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC), AllDay: true},
+		},
+		{
+			name: "all_day_in_timezone",
+			task: entity.Task{Title: "a", ActiveAt: "2023-08-15", Timezone: "Europe/Moscow"},
+			want: taskDocument{Title: "a", ActiveAt: time.Date(2023, 8, 14, 21, 0, 0, 0, time.UTC), AllDay: true, Timezone: "Europe/Moscow"},
+		},
+		{
+			name: "datetime",
+			task: entity.Task{Title: "a", ActiveAt: "2023-08-15T09:30:00+03:00", Timezone: "Europe/Moscow"},
+			want: taskDocument{Title: "a", ActiveAt: time.Date(2023, 8, 15, 6, 30, 0, 0, time.UTC), Timezone: "Europe/Moscow"},
+		},
+		{
+			name: "datetime_without_timezone",
+			task: entity.Task{Title: "a", ActiveAt: "2023-08-15T09:30:00Z"},
+			want: taskDocument{Title: "a", ActiveAt: time.Date(2023, 8, 15, 9, 30, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := newTaskDocument(test.task)
+			assert.Nil(t, err)
+			assert.Equal(t, test.want, doc)
+			assert.Equal(t, test.task, doc.task())
+		})
+	}
+
+	_, err := newTaskDocument(entity.Task{Title: "a", ActiveAt: "15.08.2023"})
+	assert.NotNil(t, err)
+}
+
+// insertedTaskID возвращает _id первой задачи, вставленной в коллекцию теста.
+func insertedTaskID(mt *mtest.T) bson.RawValue {
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName == "insert" && e.Command.Lookup("insert").StringValue() == mt.Coll.Name() {
+			return e.Command.Lookup("documents").Array().Index(0).Value().Document().Lookup("_id")
+		}
+	}
+
+	mt.Fatal("no insert into the task collection")
+	return bson.RawValue{}
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnsupported возвращают части репозитория, которые выбранный драйвер
+// базы данных не реализует.
+var ErrUnsupported = errors.New("not supported by this database driver")
+
+// unsupportedRepository заменяет outbox, вебхуки, напоминания и дайджесты в
+// драйверах, которые хранят только задачи, и поиск в драйверах без него.
+type unsupportedRepository struct{}
+
+func (unsupportedRepository) SearchTasks(ctx context.Context, query string) ([]entity.Task, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) AcquireMessages(ctx context.Context, now time.Time, owner string, lease time.Duration, limit int) ([]entity.OutboxMessage, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) DeleteMessage(ctx context.Context, messageId primitive.ObjectID, owner string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) RetryMessage(ctx context.Context, messageId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) FailMessage(ctx context.Context, messageId primitive.ObjectID, owner string, reason string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) CreateWebhook(ctx context.Context, webhook entity.Webhook) (primitive.ObjectID, error) {
+	return primitive.ObjectID{}, ErrUnsupported
+}
+
+func (unsupportedRepository) GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error) {
+	return entity.Webhook{}, ErrUnsupported
+}
+
+func (unsupportedRepository) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) DeleteWebhook(ctx context.Context, webhookId primitive.ObjectID) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error) {
+	return primitive.ObjectID{}, ErrUnsupported
+}
+
+func (unsupportedRepository) GetDelivery(ctx context.Context, deliveryId primitive.ObjectID) (entity.WebhookDelivery, error) {
+	return entity.WebhookDelivery{}, ErrUnsupported
+}
+
+func (unsupportedRepository) GetDeliveries(ctx context.Context, webhookId primitive.ObjectID) ([]entity.WebhookDelivery, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) ReplaceReminders(ctx context.Context, taskId string, title string, times []time.Time) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) CancelReminders(ctx context.Context, taskId string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) AcquireDueReminder(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.Reminder, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) CompleteReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, sentAt time.Time) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) RetryReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, retryAt time.Time, reason string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) FailReminder(ctx context.Context, reminderId primitive.ObjectID, owner string, reason string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) CreateSubscription(ctx context.Context, subscription entity.DigestSubscription) (primitive.ObjectID, error) {
+	return primitive.ObjectID{}, ErrUnsupported
+}
+
+func (unsupportedRepository) GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) GetEnabledSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) UpdateSubscription(ctx context.Context, subscription entity.DigestSubscription, subscriptionId primitive.ObjectID) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) DeleteSubscription(ctx context.Context, subscriptionId primitive.ObjectID) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) ClaimDigest(ctx context.Context, subscriptionId primitive.ObjectID, day string, now time.Time, owner string, lease time.Duration) (bool, error) {
+	return false, ErrUnsupported
+}
+
+func (unsupportedRepository) CompleteDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner, day string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) ReleaseDigest(ctx context.Context, subscriptionId primitive.ObjectID, owner string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedRepository) GetTasksActiveUntil(ctx context.Context, until time.Time, loc *time.Location) ([]entity.Task, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedRepository) GetTasksDoneBetween(ctx context.Context, from, to time.Time) ([]entity.Task, error) {
+	return nil, ErrUnsupported
+}
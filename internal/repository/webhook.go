@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type webhookRepository struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookRepository(db *mongo.Database) *webhookRepository {
+	return &webhookRepository{
+		webhooks:   db.Collection(webhooksCollection),
+		deliveries: db.Collection(deliveriesCollection),
+	}
+}
+
+// CreateWebhook сохраняет новую подписку.
+func (r *webhookRepository) CreateWebhook(ctx context.Context, webhook entity.Webhook) (primitive.ObjectID, error) {
+	webhook.ID = primitive.NewObjectID()
+
+	if _, err := r.webhooks.InsertOne(ctx, webhook); err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return webhook.ID, nil
+}
+
+// GetWebhook возвращает подписку по ее идентификатору.
+func (r *webhookRepository) GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error) {
+	var webhook entity.Webhook
+
+	err := r.webhooks.FindOne(ctx, bson.M{"_id": webhookId}).Decode(&webhook)
+	if err == mongo.ErrNoDocuments {
+		return entity.Webhook{}, errors.New("no record found")
+	}
+
+	return webhook, err
+}
+
+// GetWebhooks возвращает все подписки.
+func (r *webhookRepository) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	return r.findWebhooks(ctx, bson.M{})
+}
+
+// GetWebhooksByEvent возвращает подписки на указанное событие.
+func (r *webhookRepository) GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error) {
+	return r.findWebhooks(ctx, bson.M{"events": event})
+}
+
+// DeleteWebhook удаляет подписку вместе с историей ее доставок.
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, webhookId primitive.ObjectID) error {
+	res, err := r.webhooks.DeleteOne(ctx, bson.M{"_id": webhookId})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("no record found")
+	}
+
+	_, err = r.deliveries.DeleteMany(ctx, bson.M{"webhookid": webhookId})
+
+	return err
+}
+
+// CreateDelivery сохраняет новую доставку события. Если доставка события
+// delivery.EventID этому подписчику уже есть, возвращает ее идентификатор:
+// повторно опубликованное событие не отправляется еще раз.
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error) {
+	delivery.ID = primitive.NewObjectID()
+	if delivery.Attempts == nil {
+		delivery.Attempts = []entity.DeliveryAttempt{}
+	}
+
+	if delivery.EventID == "" {
+		if _, err := r.deliveries.InsertOne(ctx, delivery); err != nil {
+			return primitive.ObjectID{}, err
+		}
+
+		return delivery.ID, nil
+	}
+
+	filter := bson.M{"eventid": delivery.EventID, "webhookid": delivery.WebhookID}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"_id": 1})
+
+	var created struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := r.deliveries.FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": delivery}, opts).Decode(&created)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return created.ID, nil
+}
+
+// GetDelivery возвращает доставку по ее идентификатору.
+func (r *webhookRepository) GetDelivery(ctx context.Context, deliveryId primitive.ObjectID) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+
+	err := r.deliveries.FindOne(ctx, bson.M{"_id": deliveryId}).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return entity.WebhookDelivery{}, errors.New("no record found")
+	}
+
+	return delivery, err
+}
+
+// GetDeliveries возвращает историю доставок подписки, начиная с самых новых.
+func (r *webhookRepository) GetDeliveries(ctx context.Context, webhookId primitive.ObjectID) ([]entity.WebhookDelivery, error) {
+	return r.findDeliveries(ctx, bson.M{"webhookid": webhookId}, options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}}))
+}
+
+// AcquireDelivery атомарно берет в аренду самую старую ожидающую доставку,
+// время попытки которой наступило и которую никто не держит. Если таких нет,
+// возвращает nil.
+func (r *webhookRepository) AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error) {
+	filter := bson.M{
+		"status": entity.DeliveryPending,
+		"$or": bson.A{
+			bson.M{"leaseuntil": bson.M{"$exists": false}},
+			bson.M{"leaseuntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"leaseowner": owner, "leaseuntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdat", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery entity.WebhookDelivery
+	err := r.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// AddDeliveryAttempt дописывает попытку доставки и обновляет ее статус, если
+// аренда еще у owner. Доставка, которая осталась ожидающей, освобождается до
+// retryAt.
+func (r *webhookRepository) AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error {
+	update := bson.M{
+		"$push": bson.M{"attempts": attempt},
+		"$inc":  bson.M{"tries": 1},
+		"$set":  bson.M{"status": status, "leaseowner": "", "leaseuntil": retryAt},
+	}
+	if status != entity.DeliveryPending {
+		update["$set"] = bson.M{"status": status}
+		update["$unset"] = bson.M{"leaseowner": "", "leaseuntil": ""}
+	}
+
+	res, err := r.deliveries.UpdateOne(ctx, bson.M{"_id": deliveryId, "leaseowner": owner}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("webhook delivery lease lost")
+	}
+
+	return nil
+}
+
+// ResetDelivery возвращает завершенную доставку в ожидание с новым кругом
+// попыток. Ожидающая доставка и так будет отправлена: ее не трогаем, чтобы
+// не отнять аренду у того, кто ее сейчас отправляет.
+func (r *webhookRepository) ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error {
+	filter := bson.M{"_id": deliveryId, "status": bson.M{"$ne": entity.DeliveryPending}}
+	update := bson.M{
+		"$set":   bson.M{"status": entity.DeliveryPending, "tries": 0},
+		"$unset": bson.M{"leaseowner": "", "leaseuntil": ""},
+	}
+
+	_, err := r.deliveries.UpdateOne(ctx, filter, update)
+
+	return err
+}
+
+func (r *webhookRepository) findWebhooks(ctx context.Context, filter bson.M) ([]entity.Webhook, error) {
+	cursor, err := r.webhooks.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []entity.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) findDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]entity.WebhookDelivery, error) {
+	cursor, err := r.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []entity.WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
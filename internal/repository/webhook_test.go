@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAcquireDelivery(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	now := time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC)
+
+	mt.Run("success", func(mt *mtest.T) {
+		deliveryId := primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: deliveryId},
+				{Key: "event", Value: entity.EventTaskDone},
+				{Key: "status", Value: entity.DeliveryPending},
+				{Key: "tries", Value: 2},
+				{Key: "leaseowner", Value: "replica-1"},
+				{Key: "leaseuntil", Value: now.Add(time.Minute)},
+			}},
+		})
+		repo := &webhookRepository{deliveries: mt.Coll}
+
+		got, err := repo.AcquireDelivery(context.Background(), now, "replica-1", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, deliveryId, got.ID)
+		assert.Equal(t, 2, got.Tries)
+		assert.Equal(t, "replica-1", got.LeaseOwner)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, entity.DeliveryPending, cmd.Lookup("query", "status").StringValue())
+		assert.Equal(t, "replica-1", cmd.Lookup("update", "$set", "leaseowner").StringValue())
+		assert.Equal(t, now.Add(time.Minute), cmd.Lookup("update", "$set", "leaseuntil").Time().UTC())
+		assert.Equal(t, int32(1), cmd.Lookup("sort", "createdat").Int32())
+	})
+
+	mt.Run("nothing_due", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		repo := &webhookRepository{deliveries: mt.Coll}
+
+		got, err := repo.AcquireDelivery(context.Background(), now, "replica-1", time.Minute)
+		assert.Nil(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestCreateDelivery(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("once_per_event", func(mt *mtest.T) {
+		existing := primitive.NewObjectID()
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: existing}}}})
+		repo := &webhookRepository{deliveries: mt.Coll}
+		webhookId := primitive.NewObjectID()
+
+		id, err := repo.CreateDelivery(context.Background(), entity.WebhookDelivery{WebhookID: webhookId, EventID: "64d1c8747124f40af803840b", Event: entity.EventTaskDone})
+		assert.Nil(t, err)
+		assert.Equal(t, existing, id, "the delivery recorded by an earlier publication is kept")
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "64d1c8747124f40af803840b", cmd.Lookup("query", "eventid").StringValue())
+		assert.Equal(t, webhookId, cmd.Lookup("query", "webhookid").ObjectID())
+		assert.True(t, cmd.Lookup("upsert").Boolean())
+		assert.Equal(t, entity.EventTaskDone, cmd.Lookup("update", "$setOnInsert", "event").StringValue())
+	})
+}
+
+func TestAddDeliveryAttempt(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	retryAt := time.Date(2023, 8, 15, 9, 0, 5, 0, time.UTC)
+
+	mt.Run("retry", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		repo := &webhookRepository{deliveries: mt.Coll}
+
+		err := repo.AddDeliveryAttempt(context.Background(), primitive.NewObjectID(), "replica-1", entity.DeliveryAttempt{StatusCode: 502}, entity.DeliveryPending, retryAt)
+		assert.Nil(t, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "replica-1", update.Lookup("q", "leaseowner").StringValue())
+		assert.Equal(t, retryAt, update.Lookup("u", "$set", "leaseuntil").Time().UTC())
+		assert.Equal(t, int32(1), update.Lookup("u", "$inc", "tries").Int32())
+	})
+
+	mt.Run("lease_lost", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		repo := &webhookRepository{deliveries: mt.Coll}
+
+		err := repo.AddDeliveryAttempt(context.Background(), primitive.NewObjectID(), "replica-1", entity.DeliveryAttempt{}, entity.DeliverySucceeded, time.Time{})
+		assert.Equal(t, "webhook delivery lease lost", err.Error())
+	})
+}
@@ -2,30 +2,102 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 
 	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type Server struct {
 	httpServer *http.Server
+	// tls равен nil, когда сервер работает по обычному HTTP.
+	tls *certReloader
 }
 
-func NewServer(cfg *config.Config, handler http.Handler) *Server {
-	return &Server{
+func NewServer(cfg *config.Config, handler http.Handler, l logger.Interface) *Server {
+	if cfg.HTTP.HTTP2 && !cfg.HTTP.TLS.Enabled {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	s := &Server{
 		httpServer: &http.Server{
-			Addr:           ":" + cfg.HTTP.Port,
+			Addr:           net.JoinHostPort(cfg.HTTP.Host, cfg.HTTP.Port),
 			Handler: handler,
 			ReadTimeout: cfg.HTTP.ReadTimeout,
-			WriteTimeout: cfg.HTTP.WriteTimeout},
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout: cfg.HTTP.WriteTimeout,
+			IdleTimeout: cfg.HTTP.IdleTimeout,
+			MaxHeaderBytes: cfg.HTTP.MaxHeaderMegabytes << 20},
+	}
+
+	if !cfg.HTTP.HTTP2 {
+		// Непустой словарь не дает net/http включить HTTP/2 поверх TLS.
+		s.httpServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	if cfg.HTTP.TLS.Enabled {
+		s.tls = newCertReloader(cfg.HTTP.TLS, cfg.HTTP.HTTP2, l)
 	}
 
+	return s
 }
 
-func (s *Server) Run() error {
-	return s.httpServer.ListenAndServe()
+// Start занимает адрес и обслуживает запросы в фоне, чтобы занятый порт или
+// нечитаемые файлы TLS завершили запуск ошибкой. Ошибки обслуживания
+// передаются в fail.
+func (s *Server) Start(fail func(error)) error {
+	if s.tls != nil {
+		if err := s.tls.load(); err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = s.tls.tlsConfig()
+	}
+
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+
+	if s.tls != nil {
+		if err := s.tls.watch(); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+
+	go func() {
+		var err error
+		if s.tls != nil {
+			// Сертификат берется из TLSConfig.
+			err = s.httpServer.ServeTLS(ln, "", "")
+		} else {
+			err = s.httpServer.Serve(ln)
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fail(err)
+		}
+	}()
+
+	return nil
 }
 
+// Stop ждет выполняющиеся запросы, пока ctx не завершен, затем закрывает
+// оставшиеся соединения.
 func (s *Server) Stop(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
\ No newline at end of file
+	err := s.httpServer.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.httpServer.Close()
+	}
+
+	if s.tls != nil {
+		err = errors.Join(err, s.tls.stop())
+	}
+
+	return err
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+func TestServer_limits(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTP.Host = "127.0.0.1"
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.MaxHeaderMegabytes = 1
+	cfg.HTTP.ReadHeaderTimeout = 100 * time.Millisecond
+	cfg.HTTP.IdleTimeout = 100 * time.Millisecond
+	startServer(t, cfg)
+
+	addr := net.JoinHostPort(cfg.HTTP.Host, cfg.HTTP.Port)
+
+	req, err := http.NewRequest("GET", "http://"+addr+"/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Large", strings.Repeat("a", 2<<20))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+
+	// Клиент, который так и не дописал заголовки, отключается.
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Как и простаивающее keep-alive соединение.
+	idle, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer idle.Close()
+	_, err = idle.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, idle.SetReadDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(idle)
+	resp, err = http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF, "the server closes the idle connection")
+}
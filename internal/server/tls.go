@@ -0,0 +1,190 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// reloadDelay объединяет несколько событий одного обновления сертификата
+// (ключ и сертификат записываются друг за другом, секрет Kubernetes меняет
+// свою символическую ссылку) в одну перезагрузку.
+const reloadDelay = 200 * time.Millisecond
+
+// certReloader отдает сертификат, ключ и CA клиентов из конфигурации TLS и
+// загружает их заново при каждом изменении их файлов. Неудачная перезагрузка
+// оставляет прежние.
+type certReloader struct {
+	cfg        config.TLSConfig
+	nextProtos []string
+	logger     logger.Interface
+
+	mu      sync.RWMutex
+	current *tls.Config
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+func newCertReloader(cfg config.TLSConfig, http2 bool, l logger.Interface) *certReloader {
+	nextProtos := []string{"http/1.1"}
+	if http2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+
+	return &certReloader{cfg: cfg, nextProtos: nextProtos, logger: l}
+}
+
+// load читает файлы и заменяет отдаваемую конфигурацию.
+func (r *certReloader) load() error {
+	minVersion, err := tlsVersion(r.cfg.MinVersion)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	conf := &tls.Config{
+		MinVersion:   minVersion,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   r.nextProtos,
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("load client CAs: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("load client CAs: no certificate in %s", r.cfg.ClientCAFile)
+		}
+		conf.ClientCAs = pool
+
+		switch r.cfg.ClientAuth {
+		case "require":
+			conf.ClientAuth = tls.RequireAndVerifyClientCert
+		case "optional":
+			conf.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return fmt.Errorf("unknown http.tls.clientAuth %q", r.cfg.ClientAuth)
+		}
+	}
+
+	r.mu.Lock()
+	r.current = conf
+	r.mu.Unlock()
+
+	return nil
+}
+
+// tlsConfig возвращает конфигурацию для http.Server: каждое рукопожатие
+// использует файлы, загруженные последними.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		NextProtos: r.nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &r.current.Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.current, nil
+		},
+	}
+}
+
+// watch перезагружает файлы при изменении их каталогов, пока не закрыт stop.
+func (r *certReloader) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+
+		if err := watcher.Add(dir); err != nil {
+			return errors.Join(fmt.Errorf("watch %s: %w", dir, err), watcher.Close())
+		}
+	}
+
+	r.watcher = watcher
+	r.done = make(chan struct{})
+	go r.run()
+
+	return nil
+}
+
+func (r *certReloader) run() {
+	defer close(r.done)
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case _, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			reload = time.After(reloadDelay)
+
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.logger.Error(fmt.Errorf("server: watch TLS files: %w", err))
+
+		case <-reload:
+			reload = nil
+			if err := r.load(); err != nil {
+				r.logger.Error(fmt.Errorf("server: reload TLS files, keeping the previous ones: %w", err))
+				continue
+			}
+			r.logger.Info("server: TLS files reloaded")
+		}
+	}
+}
+
+func (r *certReloader) stop() error {
+	if r.watcher == nil {
+		return nil
+	}
+
+	err := r.watcher.Close()
+	<-r.done
+	return err
+}
+
+func tlsVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown http.tls.minVersion %q", version)
+	}
+}
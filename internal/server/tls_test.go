@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"golang.org/x/net/http2"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue возвращает сертификат и ключ, подписанные CA, в PEM.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func startServer(t *testing.T, cfg *config.Config) {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	srv := NewServer(cfg, handler, logger.New("error"))
+	require.NoError(t, srv.Start(func(err error) { t.Error(err) }))
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+	cert, key := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "tls.crt"), cert)
+	writeFile(t, filepath.Join(dir, "tls.key"), key)
+
+	cfg := &config.Config{}
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.HTTP2 = true
+	cfg.HTTP.TLS = config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		MinVersion: "1.2",
+	}
+	startServer(t, cfg)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	url := "https://127.0.0.1:" + cfg.HTTP.Port + "/"
+
+	get := func() (*http.Response, string) {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := get()
+	assert.Equal(t, "HTTP/2.0", body)
+	assert.Equal(t, big.NewInt(2), resp.TLS.PeerCertificates[0].SerialNumber)
+
+	// Обновленный сертификат отдается без перезапуска.
+	cert, key = ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "tls.key"), key)
+	writeFile(t, filepath.Join(dir, "tls.crt"), cert)
+
+	assert.Eventually(t, func() bool {
+		client.CloseIdleConnections()
+		resp, _ := get()
+		return resp.TLS.PeerCertificates[0].SerialNumber.Cmp(big.NewInt(3)) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Испорченный файл оставляет прежний сертификат.
+	writeFile(t, filepath.Join(dir, "tls.crt"), []byte("garbage"))
+	time.Sleep(2 * reloadDelay)
+	client.CloseIdleConnections()
+	resp, _ = get()
+	assert.Equal(t, big.NewInt(3), resp.TLS.PeerCertificates[0].SerialNumber)
+}
+
+func TestServer_TLSWithoutHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+	cert, key := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "tls.crt"), cert)
+	writeFile(t, filepath.Join(dir, "tls.key"), key)
+
+	cfg := &config.Config{}
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.TLS = config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		MinVersion: "1.3",
+	}
+	startServer(t, cfg)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	conn, err := tls.Dial("tcp", "127.0.0.1:"+cfg.HTTP.Port, &tls.Config{RootCAs: roots, NextProtos: []string{"h2", "http/1.1"}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	assert.Equal(t, "http/1.1", state.NegotiatedProtocol)
+	assert.Equal(t, uint16(tls.VersionTLS13), state.Version)
+
+	_, err = tls.Dial("tcp", "127.0.0.1:"+cfg.HTTP.Port, &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS12})
+	assert.Error(t, err, "TLS 1.2 is below minVersion")
+}
+
+func TestServer_mTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+	cert, key := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "tls.crt"), cert)
+	writeFile(t, filepath.Join(dir, "tls.key"), key)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+
+	cfg := &config.Config{}
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.TLS = config.TLSConfig{
+		Enabled:      true,
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		ClientAuth:   "require",
+		MinVersion:   "1.2",
+	}
+	startServer(t, cfg)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	url := "https://127.0.0.1:" + cfg.HTTP.Port + "/"
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err := anonymous.Get(url)
+	assert.Error(t, err, "a client without a certificate is rejected")
+
+	clientCert, clientKey := ca.issue(t, 4, x509.ExtKeyUsageClientAuth)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pair}}}}
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestServer_TLSFilesMissing(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.TLS = config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(t.TempDir(), "missing.crt"),
+		KeyFile:    filepath.Join(t.TempDir(), "missing.key"),
+		MinVersion: "1.2",
+	}
+
+	srv := NewServer(cfg, http.NotFoundHandler(), logger.New("error"))
+	assert.Error(t, srv.Start(func(err error) { t.Error(err) }))
+}
+
+func TestServer_h2c(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTP.Port = freePort(t)
+	cfg.HTTP.HTTP2 = true
+	startServer(t, cfg)
+
+	// HTTP/2 с заранее известной поддержкой поверх обычного TCP.
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("http://127.0.0.1:" + cfg.HTTP.Port + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+)
+
+type DigestService struct {
+	repo *repository.Repository
+}
+
+func NewDigestService(repo *repository.Repository) *DigestService {
+	return &DigestService{repo: repo}
+}
+
+// CreateSubscription подписывает пользователя на ежедневную сводку.
+func (d *DigestService) CreateSubscription(ctx context.Context, subscription entity.DigestSubscription) (string, error) {
+	subscription.LastSentOn = ""
+	subscription.CreatedAt = time.Now().UTC()
+
+	id, err := d.repo.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return "", err
+	}
+
+	return id.Hex(), nil
+}
+
+// GetSubscriptions возвращает все подписки на сводку.
+func (d *DigestService) GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	return d.repo.GetSubscriptions(ctx)
+}
+
+// UpdateSubscription обновляет часовой пояс, время отправки и согласие на сводку.
+func (d *DigestService) UpdateSubscription(ctx context.Context, subscription entity.DigestSubscription, subscriptionId string) error {
+	return d.repo.UpdateSubscription(ctx, subscription, repository.ObjectID(subscriptionId))
+}
+
+// DeleteSubscription удаляет подписку по ее идентификатору.
+func (d *DigestService) DeleteSubscription(ctx context.Context, subscriptionId string) error {
+	return d.repo.DeleteSubscription(ctx, repository.ObjectID(subscriptionId))
+}
@@ -7,10 +7,10 @@ package mock_service
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	entity "github.com/yervsil/toDo-microservice/internal/entity"
-	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockTask is a mock of Task interface.
@@ -37,10 +37,10 @@ func (m *MockTask) EXPECT() *MockTaskMockRecorder {
 }
 
 // CreateTask mocks base method.
-func (m *MockTask) CreateTask(ctx context.Context, input entity.Task) (primitive.ObjectID, error) {
+func (m *MockTask) CreateTask(ctx context.Context, input entity.Task) (entity.TaskID, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateTask", ctx, input)
-	ret0, _ := ret[0].(primitive.ObjectID)
+	ret0, _ := ret[0].(entity.TaskID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -52,7 +52,7 @@ func (mr *MockTaskMockRecorder) CreateTask(ctx, input interface{}) *gomock.Call
 }
 
 // DeleteTask mocks base method.
-func (m *MockTask) DeleteTask(ctx context.Context, taskId primitive.ObjectID) error {
+func (m *MockTask) DeleteTask(ctx context.Context, taskId entity.TaskID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteTask", ctx, taskId)
 	ret0, _ := ret[0].(error)
@@ -66,22 +66,37 @@ func (mr *MockTaskMockRecorder) DeleteTask(ctx, taskId interface{}) *gomock.Call
 }
 
 // GetTasks mocks base method.
-func (m *MockTask) GetTasks(ctx context.Context, status string) ([]entity.Task, error) {
+func (m *MockTask) GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTasks", ctx, status)
+	ret := m.ctrl.Call(m, "GetTasks", ctx, status, loc)
 	ret0, _ := ret[0].([]entity.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTasks indicates an expected call of GetTasks.
-func (mr *MockTaskMockRecorder) GetTasks(ctx, status interface{}) *gomock.Call {
+func (mr *MockTaskMockRecorder) GetTasks(ctx, status, loc interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTasks", reflect.TypeOf((*MockTask)(nil).GetTasks), ctx, status)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTasks", reflect.TypeOf((*MockTask)(nil).GetTasks), ctx, status, loc)
+}
+
+// SearchTasks mocks base method.
+func (m *MockTask) SearchTasks(ctx context.Context, query string) ([]entity.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchTasks", ctx, query)
+	ret0, _ := ret[0].([]entity.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchTasks indicates an expected call of SearchTasks.
+func (mr *MockTaskMockRecorder) SearchTasks(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchTasks", reflect.TypeOf((*MockTask)(nil).SearchTasks), ctx, query)
 }
 
 // StatusUpdate mocks base method.
-func (m *MockTask) StatusUpdate(ctx context.Context, taskId primitive.ObjectID) error {
+func (m *MockTask) StatusUpdate(ctx context.Context, taskId entity.TaskID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "StatusUpdate", ctx, taskId)
 	ret0, _ := ret[0].(error)
@@ -95,7 +110,7 @@ func (mr *MockTaskMockRecorder) StatusUpdate(ctx, taskId interface{}) *gomock.Ca
 }
 
 // UpdateTask mocks base method.
-func (m *MockTask) UpdateTask(ctx context.Context, input entity.Task, taskId primitive.ObjectID) error {
+func (m *MockTask) UpdateTask(ctx context.Context, input entity.Task, taskId entity.TaskID) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateTask", ctx, input, taskId)
 	ret0, _ := ret[0].(error)
@@ -107,3 +122,217 @@ func (mr *MockTaskMockRecorder) UpdateTask(ctx, input, taskId interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTask", reflect.TypeOf((*MockTask)(nil).UpdateTask), ctx, input, taskId)
 }
+
+// MockWebhook is a mock of Webhook interface.
+type MockWebhook struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookMockRecorder
+}
+
+// MockWebhookMockRecorder is the mock recorder for MockWebhook.
+type MockWebhookMockRecorder struct {
+	mock *MockWebhook
+}
+
+// NewMockWebhook creates a new mock instance.
+func NewMockWebhook(ctrl *gomock.Controller) *MockWebhook {
+	mock := &MockWebhook{ctrl: ctrl}
+	mock.recorder = &MockWebhookMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhook) EXPECT() *MockWebhookMockRecorder {
+	return m.recorder
+}
+
+// CreateWebhook mocks base method.
+func (m *MockWebhook) CreateWebhook(ctx context.Context, input entity.Webhook) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, input)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockWebhookMockRecorder) CreateWebhook(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockWebhook)(nil).CreateWebhook), ctx, input)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhook) DeleteWebhook(ctx context.Context, webhookId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, webhookId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookMockRecorder) DeleteWebhook(ctx, webhookId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhook)(nil).DeleteWebhook), ctx, webhookId)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockWebhook) GetDeliveries(ctx context.Context, webhookId string) ([]entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveries", ctx, webhookId)
+	ret0, _ := ret[0].([]entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockWebhookMockRecorder) GetDeliveries(ctx, webhookId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockWebhook)(nil).GetDeliveries), ctx, webhookId)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockWebhook) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", ctx)
+	ret0, _ := ret[0].([]entity.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockWebhookMockRecorder) GetWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockWebhook)(nil).GetWebhooks), ctx)
+}
+
+// Redeliver mocks base method.
+func (m *MockWebhook) Redeliver(ctx context.Context, webhookId, deliveryId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", ctx, webhookId, deliveryId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockWebhookMockRecorder) Redeliver(ctx, webhookId, deliveryId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockWebhook)(nil).Redeliver), ctx, webhookId, deliveryId)
+}
+
+// MockDigest is a mock of Digest interface.
+type MockDigest struct {
+	ctrl     *gomock.Controller
+	recorder *MockDigestMockRecorder
+}
+
+// MockDigestMockRecorder is the mock recorder for MockDigest.
+type MockDigestMockRecorder struct {
+	mock *MockDigest
+}
+
+// NewMockDigest creates a new mock instance.
+func NewMockDigest(ctrl *gomock.Controller) *MockDigest {
+	mock := &MockDigest{ctrl: ctrl}
+	mock.recorder = &MockDigestMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDigest) EXPECT() *MockDigestMockRecorder {
+	return m.recorder
+}
+
+// CreateSubscription mocks base method.
+func (m *MockDigest) CreateSubscription(ctx context.Context, input entity.DigestSubscription) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscription", ctx, input)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSubscription indicates an expected call of CreateSubscription.
+func (mr *MockDigestMockRecorder) CreateSubscription(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockDigest)(nil).CreateSubscription), ctx, input)
+}
+
+// DeleteSubscription mocks base method.
+func (m *MockDigest) DeleteSubscription(ctx context.Context, subscriptionId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscription", ctx, subscriptionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscription indicates an expected call of DeleteSubscription.
+func (mr *MockDigestMockRecorder) DeleteSubscription(ctx, subscriptionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscription", reflect.TypeOf((*MockDigest)(nil).DeleteSubscription), ctx, subscriptionId)
+}
+
+// GetSubscriptions mocks base method.
+func (m *MockDigest) GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptions", ctx)
+	ret0, _ := ret[0].([]entity.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptions indicates an expected call of GetSubscriptions.
+func (mr *MockDigestMockRecorder) GetSubscriptions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptions", reflect.TypeOf((*MockDigest)(nil).GetSubscriptions), ctx)
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockDigest) UpdateSubscription(ctx context.Context, input entity.DigestSubscription, subscriptionId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", ctx, input, subscriptionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockDigestMockRecorder) UpdateSubscription(ctx, input, subscriptionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockDigest)(nil).UpdateSubscription), ctx, input, subscriptionId)
+}
+
+// MockDispatcher is a mock of Dispatcher interface.
+type MockDispatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockDispatcherMockRecorder
+}
+
+// MockDispatcherMockRecorder is the mock recorder for MockDispatcher.
+type MockDispatcherMockRecorder struct {
+	mock *MockDispatcher
+}
+
+// NewMockDispatcher creates a new mock instance.
+func NewMockDispatcher(ctrl *gomock.Controller) *MockDispatcher {
+	mock := &MockDispatcher{ctrl: ctrl}
+	mock.recorder = &MockDispatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDispatcher) EXPECT() *MockDispatcherMockRecorder {
+	return m.recorder
+}
+
+// Redeliver mocks base method.
+func (m *MockDispatcher) Redeliver(ctx context.Context, delivery entity.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockDispatcherMockRecorder) Redeliver(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockDispatcher)(nil).Redeliver), ctx, delivery)
+}
@@ -2,25 +2,55 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/yervsil/toDo-microservice/internal/entity"
 	"github.com/yervsil/toDo-microservice/internal/repository"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
 
 type Task interface {
-	CreateTask(ctx context.Context, input entity.Task) (primitive.ObjectID, error)
-	UpdateTask(ctx context.Context, input entity.Task, taskId primitive.ObjectID) error
-	DeleteTask(ctx context.Context, taskId primitive.ObjectID) error
-	StatusUpdate(ctx context.Context, taskId primitive.ObjectID) error
-	GetTasks(ctx context.Context, status string) ([]entity.Task, error)
+	CreateTask(ctx context.Context, input entity.Task) (entity.TaskID, error)
+	UpdateTask(ctx context.Context, input entity.Task, taskId entity.TaskID) error
+	DeleteTask(ctx context.Context, taskId entity.TaskID) error
+	StatusUpdate(ctx context.Context, taskId entity.TaskID) error
+	GetTasks(ctx context.Context, status string, loc *time.Location) ([]entity.Task, error)
+	SearchTasks(ctx context.Context, query string) ([]entity.Task, error)
+}
+
+// Webhook и Digest принимают идентификаторы в виде строк из API, как Task
+// принимает entity.TaskID; в идентификаторы хранилища их переводит
+// репозиторий.
+type Webhook interface {
+	CreateWebhook(ctx context.Context, input entity.Webhook) (string, error)
+	GetWebhooks(ctx context.Context) ([]entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	GetDeliveries(ctx context.Context, webhookId string) ([]entity.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookId, deliveryId string) error
+}
+
+type Digest interface {
+	CreateSubscription(ctx context.Context, input entity.DigestSubscription) (string, error)
+	GetSubscriptions(ctx context.Context) ([]entity.DigestSubscription, error)
+	UpdateSubscription(ctx context.Context, input entity.DigestSubscription, subscriptionId string) error
+	DeleteSubscription(ctx context.Context, subscriptionId string) error
+}
+
+// Dispatcher доставляет события задач подписчикам.
+type Dispatcher interface {
+	Redeliver(ctx context.Context, delivery entity.WebhookDelivery) error
 }
 
 type Service struct {
 	Task
+	Webhook
+	Digest
 }
 
-func NewService(repo *repository.Repository) *Service {
-	return &Service{Task: NewTaskService(repo)}
+func NewService(repo *repository.Repository, dispatcher Dispatcher) *Service {
+	return &Service{
+		Task:    NewTaskService(repo),
+		Webhook: NewWebhookService(repo, dispatcher),
+		Digest:  NewDigestService(repo),
+	}
 }
\ No newline at end of file
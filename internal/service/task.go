@@ -2,17 +2,25 @@ package service
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
 	"github.com/yervsil/toDo-microservice/internal/repository"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	active = "active"
 )
 
+var tracer = otel.Tracer("github.com/yervsil/toDo-microservice/internal/service")
+
 type TaskService struct {
 	repo *repository.Repository
 }
@@ -22,36 +30,102 @@ func NewTaskService(repo *repository.Repository) *TaskService {
 }
 
 // CreateTask создает новую задачу.
-func(t *TaskService) CreateTask(ctx context.Context, task entity.Task) (primitive.ObjectID, error){
+func(t *TaskService) CreateTask(ctx context.Context, task entity.Task) (id entity.TaskID, err error){
+	ctx, span := tracer.Start(ctx, "TaskService.CreateTask")
+	defer func() { tracing.End(span, err) }()
+
 	task.Status = active
-	return t.repo.CreateTask(ctx, task)
+	task.DoneAt = nil
+
+	id, err = t.repo.CreateTask(ctx, task)
+	if err != nil {
+		return id, err
+	}
+
+	span.SetAttributes(attribute.String("task.id", id.String()))
+	logger.FromContext(ctx).Package("service").With("task_id", id.String()).Info("task created")
+	metrics.TasksCreated.Inc()
+	return id, nil
 }
 
 // UpdateTask обновляет существующую задачу по ее идентификатору.
-func(t *TaskService) UpdateTask(ctx context.Context, task entity.Task, taskId primitive.ObjectID) error{
+func(t *TaskService) UpdateTask(ctx context.Context, task entity.Task, taskId entity.TaskID) (err error){
+	ctx, span := tracer.Start(ctx, "TaskService.UpdateTask", trace.WithAttributes(attribute.String("task.id", taskId.String())))
+	defer func() { tracing.End(span, err) }()
+
 	task.Status = active
-	return t.repo.UpdateTask(ctx, task, taskId)
+	task.DoneAt = nil
+	if err = t.repo.UpdateTask(ctx, task, taskId); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Package("service").With("task_id", taskId.String()).Info("task updated")
+	return nil
 }
 
 // DeleteTask удаляет задачу по ее идентификатору.
-func(t *TaskService) DeleteTask(ctx context.Context, taskId primitive.ObjectID) error{
-	return t.repo.DeleteTask(ctx, taskId)
+func(t *TaskService) DeleteTask(ctx context.Context, taskId entity.TaskID) (err error){
+	ctx, span := tracer.Start(ctx, "TaskService.DeleteTask", trace.WithAttributes(attribute.String("task.id", taskId.String())))
+	defer func() { tracing.End(span, err) }()
+
+	if err = t.repo.DeleteTask(ctx, taskId); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Package("service").With("task_id", taskId.String()).Info("task deleted")
+	return nil
 }
 
 // StatusUpdate обновляет статус задачи по ее идентификатору.
-func(t *TaskService) StatusUpdate(ctx context.Context, taskId primitive.ObjectID) error{
-	return t.repo.StatusUpdate(ctx, taskId)
+func(t *TaskService) StatusUpdate(ctx context.Context, taskId entity.TaskID) (err error){
+	ctx, span := tracer.Start(ctx, "TaskService.StatusUpdate", trace.WithAttributes(attribute.String("task.id", taskId.String())))
+	defer func() { tracing.End(span, err) }()
+
+	changed, err := t.repo.StatusUpdate(ctx, taskId)
+	if err != nil || !changed {
+		return err
+	}
+
+	logger.FromContext(ctx).Package("service").With("task_id", taskId.String()).Info("task done")
+	metrics.TasksCompleted.Inc()
+	return nil
 }
 
-// GetTasks возвращает список задач с определенным статусом.
-func(t *TaskService) GetTasks(ctx context.Context, status string) ([]entity.Task, error){
-	tasks, err := t.repo.GetTasks(ctx, status)
+// SearchTasks ищет задачи по словам заголовка.
+func(t *TaskService) SearchTasks(ctx context.Context, query string) (tasks []entity.Task, err error){
+	ctx, span := tracer.Start(ctx, "TaskService.SearchTasks")
+	defer func() {
+		span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
+		tracing.End(span, err)
+	}()
+
+	return t.repo.SearchTasks(ctx, strings.TrimSpace(query))
+}
+
+// GetTasks возвращает список задач с определенным статусом для пользователя
+// в часовом поясе loc.
+func(t *TaskService) GetTasks(ctx context.Context, status string, loc *time.Location) (tasks []entity.Task, err error){
+	ctx, span := tracer.Start(ctx, "TaskService.GetTasks", trace.WithAttributes(
+		attribute.String("task.status", status),
+		attribute.String("user.timezone", loc.String()),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("tasks.count", len(tasks)))
+		tracing.End(span, err)
+	}()
+
+	tasks, err = t.repo.GetTasks(ctx, status, loc)
     if err != nil {
         return nil, err
     }
 
     for i, task := range tasks {
-        activeDate, err := time.Parse("2006-01-02", task.ActiveAt)
+        day, err := task.Day(loc)
+        if err != nil {
+            return nil, err
+        }
+
+        activeDate, err := time.Parse(entity.DateLayout, day)
         if err != nil {
             return nil, err
         }
@@ -62,4 +136,4 @@ func(t *TaskService) GetTasks(ctx context.Context, status string) ([]entity.Task
     }
 
     return tasks, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/metrics"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+)
+
+func TestTaskService_StatusUpdate_countsOnce(t *testing.T) {
+	ctx := context.Background()
+	ids, err := entity.NewTaskIDGenerator(entity.TaskIDObjectID)
+	require.NoError(t, err)
+	tasks := NewTaskService(repository.NewMemoryRepository(ids))
+
+	id, err := tasks.CreateTask(ctx, entity.Task{Title: "Купить книгу", ActiveAt: time.Now().UTC().Format(entity.DateLayout)})
+	require.NoError(t, err)
+	completed := testutil.ToFloat64(metrics.TasksCompleted)
+
+	require.NoError(t, tasks.StatusUpdate(ctx, id))
+	assert.Equal(t, completed+1, testutil.ToFloat64(metrics.TasksCompleted))
+
+	// Отметить выполненную задачу еще раз можно, но выполненной второй раз
+	// она не считается.
+	require.NoError(t, tasks.StatusUpdate(ctx, id))
+	assert.Equal(t, completed+1, testutil.ToFloat64(metrics.TasksCompleted))
+}
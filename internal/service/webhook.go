@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/internal/repository"
+)
+
+type WebhookService struct {
+	repo       *repository.Repository
+	dispatcher Dispatcher
+}
+
+func NewWebhookService(repo *repository.Repository, dispatcher Dispatcher) *WebhookService {
+	return &WebhookService{repo: repo, dispatcher: dispatcher}
+}
+
+// CreateWebhook создает подписку на события задач.
+func (w *WebhookService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (string, error) {
+	webhook.CreatedAt = time.Now().UTC()
+
+	id, err := w.repo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return "", err
+	}
+
+	return id.Hex(), nil
+}
+
+// GetWebhooks возвращает все подписки без их секретов.
+func (w *WebhookService) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	webhooks, err := w.repo.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет подписку по ее идентификатору.
+func (w *WebhookService) DeleteWebhook(ctx context.Context, webhookId string) error {
+	return w.repo.DeleteWebhook(ctx, repository.ObjectID(webhookId))
+}
+
+// GetDeliveries возвращает историю доставок подписки.
+func (w *WebhookService) GetDeliveries(ctx context.Context, webhookId string) ([]entity.WebhookDelivery, error) {
+	webhook, err := w.repo.GetWebhook(ctx, repository.ObjectID(webhookId))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.repo.GetDeliveries(ctx, webhook.ID)
+}
+
+// Redeliver повторно отправляет доставку подписчику.
+func (w *WebhookService) Redeliver(ctx context.Context, webhookId, deliveryId string) error {
+	delivery, err := w.repo.GetDelivery(ctx, repository.ObjectID(deliveryId))
+	if err != nil {
+		return err
+	}
+
+	if delivery.WebhookID.Hex() != webhookId {
+		return errors.New("no record found")
+	}
+
+	return w.dispatcher.Redeliver(ctx, delivery)
+}
@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	HeaderEvent     = "X-Todo-Event"
+	HeaderDelivery  = "X-Todo-Delivery"
+	HeaderSignature = "X-Todo-Signature"
+
+	userAgent = "toDo-Webhook/1.0"
+)
+
+var tracer = otel.Tracer("github.com/yervsil/toDo-microservice/internal/webhook")
+
+// Store хранит подписки и историю их доставок и выдает в аренду ожидающие
+// доставки. Аренда позволяет запускать диспетчер в каждой реплике: доставку
+// одновременно отправляет один владелец, а аренда упавшей реплики истекает, и
+// доставку забирает другая.
+type Store interface {
+	GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error)
+	GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error)
+	CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error)
+	AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error)
+	AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error
+	ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error
+}
+
+// Dispatcher рассылает события задач подписанным вебхукам и доставляет их в
+// фоне, повторяя неудачные запросы с экспоненциальной паузой. Это издатель
+// outbox: событие подтверждается, когда доставка записана для каждого
+// подписчика. Доставки остаются в хранилище, пока не удадутся или не
+// провалятся: воркеры берут в аренду те, которым пора, каждые interval и
+// раньше, когда Publish или Redeliver добавляют новые.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	clock  clock.Clock
+	logger logger.Interface
+	owner  string
+	wake   chan struct{}
+
+	workers     int
+	interval    time.Duration
+	lease       time.Duration
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+// NewDispatcher -.
+func NewDispatcher(store Store, c clock.Clock, cfg config.WebhookConfig, l logger.Interface) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		clock:       c,
+		logger:      l,
+		owner:       ownerID(),
+		wake:        make(chan struct{}, 1),
+		workers:     cfg.Workers,
+		interval:    cfg.Interval,
+		lease:       cfg.Lease,
+		maxAttempts: cfg.MaxAttempts,
+		backoffBase: cfg.BackoffBase,
+		backoffMax:  cfg.BackoffMax,
+	}
+
+	if d.workers <= 0 {
+		d.workers = 1
+	}
+	if d.interval <= 0 {
+		d.interval = 10 * time.Second
+	}
+	if d.lease <= 0 {
+		d.lease = time.Minute
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = 1
+	}
+	if d.backoffBase <= 0 {
+		d.backoffBase = time.Second
+	}
+	if d.backoffMax < d.backoffBase {
+		d.backoffMax = d.backoffBase
+	}
+
+	return d
+}
+
+// Publish записывает доставку event для каждого вебхука, подписанного на его
+// тип, и будит воркеры. Повторно опубликованное событие сохраняет свои
+// доставки, поэтому каждый подписчик получает его один раз.
+func (d *Dispatcher) Publish(ctx context.Context, event entity.Event) error {
+	webhooks, err := d.store.GetWebhooksByEvent(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("find subscribers of %s: %w", event.Type, err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", event.Type, err)
+	}
+
+	for _, webhook := range webhooks {
+		_, err := d.store.CreateDelivery(ctx, entity.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			Event:     event.Type,
+			Payload:   string(payload),
+			Status:    entity.DeliveryPending,
+			CreatedAt: d.clock.Now().UTC(),
+			// Доставки выполняются позже, возможно после перезапуска:
+			// трассировка идет с ними.
+			TraceContext: tracing.Inject(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("record delivery for %s: %w", webhook.ID.Hex(), err)
+		}
+	}
+	d.notify()
+
+	return nil
+}
+
+// Redeliver назначает еще один круг попыток для завершенной доставки.
+// Ожидающая доставка остается как есть: она и так будет отправлена.
+func (d *Dispatcher) Redeliver(ctx context.Context, delivery entity.WebhookDelivery) error {
+	if err := d.store.ResetDelivery(ctx, delivery.ID); err != nil {
+		return err
+	}
+	d.notify()
+
+	return nil
+}
+
+// Run отправляет доставки, которым пора, заданным числом воркеров, пока ctx не
+// отменен. Доставки, оставшиеся ожидающими с прошлого запуска, отправляются сразу.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// Tick делает одну попытку для каждой доставки, которой пора сейчас, и
+// возвращает число удачных.
+func (d *Dispatcher) Tick(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		delivery, err := d.store.AcquireDelivery(ctx, d.clock.Now(), d.owner, d.lease)
+		if err != nil {
+			return sent, fmt.Errorf("acquire delivery: %w", err)
+		}
+		if delivery == nil {
+			return sent, nil
+		}
+
+		if d.deliver(ctx, *delivery) {
+			sent++
+		}
+	}
+
+	return sent, ctx.Err()
+}
+
+// notify будит ожидающий воркер; занятый и так найдет новые доставки.
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		if _, err := d.Tick(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error(fmt.Errorf("webhook: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-d.clock.After(d.interval):
+		}
+	}
+}
+
+// deliver делает одну попытку арендованной доставки и записывает ее: доставка
+// удается, проваливается после maxAttempts попыток или ждет паузы.
+func (d *Dispatcher) deliver(ctx context.Context, delivery entity.WebhookDelivery) bool {
+	webhook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		// Аренда истечет, и доставка будет повторена.
+		d.logger.Error(fmt.Errorf("webhook: load subscription %s: %w", delivery.WebhookID.Hex(), err))
+		return false
+	}
+
+	ctx, span := tracer.Start(tracing.Extract(ctx, delivery.TraceContext), "webhook.deliver", trace.WithAttributes(
+		attribute.String("webhook.id", webhook.ID.Hex()),
+		attribute.String("webhook.delivery", delivery.ID.Hex()),
+		attribute.String("event.type", delivery.Event),
+	))
+	defer span.End()
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.lease)
+	result := d.send(sendCtx, webhook, delivery)
+	cancel()
+	if ctx.Err() != nil {
+		return false
+	}
+
+	tries := delivery.Tries + 1
+	status := entity.DeliveryPending
+	var retryAt time.Time
+	switch {
+	case result.Error == "":
+		status = entity.DeliverySucceeded
+	case tries >= d.maxAttempts:
+		status = entity.DeliveryFailed
+		d.logger.Warn("webhook: delivery %s to %s failed after %d attempts: %s", delivery.ID.Hex(), webhook.URL, tries, result.Error)
+	default:
+		retryAt = d.clock.Now().Add(d.backoff(tries))
+	}
+
+	if err := d.store.AddDeliveryAttempt(ctx, delivery.ID, d.owner, result, status, retryAt); err != nil {
+		d.logger.Error(fmt.Errorf("webhook: record attempt for %s: %w", delivery.ID.Hex(), err))
+	}
+
+	return status == entity.DeliverySucceeded
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) (attempt entity.DeliveryAttempt) {
+	body := []byte(delivery.Payload)
+	attempt = entity.DeliveryAttempt{At: d.clock.Now().UTC()}
+
+	ctx, span := tracer.Start(ctx, "webhook.send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(http.MethodPost),
+		semconv.URLFull(webhook.URL),
+	))
+	defer func() {
+		if attempt.StatusCode != 0 {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(attempt.StatusCode))
+		}
+		var err error
+		if attempt.Error != "" {
+			err = errors.New(attempt.Error)
+		}
+		tracing.End(span, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	tracing.InjectHTTP(ctx, req.Header)
+
+	resp, err := d.client.Do(req)
+	attempt.Duration = d.clock.Now().Sub(attempt.At)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+// backoff возвращает паузу после попытки attempt: base, 2*base, 4*base... но не
+// больше max.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.backoffBase
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.backoffMax {
+			return d.backoffMax
+		}
+	}
+
+	return delay
+}
+
+func ownerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "todo"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return host + "-" + hex.EncodeToString(b)
+}
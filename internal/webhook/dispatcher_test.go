@@ -0,0 +1,439 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/internal/entity"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"github.com/yervsil/toDo-microservice/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// memoryStore выдает доставки в аренду, как репозиторий Mongo: самую старую
+// ожидающую, аренда которой свободна.
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   []entity.Webhook
+	deliveries map[primitive.ObjectID]*entity.WebhookDelivery
+	order      []primitive.ObjectID
+}
+
+func newMemoryStore(webhooks ...entity.Webhook) *memoryStore {
+	return &memoryStore{webhooks: webhooks, deliveries: map[primitive.ObjectID]*entity.WebhookDelivery{}}
+}
+
+func (s *memoryStore) GetWebhook(ctx context.Context, webhookId primitive.ObjectID) (entity.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range s.webhooks {
+		if w.ID == webhookId {
+			return w, nil
+		}
+	}
+	return entity.Webhook{}, errors.New("no record found")
+}
+
+func (s *memoryStore) GetWebhooksByEvent(ctx context.Context, event string) ([]entity.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []entity.Webhook
+	for _, w := range s.webhooks {
+		if w.Subscribed(event) {
+			res = append(res, w)
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) CreateDelivery(ctx context.Context, delivery entity.WebhookDelivery) (primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if delivery.EventID != "" && d.EventID == delivery.EventID && d.WebhookID == delivery.WebhookID {
+			return d.ID, nil
+		}
+	}
+	delivery.ID = primitive.NewObjectID()
+	s.deliveries[delivery.ID] = &delivery
+	s.order = append(s.order, delivery.ID)
+	return delivery.ID, nil
+}
+
+func (s *memoryStore) AcquireDelivery(ctx context.Context, now time.Time, owner string, lease time.Duration) (*entity.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.order {
+		d := s.deliveries[id]
+		if d.Status != entity.DeliveryPending || d.LeaseUntil.After(now) {
+			continue
+		}
+		d.LeaseOwner, d.LeaseUntil = owner, now.Add(lease)
+		acquired := *d
+		return &acquired, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) AddDeliveryAttempt(ctx context.Context, deliveryId primitive.ObjectID, owner string, attempt entity.DeliveryAttempt, status string, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[deliveryId]
+	if d.LeaseOwner != owner {
+		return errors.New("webhook delivery lease lost")
+	}
+	d.Attempts = append(d.Attempts, attempt)
+	d.Tries++
+	d.Status = status
+	d.LeaseOwner, d.LeaseUntil = "", retryAt
+	return nil
+}
+
+func (s *memoryStore) ResetDelivery(ctx context.Context, deliveryId primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[deliveryId]
+	if d.Status != entity.DeliveryPending {
+		d.Status, d.Tries = entity.DeliveryPending, 0
+		d.LeaseOwner, d.LeaseUntil = "", time.Time{}
+	}
+	return nil
+}
+
+func (s *memoryStore) only(t *testing.T) entity.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Len(t, s.deliveries, 1)
+	for _, d := range s.deliveries {
+		return *d
+	}
+	return entity.WebhookDelivery{}
+}
+
+func (s *memoryStore) waitStatus(t *testing.T, status string) entity.WebhookDelivery {
+	var d entity.WebhookDelivery
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		n := len(s.deliveries)
+		s.mu.Unlock()
+		if n == 0 {
+			return false
+		}
+		d = s.only(t)
+		return d.Status == status
+	}, 5*time.Second, 5*time.Millisecond)
+	return d
+}
+
+func testConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		Workers:     2,
+		Interval:    5 * time.Millisecond,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		Timeout:     time.Second,
+		BackoffBase: 5 * time.Millisecond,
+		BackoffMax:  20 * time.Millisecond,
+	}
+}
+
+func startDispatcher(t *testing.T, store Store) *Dispatcher {
+	d := NewDispatcher(store, clock.New(), testConfig(), logger.New("error"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return d
+}
+
+func TestDispatcher_signedDeliveryWithRetries(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	var calls int32
+	received := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: secret,
+		Events: []string{entity.EventTaskCreated},
+	})
+	d := startDispatcher(t, store)
+
+	event := entity.Event{
+		Type:   entity.EventTaskCreated,
+		TaskID: primitive.NewObjectID().Hex(),
+		Task:   &entity.Task{Title: "Купить книгу", ActiveAt: "2023-08-04", Status: "active"},
+	}
+	require.NoError(t, d.Publish(context.Background(), event))
+
+	delivery := store.waitStatus(t, entity.DeliverySucceeded)
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusInternalServerError, delivery.Attempts[0].StatusCode)
+	assert.NotEmpty(t, delivery.Attempts[0].Error)
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[2].StatusCode)
+	assert.Empty(t, delivery.Attempts[2].Error)
+
+	for i := 0; i < 3; i++ {
+		r, body := <-received, <-bodies
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, entity.EventTaskCreated, r.Header.Get(HeaderEvent))
+		assert.Equal(t, delivery.ID.Hex(), r.Header.Get(HeaderDelivery))
+		assert.True(t, Verify(secret, body, r.Header.Get(HeaderSignature)))
+		assert.False(t, Verify("another-secret-value", body, r.Header.Get(HeaderSignature)))
+
+		var got entity.Event
+		require.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, event.TaskID, got.TaskID)
+		assert.Equal(t, "Купить книгу", got.Task.Title)
+	}
+}
+
+func TestDispatcher_failsAfterMaxAttemptsAndRedelivers(t *testing.T) {
+	var healthy int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskDone},
+	})
+	d := startDispatcher(t, store)
+
+	require.NoError(t, d.Publish(context.Background(), entity.Event{Type: entity.EventTaskDone, TaskID: "1"}))
+	delivery := store.waitStatus(t, entity.DeliveryFailed)
+	assert.Len(t, delivery.Attempts, 3)
+
+	atomic.StoreInt32(&healthy, 1)
+	require.NoError(t, d.Redeliver(context.Background(), delivery))
+
+	delivery = store.waitStatus(t, entity.DeliverySucceeded)
+	assert.Len(t, delivery.Attempts, 4)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[3].StatusCode)
+}
+
+func TestDispatcher_skipsUnsubscribedEvents(t *testing.T) {
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    "http://127.0.0.1:0",
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskDeleted},
+	})
+	d := startDispatcher(t, store)
+
+	require.NoError(t, d.Publish(context.Background(), entity.Event{Type: entity.EventTaskCreated, TaskID: "1"}))
+	time.Sleep(50 * time.Millisecond)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Empty(t, store.deliveries)
+}
+
+func TestDispatcher_publishedAgain(t *testing.T) {
+	var calls int32
+	deliveryIds := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		deliveryIds <- r.Header.Get(HeaderDelivery)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskDone},
+	})
+	d := startDispatcher(t, store)
+
+	// Outbox публикует событие повторно, когда другой издатель ошибся.
+	event := entity.Event{ID: primitive.NewObjectID().Hex(), Type: entity.EventTaskDone, TaskID: "1"}
+	require.NoError(t, d.Publish(context.Background(), event))
+	delivery := store.waitStatus(t, entity.DeliverySucceeded)
+	require.NoError(t, d.Publish(context.Background(), event))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, delivery.ID.Hex(), <-deliveryIds)
+	assert.Equal(t, event.ID, store.only(t).EventID)
+}
+
+func TestDispatcher_resumesPendingDeliveries(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskCreated},
+	})
+
+	// Опубликовано, пока диспетчер не работал, например перед перезапуском.
+	publisher := NewDispatcher(store, clock.New(), testConfig(), logger.New("error"))
+	for i := 0; i < 20; i++ {
+		require.NoError(t, publisher.Publish(context.Background(), entity.Event{Type: entity.EventTaskCreated, TaskID: "1"}))
+	}
+
+	// Две реплики делят хранилище: каждая доставка отправляется один раз.
+	startDispatcher(t, store)
+	startDispatcher(t, store)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 20 }, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(20), atomic.LoadInt32(&calls))
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, d := range store.deliveries {
+		assert.Equal(t, entity.DeliverySucceeded, d.Status)
+		assert.Len(t, d.Attempts, 1)
+	}
+}
+
+func TestDispatcher_lease(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskDone},
+	})
+	clk := clock.NewFake(time.Date(2023, 8, 15, 9, 0, 0, 0, time.UTC))
+	d := NewDispatcher(store, clk, config.WebhookConfig{Lease: time.Minute, MaxAttempts: 3, BackoffBase: time.Second, Timeout: time.Second}, logger.New("error"))
+	ctx := context.Background()
+
+	require.NoError(t, d.Publish(ctx, entity.Event{Type: entity.EventTaskDone, TaskID: "1"}))
+
+	// Доставку держит другая реплика: она не отправляется.
+	leased, err := store.AcquireDelivery(ctx, clk.Now(), "other", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, leased)
+	sent, err := d.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+
+	// Другая реплика упала: ее аренда истекает, и доставку забирают.
+	clk.Advance(time.Minute)
+	sent, err = d.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "the first attempt fails")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Повтор ждет паузы.
+	sent, err = d.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	clk.Advance(time.Second)
+	sent, err = d.Tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	delivery := store.only(t)
+	assert.Equal(t, entity.DeliverySucceeded, delivery.Status)
+	assert.Len(t, delivery.Attempts, 2)
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(newMemoryStore(), clock.New(), config.WebhookConfig{BackoffBase: time.Second, BackoffMax: 5 * time.Second}, logger.New("error"))
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 4*time.Second, d.backoff(3))
+	assert.Equal(t, 5*time.Second, d.backoff(4))
+	assert.Equal(t, 5*time.Second, d.backoff(10))
+}
+
+func TestDispatcher_continuesTrace(t *testing.T) {
+	_, err := tracing.Init(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	traceparents := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(entity.Webhook{
+		ID:     primitive.NewObjectID(),
+		URL:    receiver.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{entity.EventTaskDone},
+	})
+	d := startDispatcher(t, store)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "outbox.publish")
+	require.NoError(t, d.Publish(ctx, entity.Event{Type: entity.EventTaskDone, TaskID: "1"}))
+	span.End()
+
+	delivery := store.waitStatus(t, entity.DeliverySucceeded)
+	assert.Contains(t, delivery.TraceContext, "traceparent")
+
+	traceparent := <-traceparents
+	require.NotEmpty(t, traceparent)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.NotContains(t, traceparent, span.SpanContext().SpanID().String(), "the request is sent from its own span")
+}
@@ -0,0 +1,174 @@
+// Package breaker реализует предохранитель (circuit breaker): после серии
+// неудачных вызовов зависимости он на время сразу отклоняет вызовы, вместо
+// того чтобы каждый вызывающий ждал таймаута.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+// ErrOpen возвращает Allow, пока предохранитель открыт.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State — состояние Breaker.
+type State int
+
+const (
+	// Closed пропускает все вызовы.
+	Closed State = iota
+	// Open отклоняет все вызовы.
+	Open
+	// HalfOpen пропускает один пробный вызов; его исход закрывает или снова
+	// открывает предохранитель.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker открывается после Failures неудачных вызовов подряд и остается
+// открытым OpenTimeout. Затем он пропускает один вызов: успех закрывает его,
+// неудача снова открывает. Breaker можно использовать из нескольких горутин.
+type Breaker struct {
+	failures    int
+	openTimeout time.Duration
+	clock       clock.Clock
+	onChange    func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failed   int
+	openedAt time.Time
+	probing  bool
+}
+
+// Option настраивает Breaker.
+type Option func(*Breaker)
+
+// WithClock заменяет часы, отсчитывающие открытое состояние.
+func WithClock(c clock.Clock) Option {
+	return func(b *Breaker) { b.clock = c }
+}
+
+// OnChange задает функцию, вызываемую при каждой смене состояния. Она
+// вызывается под блокировкой предохранителя, поэтому не должна его вызывать.
+func OnChange(fn func(from, to State)) Option {
+	return func(b *Breaker) { b.onChange = fn }
+}
+
+// New возвращает закрытый Breaker. failures меньше 1 считается равным 1.
+func New(failures int, openTimeout time.Duration, opts ...Option) *Breaker {
+	if failures < 1 {
+		failures = 1
+	}
+
+	b := &Breaker{failures: failures, openTimeout: openTimeout, clock: clock.New()}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Allow сообщает, можно ли выполнить вызов. Он возвращает ErrOpen, пока
+// предохранитель открыт или пока выполняется пробный вызов полуоткрытого
+// предохранителя. За каждым разрешенным вызовом должен следовать Record или Ignore.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.clock.Now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(HalfOpen)
+	}
+
+	switch b.state {
+	case Open:
+		return ErrOpen
+	case HalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// Record сообщает исход вызова, разрешенного Allow.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.setState(Closed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failed = 0
+		return
+	}
+
+	b.failed++
+	if b.state == Closed && b.failed >= b.failures {
+		b.open()
+	}
+}
+
+// Ignore сообщает, что вызов, разрешенный Allow, завершился, не показав,
+// работает ли зависимость, например потому что вызывающий сдался.
+// Полуоткрытый предохранитель дает проверить зависимость следующему вызову.
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen {
+		b.probing = false
+	}
+}
+
+// State возвращает текущее состояние. Открытый предохранитель, у которого
+// истек таймаут, считается полуоткрытым.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.clock.Now().Sub(b.openedAt) >= b.openTimeout {
+		return HalfOpen
+	}
+
+	return b.state
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.clock.Now()
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	b.failed = 0
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,91 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+func TestBreaker(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	var changes []string
+	b := New(3, 10*time.Second, WithClock(clk), OnChange(func(from, to State) {
+		changes = append(changes, from.String()+"->"+to.String())
+	}))
+
+	// Успех сбрасывает серию неудач.
+	for _, failed := range []bool{true, true, false, true, true} {
+		require.NoError(t, b.Allow())
+		b.Record(failed)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	require.NoError(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	clk.Advance(10 * time.Second)
+	assert.Equal(t, HalfOpen, b.State())
+
+	// Одна проба за раз; ее неудача снова открывает предохранитель.
+	require.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	b.Record(true)
+	assert.Equal(t, Open, b.State())
+
+	clk.Advance(9 * time.Second)
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	clk.Advance(time.Second)
+	require.NoError(t, b.Allow())
+	b.Record(false)
+	assert.Equal(t, Closed, b.State())
+	require.NoError(t, b.Allow())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, changes)
+}
+
+func TestBreaker_closedAfterProbeNeedsFullRun(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := New(2, time.Second, WithClock(clk))
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.Allow())
+		b.Record(true)
+	}
+	clk.Advance(time.Second)
+	require.NoError(t, b.Allow())
+	b.Record(false)
+
+	require.NoError(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_Ignore(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := New(1, time.Second, WithClock(clk))
+
+	require.NoError(t, b.Allow())
+	b.Record(true)
+	clk.Advance(time.Second)
+
+	require.NoError(t, b.Allow())
+	b.Ignore()
+	assert.Equal(t, HalfOpen, b.State())
+
+	require.NoError(t, b.Allow())
+	b.Record(false)
+	assert.Equal(t, Closed, b.State())
+}
@@ -0,0 +1,24 @@
+// Package cache хранит значения некоторое время, чтобы повторные чтения не
+// доходили до базы.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Хранилища для taskCache.store.
+const (
+	StoreMemory = "memory"
+)
+
+// Store хранит закодированные значения по ключу. Хранилища, общие для
+// нескольких реплик, позволяют записи на одной из них очистить кэш всех.
+type Store interface {
+	// Get возвращает значение key и сообщает, найдено ли оно и не истекло ли.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set хранит value под key в течение ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Clear удаляет все ключи.
+	Clear(ctx context.Context) error
+}
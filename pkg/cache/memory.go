@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRU — Store одного процесса, который хранит до size ключей и, чтобы
+// освободить место, удаляет дольше всех не использованный.
+type LRU struct {
+	size  int
+	clock clock.Clock
+
+	mu      sync.Mutex
+	order   *list.List // впереди использованный последним
+	entries map[string]*list.Element
+}
+
+var _ Store = (*LRU)(nil)
+
+// NewLRU возвращает пустой LRU. size меньше 1 считается равным 1.
+func NewLRU(size int, clk clock.Clock) *LRU {
+	if size < 1 {
+		size = 1
+	}
+
+	return &LRU{
+		size:    size,
+		clock:   clk,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get -.
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*entry)
+	if !now.Before(e.expiresAt) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+
+	return e.value, true, nil
+}
+
+// Set -.
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	expiresAt := c.clock.Now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+// Clear -.
+func (c *LRU) Clear(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+
+	return nil
+}
+
+// Len возвращает число хранимых ключей, включая истекшие, но еще не удаленные.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+func get(t *testing.T, c Store, key string) (string, bool) {
+	t.Helper()
+
+	value, ok, err := c.Get(context.Background(), key)
+	require.NoError(t, err)
+	return string(value), ok
+}
+
+func set(t *testing.T, c Store, key, value string, ttl time.Duration) {
+	t.Helper()
+
+	require.NoError(t, c.Set(context.Background(), key, []byte(value), ttl))
+}
+
+func TestLRU_evictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, clock.NewFake(time.Now()))
+
+	set(t, c, "a", "1", time.Minute)
+	set(t, c, "b", "2", time.Minute)
+	// Чтение a делает b дольше всех не использованным.
+	_, ok := get(t, c, "a")
+	require.True(t, ok)
+	set(t, c, "c", "3", time.Minute)
+
+	_, ok = get(t, c, "b")
+	assert.False(t, ok)
+	value, ok := get(t, c, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+	value, ok = get(t, c, "c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", value)
+	assert.Equal(t, 2, c.Len())
+
+	// Запись хранимого ключа заменяет его значение без вытеснения.
+	set(t, c, "c", "4", time.Minute)
+	value, _ = get(t, c, "c")
+	assert.Equal(t, "4", value)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRU_expires(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewLRU(10, clk)
+
+	set(t, c, "a", "1", 10*time.Second)
+	clk.Advance(9 * time.Second)
+	_, ok := get(t, c, "a")
+	assert.True(t, ok)
+
+	clk.Advance(time.Second)
+	_, ok = get(t, c, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_Clear(t *testing.T) {
+	c := NewLRU(10, clock.NewFake(time.Now()))
+	set(t, c, "a", "1", time.Minute)
+	set(t, c, "b", "2", time.Minute)
+
+	require.NoError(t, c.Clear(context.Background()))
+
+	_, ok := get(t, c, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
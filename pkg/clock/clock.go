@@ -0,0 +1,106 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock абстрагирует время, чтобы в тестах планировщиками управляли поддельные
+// часы.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+// New возвращает Clock на основе пакета time.
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// Fake — Clock, который переводят вручную.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+var _ Clock = (*Fake)(nil)
+
+// NewFake возвращает поддельные часы, установленные на now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now -.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// After возвращает канал, который получит поддельное время, когда часы
+// переведут вперед хотя бы на d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+
+	f.waiters = append(f.waiters, waiter{deadline: f.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance переводит часы вперед и срабатывает все таймеры, которым пора.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set переводит часы на t и срабатывает все таймеры, которым пора.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = t
+	sort.Slice(f.waiters, func(i, j int) bool { return f.waiters[i].deadline.Before(f.waiters[j].deadline) })
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- t
+	}
+	f.waiters = pending
+}
+
+// Waiters возвращает число еще не сработавших таймеров. Тесты ждут по нему,
+// пока горутина не заблокируется на часах, прежде чем перевести их.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
@@ -2,37 +2,181 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
-const timeout = 10 * time.Second
-
-// NewClient established connection to a mongoDb instance using provided URI and auth credentials.
-func NewClient(uri, username, password string) (*mongo.Client, error) {
-	opts := options.Client().ApplyURI(uri)
-	if username != "" && password != "" {
-		opts.SetAuth(options.Credential{
-			Username: username, Password: password,
-		})
+// NewClient устанавливает соединение с MongoDB по URI, учетным данным и
+// настройкам драйвера из cfg. Первый ping повторяется с экспоненциальной
+// паузой, как задано в cfg.ConnectRetry, чтобы сервис дождался базы, которая
+// еще запускается; ctx отменяет ожидание. Мониторы получают каждую команду,
+// например чтобы замерить ее время или трассировать ее.
+func NewClient(ctx context.Context, cfg config.MongoConfig, l logger.Interface, monitors ...*event.CommandMonitor) (*mongo.Client, error) {
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(monitors) > 0 {
+		opts.SetMonitor(combineMonitors(monitors))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
+	// Connect не обращается к серверу: он возвращает ошибку только на неверные
+	// опции.
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	err = client.Ping(context.Background(), nil)
+	err = retry(ctx, cfg.ConnectRetry, clock.New(), func(ctx context.Context, attempt int) error {
+		err := client.Ping(ctx, readpref.Primary())
+		if err != nil {
+			l.Warn("mongo: ping attempt %d failed: %s", attempt, err)
+		}
+		return err
+	})
 	if err != nil {
-		return nil, err
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongo: connect: %w", err)
 	}
 
 	return client, nil
 }
 
+// clientOptions переводит cfg в опции драйвера. Нулевые значения оставляют
+// значения драйвера по умолчанию или заданные в URI.
+func clientOptions(cfg config.MongoConfig) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(cfg.URI)
+	if cfg.User != "" && cfg.Password != "" {
+		opts.SetAuth(options.Credential{
+			Username: cfg.User, Password: cfg.Password,
+		})
+	}
+
+	if cfg.MinPoolSize > 0 {
+		opts.SetMinPoolSize(cfg.MinPoolSize)
+	}
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(cfg.MaxPoolSize)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if cfg.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+	if cfg.SocketTimeout > 0 {
+		opts.SetSocketTimeout(cfg.SocketTimeout)
+	}
+
+	if cfg.ReadConcern != "" {
+		opts.SetReadConcern(&readconcern.ReadConcern{Level: cfg.ReadConcern})
+	}
+
+	if wc := cfg.WriteConcern; wc.W != "" || wc.Journal || wc.WTimeout > 0 {
+		concern := &writeconcern.WriteConcern{WTimeout: wc.WTimeout}
+		switch {
+		case wc.W == "majority":
+			concern.W = "majority"
+		case wc.W != "":
+			n, err := strconv.Atoi(wc.W)
+			if err != nil {
+				return nil, fmt.Errorf("db.writeConcern.w: %q is neither majority nor a number", wc.W)
+			}
+			concern.W = n
+		}
+		if wc.Journal {
+			concern.Journal = &wc.Journal
+		}
+		opts.SetWriteConcern(concern)
+	}
+
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("db.readPreference: %w", err)
+		}
+		pref, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("db.readPreference: %w", err)
+		}
+		opts.SetReadPreference(pref)
+	}
+
+	return opts, opts.Validate()
+}
+
+// retry вызывает fn, пока он не удастся, не провалятся cfg.MaxAttempts вызовов
+// или не завершится ctx, с паузами между вызовами. Возвращает последнюю ошибку fn.
+func retry(ctx context.Context, cfg config.MongoRetryConfig, clk clock.Clock, fn func(ctx context.Context, attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			return err
+		}
 
+		select {
+		case <-ctx.Done():
+			return err
+		case <-clk.After(backoff(cfg, attempt)):
+		}
+	}
+}
+
+// backoff возвращает паузу после попытки attempt: base, 2*base, 4*base... но
+// не больше max.
+func backoff(cfg config.MongoRetryConfig, attempt int) time.Duration {
+	delay := cfg.BackoffBase
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= cfg.BackoffMax {
+			return cfg.BackoffMax
+		}
+	}
+
+	return delay
+}
+
+// combineMonitors объединяет несколько мониторов в один: драйвер принимает
+// только один.
+func combineMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts, err := clientOptions(config.MongoConfig{
+		URI:                    "mongodb://localhost:27017",
+		User:                   "admin",
+		Password:               "secret",
+		MinPoolSize:            2,
+		MaxPoolSize:            20,
+		MaxConnIdleTime:        time.Minute,
+		ConnectTimeout:         3 * time.Second,
+		ServerSelectionTimeout: 2 * time.Second,
+		SocketTimeout:          4 * time.Second,
+		ReadConcern:            "majority",
+		WriteConcern:           config.MongoWriteConcern{W: "2", Journal: true, WTimeout: time.Second},
+		ReadPreference:         "secondaryPreferred",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "admin", opts.Auth.Username)
+	assert.Equal(t, uint64(2), *opts.MinPoolSize)
+	assert.Equal(t, uint64(20), *opts.MaxPoolSize)
+	assert.Equal(t, time.Minute, *opts.MaxConnIdleTime)
+	assert.Equal(t, 3*time.Second, *opts.ConnectTimeout)
+	assert.Equal(t, 2*time.Second, *opts.ServerSelectionTimeout)
+	assert.Equal(t, 4*time.Second, *opts.SocketTimeout)
+	assert.Equal(t, "majority", opts.ReadConcern.Level)
+	assert.Equal(t, 2, opts.WriteConcern.W)
+	assert.True(t, *opts.WriteConcern.Journal)
+	assert.Equal(t, time.Second, opts.WriteConcern.WTimeout)
+	assert.Equal(t, readpref.SecondaryPreferredMode, opts.ReadPreference.Mode())
+}
+
+func TestClientOptions_defaults(t *testing.T) {
+	opts, err := clientOptions(config.MongoConfig{URI: "mongodb://localhost:27017", WriteConcern: config.MongoWriteConcern{W: "majority"}})
+	require.NoError(t, err)
+
+	assert.Nil(t, opts.Auth)
+	assert.Nil(t, opts.MaxPoolSize)
+	assert.Nil(t, opts.ServerSelectionTimeout)
+	assert.Nil(t, opts.ReadConcern)
+	assert.Nil(t, opts.ReadPreference)
+	assert.Equal(t, "majority", opts.WriteConcern.W)
+	assert.Nil(t, opts.WriteConcern.Journal)
+}
+
+func TestClientOptions_invalid(t *testing.T) {
+	_, err := clientOptions(config.MongoConfig{URI: "mongodb://localhost", ReadPreference: "closest"})
+	assert.ErrorContains(t, err, "db.readPreference")
+
+	_, err = clientOptions(config.MongoConfig{URI: "mongodb://localhost", WriteConcern: config.MongoWriteConcern{W: "all"}})
+	assert.ErrorContains(t, err, "db.writeConcern.w")
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.MongoRetryConfig{BackoffBase: time.Second, BackoffMax: 5 * time.Second}
+
+	assert.Equal(t, time.Second, backoff(cfg, 1))
+	assert.Equal(t, 2*time.Second, backoff(cfg, 2))
+	assert.Equal(t, 4*time.Second, backoff(cfg, 3))
+	assert.Equal(t, 5*time.Second, backoff(cfg, 4))
+	assert.Equal(t, 5*time.Second, backoff(cfg, 40))
+}
+
+func TestRetry(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	cfg := config.MongoRetryConfig{MaxAttempts: 5, BackoffBase: time.Second, BackoffMax: 2 * time.Second}
+
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- retry(context.Background(), cfg, clk, func(context.Context, int) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("server selection timeout")
+			}
+			return nil
+		})
+	}()
+
+	for _, pause := range []time.Duration{time.Second, 2 * time.Second} {
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Advance(pause)
+	}
+
+	require.NoError(t, <-done)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetry_maxAttempts(t *testing.T) {
+	cfg := config.MongoRetryConfig{MaxAttempts: 3, BackoffBase: time.Nanosecond, BackoffMax: time.Nanosecond}
+
+	attempts := 0
+	err := retry(context.Background(), cfg, clock.New(), func(_ context.Context, attempt int) error {
+		attempts++
+		assert.Equal(t, attempts, attempt)
+		return errors.New("unreachable")
+	})
+
+	assert.EqualError(t, err, "unreachable")
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetry_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := config.MongoRetryConfig{BackoffBase: time.Hour, BackoffMax: time.Hour}
+
+	err := retry(ctx, cfg, clock.New(), func(context.Context, int) error {
+		cancel()
+		return errors.New("unreachable")
+	})
+
+	assert.EqualError(t, err, "unreachable")
+}
+
+func TestNewClient_unreachable(t *testing.T) {
+	cfg := config.MongoConfig{
+		URI:                    "mongodb://127.0.0.1:1",
+		ServerSelectionTimeout: 50 * time.Millisecond,
+		ConnectRetry:           config.MongoRetryConfig{MaxAttempts: 2, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond},
+	}
+
+	_, err := NewClient(context.Background(), cfg, logger.New("error"))
+	assert.ErrorContains(t, err, "mongo: connect")
+}
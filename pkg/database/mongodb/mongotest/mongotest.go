@@ -0,0 +1,158 @@
+// Package mongotest предоставляет набор реплик MongoDB для тестов.
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// EnvURI — переменная с URI работающего набора реплик, на котором тестировать
+	// вместо запуска своего.
+	EnvURI = "TODO_TEST_MONGO_URI"
+	// EnvMongod — переменная с путем к mongod, если его нет в PATH.
+	EnvMongod = "TODO_TEST_MONGOD"
+
+	startTimeout = time.Minute
+	stopTimeout  = 10 * time.Second
+)
+
+// URI возвращает URI набора реплик MongoDB для t: указанный в
+// TODO_TEST_MONGO_URI или набор из одного узла, запущенный из mongod из
+// TODO_TEST_MONGOD или PATH и остановленный в конце t. Репозиторию задач
+// набор реплик нужен для транзакций. t пропускается, если mongod нет или его
+// не удалось запустить.
+func URI(t testing.TB) string {
+	t.Helper()
+
+	if uri := os.Getenv(EnvURI); uri != "" {
+		return uri
+	}
+
+	bin := os.Getenv(EnvMongod)
+	if bin == "" {
+		var err error
+		if bin, err = exec.LookPath("mongod"); err != nil {
+			t.Skipf("mongo: no mongod on PATH; set %s or %s", EnvURI, EnvMongod)
+		}
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Skipf("mongo: %s", err)
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "mongod.log")
+	cmd := exec.Command(bin,
+		"--replSet", "rs0",
+		"--bind_ip", "127.0.0.1",
+		"--port", strconv.Itoa(port),
+		"--dbpath", dir,
+		"--logpath", logPath,
+	)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("mongo: start %s: %s", bin, err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-exited:
+		case <-time.After(stopTimeout):
+			_ = cmd.Process.Kill()
+			<-exited
+		}
+	})
+
+	host := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	if err := initiate(host, exited); err != nil {
+		log, _ := os.ReadFile(logPath)
+		t.Skipf("mongo: cannot start a replica set with %s: %s\n%s", bin, err, log)
+	}
+
+	return "mongodb://" + host + "/?replicaSet=rs0&directConnection=true"
+}
+
+// initiate делает сервер host первичным узлом нового набора реплик.
+// Соединение не называет набор реплик: драйвер игнорирует узел, который еще не
+// сообщает имя набора.
+func initiate(host string, exited <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://"+host+"/?directConnection=true"))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	admin := client.Database("admin")
+	// wait опрашивает ready, каждый раз не дольше секунды, пока тот не вернет true.
+	wait := func(ready func(ctx context.Context) bool) error {
+		for {
+			probeCtx, cancel := context.WithTimeout(ctx, time.Second)
+			ok := ready(probeCtx)
+			cancel()
+			if ok {
+				return nil
+			}
+
+			select {
+			case <-exited:
+				return errors.New("mongod exited")
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+
+	if err := wait(func(ctx context.Context) bool { return client.Ping(ctx, nil) == nil }); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	config := bson.M{"_id": "rs0", "members": bson.A{bson.M{"_id": 0, "host": host}}}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: config}}).Err(); err != nil {
+		return fmt.Errorf("replSetInitiate: %w", err)
+	}
+
+	err = wait(func(ctx context.Context) bool {
+		var hello struct {
+			IsWritablePrimary bool `bson:"isWritablePrimary"`
+		}
+		err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+		return err == nil && hello.IsWritablePrimary
+	})
+	if err != nil {
+		return fmt.Errorf("wait for primary: %w", err)
+	}
+
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const timeout = 10 * time.Second
+
+// NewDB открывает пул database/sql на pgx для DSN и проверяет соединение.
+// Непустой password переопределяет пароль в DSN, чтобы его можно было не
+// хранить в файле конфигурации.
+func NewDB(dsn, password string) (*sql.DB, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if password != "" {
+		cfg.Password = password
+	}
+
+	db := stdlib.OpenDB(*cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
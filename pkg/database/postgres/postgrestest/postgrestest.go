@@ -0,0 +1,67 @@
+// Package postgrestest предоставляет сервер PostgreSQL для тестов.
+package postgrestest
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// EnvDSN — переменная с DSN работающего сервера, на котором тестировать
+// вместо запуска своего.
+const EnvDSN = "TODO_TEST_POSTGRES_DSN"
+
+// DSN возвращает DSN сервера PostgreSQL для t: указанный в
+// TODO_TEST_POSTGRES_DSN или сервер, запущенный через embedded-postgres и
+// остановленный в конце t. Его бинарные файлы скачиваются один раз и
+// кэшируются в ~/.embedded-postgres-go. t пропускается, если сервер не
+// удалось запустить, например без сети или от root, от которого initdb
+// отказывается работать.
+func DSN(t testing.TB) string {
+	t.Helper()
+
+	if dsn := os.Getenv(EnvDSN); dsn != "" {
+		return dsn
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Skipf("postgres: %s", err)
+	}
+
+	var log bytes.Buffer
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(t.TempDir()).
+		Database("todo").
+		Username("todo").
+		Password("todo").
+		StartTimeout(time.Minute).
+		Logger(&log)
+
+	server := embeddedpostgres.NewDatabase(cfg)
+	if err := server.Start(); err != nil {
+		t.Skipf("postgres: cannot start an embedded server, set %s to use a running one: %s\n%s", EnvDSN, err, log.String())
+	}
+	t.Cleanup(func() {
+		if err := server.Stop(); err != nil {
+			t.Errorf("postgres: stop: %s", err)
+		}
+	})
+
+	return cfg.GetConnectionURL() + "?sslmode=disable"
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
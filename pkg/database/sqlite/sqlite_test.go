@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "todo.db")
+
+	db, err := NewDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var mode string
+	require.NoError(t, db.QueryRow(`PRAGMA journal_mode`).Scan(&mode))
+	assert.Equal(t, "wal", mode)
+
+	var busyTimeout int
+	require.NoError(t, db.QueryRow(`PRAGMA busy_timeout`).Scan(&busyTimeout))
+	assert.Equal(t, 5000, busyTimeout)
+
+	assert.FileExists(t, path)
+}
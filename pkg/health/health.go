@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check сообщает, можно ли использовать зависимость. Он должен учитывать ctx.
+type Check func(ctx context.Context) error
+
+// Статусы Report и его результатов.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DefaultTimeout ограничивает каждую проверку, если Checker создан без таймаута.
+const DefaultTimeout = 2 * time.Second
+
+// ErrShuttingDown сообщается после вызова Shutdown.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Result — исход одной проверки.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report — готовность приложения: ok, только если ok каждая проверка.
+type Report struct {
+	Status string            `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK сообщает, готово ли приложение.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker выполняет проверки готовности приложения.
+type Checker struct {
+	mu           sync.RWMutex
+	names        []string
+	checks       map[string]Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New возвращает Checker, каждой проверке которого дается timeout на ответ.
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Checker{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+// Add регистрирует проверку под name, заменяя проверку с тем же именем.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Shutdown заставляет все следующие Ready возвращать ошибку, чтобы балансировщики
+// перестали слать запросы, пока сервер завершает начатые.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready выполняет все проверки параллельно и сообщает их результаты.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	names := append([]string(nil), c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Error = ErrShuttingDown.Error()
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	latency := time.Since(start)
+
+	result := Result{
+		Status:    StatusOK,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	c := New(time.Second)
+	c.Add("mongo", func(ctx context.Context) error { return nil })
+	c.Add("migrations", func(ctx context.Context) error { return nil })
+
+	report := c.Ready(context.Background())
+	assert.True(t, report.OK())
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["mongo"].Status)
+	assert.Empty(t, report.Checks["mongo"].Error)
+
+	c.Add("migrations", func(ctx context.Context) error { return errors.New("1 pending migration") })
+
+	report = c.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["mongo"].Status)
+	assert.Equal(t, Result{Status: StatusFail, LatencyMs: report.Checks["migrations"].LatencyMs, Error: "1 pending migration"}, report.Checks["migrations"])
+}
+
+func TestChecker_ReadyTimeout(t *testing.T) {
+	c := New(20 * time.Millisecond)
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Add("fast", func(ctx context.Context) error { return nil })
+
+	start := time.Now()
+	report := c.Ready(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second, "checks must run concurrently and time out")
+	assert.False(t, report.OK())
+	assert.Equal(t, "context deadline exceeded", report.Checks["slow"].Error)
+	assert.GreaterOrEqual(t, report.Checks["slow"].LatencyMs, float64(20))
+	assert.Equal(t, StatusOK, report.Checks["fast"].Status)
+}
+
+func TestChecker_Shutdown(t *testing.T) {
+	c := New(time.Second)
+	c.Add("mongo", func(ctx context.Context) error { return nil })
+	require.True(t, c.Ready(context.Background()).OK())
+
+	c.Shutdown()
+
+	report := c.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, "shutting down", report.Error)
+	assert.Equal(t, StatusOK, report.Checks["mongo"].Status)
+}
+
+func TestReportJSON(t *testing.T) {
+	report := Report{
+		Status: StatusFail,
+		Checks: map[string]Result{
+			"mongo":   {Status: StatusOK, LatencyMs: 1.5},
+			"workers": {Status: StatusFail, LatencyMs: 0.01, Error: "outbox relay: stopped"},
+		},
+	}
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"status": "fail",
+		"checks": {
+			"mongo": {"status": "ok", "latencyMs": 1.5},
+			"workers": {"status": "fail", "latencyMs": 0.01, "error": "outbox relay: stopped"}
+		}
+	}`, string(data))
+}
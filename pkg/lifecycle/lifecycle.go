@@ -0,0 +1,225 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+// DefaultStopTimeout ограничивает остановку хука, не задавшего свой Timeout.
+const DefaultStopTimeout = 5 * time.Second
+
+// Hook — компонент приложения. OnStart не должен блокироваться: долгая работа
+// уходит в горутину (см. Worker), которая сообщает об ошибках через
+// Manager.Fail. Любая из функций может быть nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+	// Timeout ограничивает OnStop; ноль значит DefaultStopTimeout.
+	Timeout time.Duration
+	// Alive, если задан, сообщает, работает ли еще компонент. Его использует
+	// Check.
+	Alive func() error
+}
+
+// Ошибки, которые возвращает Alive у Worker.
+var (
+	ErrNotStarted = errors.New("not started")
+	ErrStopped    = errors.New("stopped")
+)
+
+// Manager запускает хуки в порядке добавления и останавливает в обратном,
+// чтобы компонент останавливался раньше компонентов, от которых зависит.
+type Manager struct {
+	hooks   []Hook
+	signals []os.Signal
+	failed  chan error
+	logger  *logger.Logger
+}
+
+// New возвращает Manager, который завершает работу по SIGINT и SIGTERM.
+func New(l *logger.Logger) *Manager {
+	return &Manager{
+		signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+		failed:  make(chan error, 1),
+		logger:  l,
+	}
+}
+
+// Add регистрирует хук. Хуки нужно добавить до Run.
+func (m *Manager) Add(h Hook) {
+	m.hooks = append(m.hooks, h)
+}
+
+// Fail просит работающий Manager завершиться, потому что компонент отказал.
+// Сохраняется только первый отказ.
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+// Run запускает все хуки и блокируется, пока не завершится ctx, не придет
+// сигнал или не откажет компонент, затем останавливает запущенные хуки.
+// Неудачный запуск останавливает хуки, запущенные до него. Второй сигнал во
+// время остановки убивает процесс.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, m.signals...)
+	defer cancel()
+
+	var (
+		err     error
+		started int
+	)
+	for _, h := range m.hooks {
+		if h.OnStart != nil {
+			if err = h.OnStart(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", h.Name, err)
+				break
+			}
+		}
+		started++
+	}
+
+	if err == nil {
+		m.logger.Info("lifecycle: started")
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("lifecycle: shutting down")
+		case err = <-m.failed:
+			m.logger.Error(fmt.Errorf("lifecycle: shutting down: %w", err))
+		}
+	}
+
+	cancel()
+
+	return errors.Join(err, m.stop(m.hooks[:started]))
+}
+
+// Check сообщает о хуках, которые не работают, для проверок готовности.
+func (m *Manager) Check(context.Context) error {
+	var errs []error
+	for _, h := range m.hooks {
+		if h.Alive == nil {
+			continue
+		}
+		if err := h.Alive(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) stop(hooks []Hook) error {
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if h.OnStop == nil {
+			continue
+		}
+
+		timeout := h.Timeout
+		if timeout <= 0 {
+			timeout = DefaultStopTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := h.OnStop(ctx)
+		cancel()
+
+		if err != nil {
+			err = fmt.Errorf("stop %s: %w", h.Name, err)
+			m.logger.Error(err)
+			errs = append(errs, err)
+			continue
+		}
+		m.logger.Info("lifecycle: stopped %s", h.Name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Worker превращает в хук цикл, работающий до отмены своего контекста, как
+// методы Run фоновых задач. Остановка отменяет цикл и ждет его возврата;
+// цикл, вернувшийся сам, отмечается через Alive.
+func Worker(name string, run func(ctx context.Context)) Hook {
+	w := &worker{run: run}
+
+	return Hook{
+		Name:    name,
+		OnStart: w.start,
+		OnStop:  w.stop,
+		Alive:   w.alive,
+	}
+}
+
+type worker struct {
+	run func(ctx context.Context)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (w *worker) start(context.Context) error {
+	// Контекст запуска завершается сигналом остановки; воркер должен работать,
+	// пока не придет его очередь остановиться.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	w.mu.Lock()
+	w.cancel, w.done = cancel, done
+	w.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		w.run(ctx)
+	}()
+	return nil
+}
+
+func (w *worker) stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *worker) alive() error {
+	w.mu.Lock()
+	done := w.done
+	w.mu.Unlock()
+
+	if done == nil {
+		return ErrNotStarted
+	}
+
+	select {
+	case <-done:
+		return ErrStopped
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/pkg/logger"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) hook(name string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func TestManager_Run(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New("local"))
+	m.Add(rec.hook("db", nil))
+	m.Add(rec.hook("worker", nil))
+	m.Add(rec.hook("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, m.Run(ctx))
+	assert.Equal(t, []string{
+		"start db", "start worker", "start http",
+		"stop http", "stop worker", "stop db",
+	}, rec.get())
+}
+
+func TestManager_RunSignal(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New("local"))
+	m.Add(rec.hook("db", nil))
+	m.Add(Hook{
+		Name: "signal",
+		OnStart: func(context.Context) error {
+			return syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		},
+	})
+
+	require.NoError(t, m.Run(context.Background()))
+	assert.Equal(t, []string{"start db", "stop db"}, rec.get())
+}
+
+func TestManager_RunStartError(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New("local"))
+	m.Add(rec.hook("db", nil))
+	m.Add(rec.hook("http", errors.New("address already in use")))
+	m.Add(rec.hook("never", nil))
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "start http: address already in use")
+	assert.Equal(t, []string{"start db", "start http", "stop db"}, rec.get())
+}
+
+func TestManager_Fail(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New("local"))
+	m.Add(rec.hook("db", nil))
+	m.Add(Hook{
+		Name: "http",
+		OnStart: func(context.Context) error {
+			m.Fail(errors.New("serve failed"))
+			m.Fail(errors.New("second failure"))
+			return nil
+		},
+	})
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "serve failed")
+	assert.Equal(t, []string{"start db", "stop db"}, rec.get())
+}
+
+func TestManager_StopError(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New("local"))
+	m.Add(rec.hook("db", nil))
+	m.Add(Hook{
+		Name: "stuck",
+		OnStop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "stop stuck: context deadline exceeded")
+	// Зависший компонент не мешает остановке остальных.
+	assert.Equal(t, []string{"start db", "stop db"}, rec.get())
+}
+
+func TestWorker(t *testing.T) {
+	running := make(chan struct{})
+	stopped := false
+
+	w := Worker("loop", func(ctx context.Context) {
+		close(running)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		stopped = true
+	})
+
+	startCtx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, w.OnStart(startCtx))
+	<-running
+
+	// Отмена контекста запуска не должна останавливать воркер.
+	cancel()
+	assert.False(t, stopped)
+
+	require.NoError(t, w.OnStop(context.Background()))
+	assert.True(t, stopped)
+}
+
+func TestWorkerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	w := Worker("stuck", func(ctx context.Context) {
+		<-release
+	})
+	require.NoError(t, w.OnStart(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, w.OnStop(ctx), context.DeadlineExceeded)
+}
+
+func TestManager_Check(t *testing.T) {
+	m := New(logger.New("local"))
+
+	exit := make(chan struct{})
+	m.Add(Worker("relay", func(ctx context.Context) {
+		<-ctx.Done()
+	}))
+	m.Add(Worker("scheduler", func(ctx context.Context) {
+		<-exit
+	}))
+	m.Add(Hook{Name: "db"})
+
+	assert.EqualError(t, m.Check(context.Background()), "relay: not started\nscheduler: not started")
+
+	for _, h := range m.hooks {
+		if h.OnStart != nil {
+			require.NoError(t, h.OnStart(context.Background()))
+		}
+	}
+	assert.NoError(t, m.Check(context.Background()))
+
+	close(exit)
+	require.Eventually(t, func() bool {
+		return errors.Is(m.Check(context.Background()), ErrStopped)
+	}, time.Second, time.Millisecond)
+	assert.EqualError(t, m.Check(context.Background()), "scheduler: stopped")
+
+	require.NoError(t, m.stop(m.hooks))
+}
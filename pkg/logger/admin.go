@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// LevelRequest меняет уровень пакета или уровень по умолчанию, если Package
+// пуст. Пустой Level снимает переопределение для Package.
+type LevelRequest struct {
+	Package string `json:"package,omitempty"`
+	Level   string `json:"level"`
+}
+
+// LevelResponse — состояние уровней.
+type LevelResponse struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// LevelHandler отдает уровни: GET возвращает их, PUT меняет один по
+// LevelRequest. Запросы должны нести "Authorization: Bearer <token>".
+func LevelHandler(levels *Levels, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req LevelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid input body"})
+				return
+			}
+			if req.Package == "" && req.Level == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "level is required"})
+				return
+			}
+			if err := levels.Set(req.Package, req.Level); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		level, packages := levels.Snapshot()
+		writeJSON(w, http.StatusOK, LevelResponse{Level: level, Packages: packages})
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	got := r.Header.Get("Authorization")
+	want := "Bearer " + token
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+func fileConfig(t *testing.T, format string) (config.LogConfig, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	return config.LogConfig{
+		Level:   "info",
+		Format:  format,
+		Outputs: []config.LogOutputConfig{{Type: OutputFile, Path: path, MaxSizeMB: 1}},
+	}, path
+}
+
+func TestNewFromConfig_file(t *testing.T) {
+	cfg, path := fileConfig(t, FormatJSON)
+	cfg.Packages = map[string]string{"repository": "debug", "webhook": "error"}
+
+	l, err := NewFromConfig(cfg)
+	require.NoError(t, err)
+
+	l.Debug("hidden")
+	l.Info("started")
+	l.Package("repository").Debug("query")
+	l.Package("webhook").Warn("hidden too")
+	l.Package("webhook").With("delivery", "1").Error("delivery failed")
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	got := lines(t, bytes.NewBuffer(data))
+	require.Len(t, got, 3)
+
+	assert.Equal(t, "started", got[0]["message"])
+	assert.NotContains(t, got[0], "pkg")
+	assert.Equal(t, "query", got[1]["message"])
+	assert.Equal(t, "repository", got[1]["pkg"])
+	assert.Equal(t, "delivery failed", got[2]["message"])
+	assert.Equal(t, "webhook", got[2]["pkg"])
+	assert.Equal(t, "1", got[2]["delivery"])
+}
+
+func TestNewFromConfig_console(t *testing.T) {
+	cfg, path := fileConfig(t, FormatConsole)
+
+	l, err := NewFromConfig(cfg)
+	require.NoError(t, err)
+	l.With("task_id", "42").Warn("slow query")
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	line := string(data)
+	assert.Contains(t, line, "WRN")
+	assert.Contains(t, line, "slow query")
+	assert.Contains(t, line, "task_id=42")
+	assert.NotContains(t, line, "\x1b[", "files get no colors")
+}
+
+func TestNewFromConfig_sampling(t *testing.T) {
+	cfg, path := fileConfig(t, FormatJSON)
+	cfg.Level = "debug"
+	cfg.Sampling = config.LogSamplingConfig{Burst: 3, Period: time.Hour, Thereafter: 5}
+
+	l, err := NewFromConfig(cfg)
+	require.NoError(t, err)
+	for i := 0; i < 13; i++ {
+		l.Debug("tick %d", i)
+	}
+	for i := 0; i < 4; i++ {
+		l.Info("not sampled")
+	}
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	got := lines(t, bytes.NewBuffer(data))
+
+	var debug, info int
+	for _, line := range got {
+		if line["level"] == "debug" {
+			debug++
+		} else {
+			info++
+		}
+	}
+	// 3 в пределах burst, затем 1-я и 6-я из оставшихся 10.
+	assert.Equal(t, 5, debug)
+	assert.Equal(t, 4, info)
+}
+
+func TestNewFromConfig_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LogConfig
+		err  string
+	}{
+		{name: "level", cfg: config.LogConfig{Level: "verbose", Format: FormatJSON}, err: `log.level: unknown log level "verbose"`},
+		{name: "package level", cfg: config.LogConfig{Level: "info", Format: FormatJSON, Packages: map[string]string{"outbox": "loud"}}, err: `log.packages.outbox: unknown log level "loud"`},
+		{name: "format", cfg: config.LogConfig{Level: "info", Format: "xml"}, err: `unknown log.format "xml"`},
+		{name: "output", cfg: config.LogConfig{Level: "info", Format: FormatJSON, Outputs: []config.LogOutputConfig{{Type: "kafka"}}}, err: `log.outputs[0]: unknown type "kafka"`},
+		{name: "file path", cfg: config.LogConfig{Level: "info", Format: FormatJSON, Outputs: []config.LogOutputConfig{{Type: OutputFile}}}, err: "log.outputs[0]: file output needs a path"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewFromConfig(test.cfg)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriter(&buf, "info")
+	h := LevelHandler(l.Levels(), "secret")
+
+	do := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/log/level", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "wrong", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "secret", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "secret", `{"level":"loud"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "secret", `{}`).Code)
+
+	w := do(http.MethodGet, "secret", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","packages":{}}`, w.Body.String())
+
+	l.Package("repository").Debug("before")
+	w = do(http.MethodPut, "secret", `{"package":"repository","level":"debug"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","packages":{"repository":"debug"}}`, w.Body.String())
+	l.Package("repository").Debug("after")
+	l.Debug("still hidden")
+
+	w = do(http.MethodPut, "secret", `{"level":"error"}`)
+	assert.JSONEq(t, `{"level":"error","packages":{"repository":"debug"}}`, w.Body.String())
+	w = do(http.MethodPut, "secret", `{"package":"repository"}`)
+	assert.JSONEq(t, `{"level":"error","packages":{}}`, w.Body.String())
+	l.Package("repository").Info("hidden again")
+
+	got := lines(t, &buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, "after", got[0]["message"])
+
+	var resp LevelResponse
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "secret", "").Body.Bytes(), &resp))
+	assert.Equal(t, "error", resp.Level)
+}
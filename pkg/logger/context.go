@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+var std atomic.Pointer[Logger]
+
+func init() {
+	std.Store(New("info"))
+}
+
+// SetDefault задает логгер, который FromContext возвращает для контекстов без
+// логгера, например контекстов фоновых задач.
+func SetDefault(l *Logger) {
+	std.Store(l)
+}
+
+// Default возвращает логгер, заданный SetDefault.
+func Default() *Logger {
+	return std.Load()
+}
+
+// WithContext возвращает копию ctx, несущую l.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext возвращает логгер из ctx, для запроса — логгер с его request ID,
+// иначе логгер по умолчанию.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+
+	return Default()
+}
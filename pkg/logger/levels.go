@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Levels хранит уровни логгера и всех производных от него: уровень по умолчанию
+// и переопределения для отдельных пакетов. Их можно менять во время работы.
+type Levels struct {
+	mu       sync.RWMutex
+	level    zerolog.Level
+	packages map[string]zerolog.Level
+}
+
+// ParseLevel разбирает debug, info, warn или error.
+func ParseLevel(level string) (zerolog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zerolog.DebugLevel, nil
+	case "info":
+		return zerolog.InfoLevel, nil
+	case "warn":
+		return zerolog.WarnLevel, nil
+	case "error":
+		return zerolog.ErrorLevel, nil
+	default:
+		return zerolog.NoLevel, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+func newLevels(level zerolog.Level) *Levels {
+	return &Levels{level: level, packages: make(map[string]zerolog.Level)}
+}
+
+// Enabled сообщает, пишут ли логгеры pkg строку уровня level.
+func (v *Levels) Enabled(pkg string, level zerolog.Level) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	min, ok := v.packages[pkg]
+	if !ok {
+		min = v.level
+	}
+
+	return level >= min
+}
+
+// Set задает уровень pkg или уровень по умолчанию, если pkg пуст. Пустой
+// level снимает переопределение для pkg.
+func (v *Levels) Set(pkg, level string) error {
+	if pkg != "" && level == "" {
+		v.mu.Lock()
+		delete(v.packages, pkg)
+		v.mu.Unlock()
+		return nil
+	}
+
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if pkg == "" {
+		v.level = l
+	} else {
+		v.packages[pkg] = l
+	}
+	return nil
+}
+
+// Replace задает уровень по умолчанию и заменяет все переопределения пакетов,
+// как они загружены из log.level и log.packages. Если хоть один уровень
+// неверен, ничего не меняется.
+func (v *Levels) Replace(level string, packages map[string]string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]zerolog.Level, len(packages))
+	for pkg, level := range packages {
+		if parsed[pkg], err = ParseLevel(level); err != nil {
+			return fmt.Errorf("%s: %w", pkg, err)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.level = l
+	v.packages = parsed
+	return nil
+}
+
+// Snapshot возвращает уровень по умолчанию и переопределения пакетов.
+func (v *Levels) Snapshot() (string, map[string]string) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	packages := make(map[string]string, len(v.packages))
+	for pkg, l := range v.packages {
+		packages[pkg] = l.String()
+	}
+
+	return v.level.String(), packages
+}
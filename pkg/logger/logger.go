@@ -2,10 +2,12 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/yervsil/toDo-microservice/config"
 )
 
 // Interface -.
@@ -17,86 +19,197 @@ type Interface interface {
 	Fatal(message interface{}, args ...interface{})
 }
 
-// Logger -.
+// Logger пишет строки на уровне вызванного метода. Логгеры неизменяемы: With
+// и Package возвращают дочерние логгеры, которые делят выводы и уровни
+// родителя.
 type Logger struct {
-	logger *zerolog.Logger
+	logger  *zerolog.Logger
+	levels  *Levels
+	pkg     string
+	closers []io.Closer
 }
 
 var _ Interface = (*Logger)(nil)
 
-// New -.
+// New возвращает JSON-логгер в stdout, отбрасывающий строки ниже level (debug,
+// info, warn или error; любое другое значение значит info).
 func New(level string) *Logger {
-	var l zerolog.Level
-
-	switch strings.ToLower(level) {
-	case "error":
-		l = zerolog.ErrorLevel
-	case "warn":
-		l = zerolog.WarnLevel
-	case "info":
-		l = zerolog.InfoLevel
-	case "debug":
-		l = zerolog.DebugLevel
-	default:
+	return NewWriter(os.Stdout, level)
+}
+
+// NewWriter — New с записью в w.
+func NewWriter(w io.Writer, level string) *Logger {
+	l, err := ParseLevel(level)
+	if err != nil {
 		l = zerolog.InfoLevel
 	}
 
-	zerolog.SetGlobalLevel(l)
+	return newLogger(w, newLevels(l))
+}
 
-	skipFrameCount := 3
-	logger := zerolog.New(os.Stdout).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).Logger()
+// NewFromConfig возвращает логгер, пишущий в выводы cfg. Close освобождает
+// его файлы и соединения с syslog.
+func NewFromConfig(cfg config.LogConfig) (*Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("log.level: %w", err)
+	}
+
+	levels := newLevels(level)
+	for pkg, l := range cfg.Packages {
+		if err := levels.Set(pkg, l); err != nil {
+			return nil, fmt.Errorf("log.packages.%s: %w", pkg, err)
+		}
+	}
+
+	w, closers, err := openOutputs(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLogger(w, levels)
+	l.closers = closers
+
+	if s := cfg.Sampling; s.Burst > 0 {
+		period := s.Period
+		if period <= 0 {
+			period = time.Second
+		}
+
+		// Без следующего сэмплера после burst ничего не пишется.
+		var next zerolog.Sampler
+		if s.Thereafter > 0 {
+			next = &zerolog.BasicSampler{N: s.Thereafter}
+		}
+
+		sampled := l.logger.Sample(zerolog.LevelSampler{
+			DebugSampler: &zerolog.BurstSampler{Burst: s.Burst, Period: period, NextSampler: next},
+		})
+		l.logger = &sampled
+	}
+
+	return l, nil
+}
+
+func newLogger(w io.Writer, levels *Levels) *Logger {
+	// Уровни проверяет Logger, zerolog о них не знает.
+	logger := zerolog.New(w).Level(zerolog.DebugLevel).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + 2).Logger()
 
 	return &Logger{
 		logger: &logger,
+		levels: levels,
+	}
+}
+
+// Levels возвращает уровни, общие для l и производных от него логгеров.
+func (l *Logger) Levels() *Levels {
+	return l.levels
+}
+
+// Close освобождает выводы, открытые NewFromConfig.
+func (l *Logger) Close() error {
+	return closeAll(l.closers)
+}
+
+// Package возвращает дочерний логгер для пакета name: его строки несут пакет
+// в поле pkg и подчиняются уровню, заданному для него в log.packages.
+func (l *Logger) Package(name string) *Logger {
+	child := *l
+	child.pkg = name
+	return &child
+}
+
+// With возвращает дочерний логгер, добавляющий key к каждой строке. Строки,
+// числа, булевы значения, длительности, время и ошибки сохраняют свои типы
+// JSON; остальные значения сериализуются как есть.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	ctx := l.logger.With()
+
+	switch v := value.(type) {
+	case string:
+		ctx = ctx.Str(key, v)
+	case int:
+		ctx = ctx.Int(key, v)
+	case int64:
+		ctx = ctx.Int64(key, v)
+	case uint64:
+		ctx = ctx.Uint64(key, v)
+	case float64:
+		ctx = ctx.Float64(key, v)
+	case bool:
+		ctx = ctx.Bool(key, v)
+	case time.Duration:
+		ctx = ctx.Dur(key, v)
+	case time.Time:
+		ctx = ctx.Time(key, v)
+	case error:
+		ctx = ctx.AnErr(key, v)
+	case fmt.Stringer:
+		ctx = ctx.Stringer(key, v)
+	default:
+		ctx = ctx.Interface(key, v)
 	}
+
+	logger := ctx.Logger()
+	child := *l
+	child.logger = &logger
+	return &child
 }
 
 // Debug -.
 func (l *Logger) Debug(message interface{}, args ...interface{}) {
-	l.msg("debug", message, args...)
+	l.write(zerolog.DebugLevel, message, args...)
 }
 
 // Info -.
 func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.write(zerolog.InfoLevel, message, args...)
 }
 
 // Warn -.
 func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.write(zerolog.WarnLevel, message, args...)
 }
 
 // Error -.
 func (l *Logger) Error(message interface{}, args ...interface{}) {
-	if l.logger.GetLevel() == zerolog.DebugLevel {
-		l.Debug(message, args...)
-	}
-
-	l.msg("error", message, args...)
+	l.write(zerolog.ErrorLevel, message, args...)
 }
 
-// Fatal -.
+// Fatal пишет на уровне fatal и завершает процесс.
 func (l *Logger) Fatal(message interface{}, args ...interface{}) {
-	l.msg("fatal", message, args...)
+	l.write(zerolog.FatalLevel, message, args...)
 
 	os.Exit(1)
 }
 
-func (l *Logger) log(message string, args ...interface{}) {
-	if len(args) == 0 {
-		l.logger.Info().Msg(message)
-	} else {
-		l.logger.Info().Msgf(message, args...)
+func (l *Logger) write(level zerolog.Level, message interface{}, args ...interface{}) {
+	if !l.levels.Enabled(l.pkg, level) {
+		return
+	}
+
+	// Событие nil, когда строка отброшена выборкой.
+	e := l.logger.WithLevel(level)
+	if e == nil {
+		return
+	}
+	if l.pkg != "" {
+		e = e.Str("pkg", l.pkg)
 	}
-}
 
-func (l *Logger) msg(level string, message interface{}, args ...interface{}) {
-	switch msg := message.(type) {
+	var msg string
+	switch m := message.(type) {
 	case error:
-		l.log(msg.Error(), args...)
+		msg = m.Error()
 	case string:
-		l.log(msg, args...)
+		msg = m
 	default:
-		l.log(fmt.Sprintf("%s message %v has unknown type %v", level, message, msg), args...)
+		msg = fmt.Sprintf("message %v has unknown type %T", message, message)
 	}
-}
\ No newline at end of file
+
+	if len(args) == 0 {
+		e.Msg(msg)
+	} else {
+		e.Msgf(msg, args...)
+	}
+}
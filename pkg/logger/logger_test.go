@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var res []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestLogger_levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriter(&buf, "info")
+
+	l.Debug("hidden")
+	l.Info("task %s created", "1")
+	l.Warn("slow")
+	l.Error(errors.New("connection refused"))
+
+	got := lines(t, &buf)
+	require.Len(t, got, 3)
+	assert.Equal(t, "info", got[0]["level"])
+	assert.Equal(t, "task 1 created", got[0]["message"])
+	assert.Equal(t, "warn", got[1]["level"])
+	assert.Equal(t, "error", got[2]["level"])
+	assert.Equal(t, "connection refused", got[2]["message"])
+	assert.Contains(t, got[0]["caller"], "logger_test.go")
+
+	buf.Reset()
+	NewWriter(&buf, "error").Warn("dropped")
+	assert.Empty(t, buf.String())
+}
+
+func TestLevels_Replace(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriter(&buf, "info")
+	require.NoError(t, l.Levels().Set("repository", "debug"))
+
+	require.NoError(t, l.Levels().Replace("warn", map[string]string{"http": "debug"}))
+	level, packages := l.Levels().Snapshot()
+	assert.Equal(t, "warn", level)
+	assert.Equal(t, map[string]string{"http": "debug"}, packages)
+
+	l.Package("repository").Info("dropped")
+	l.Package("http").Debug("written")
+	got := lines(t, &buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, "written", got[0]["message"])
+
+	assert.Error(t, l.Levels().Replace("info", map[string]string{"http": "loud"}))
+	level, packages = l.Levels().Snapshot()
+	assert.Equal(t, "warn", level, "nothing changes on error")
+	assert.Equal(t, map[string]string{"http": "debug"}, packages)
+}
+
+func TestLogger_With(t *testing.T) {
+	var buf bytes.Buffer
+	base := NewWriter(&buf, "debug")
+
+	l := base.
+		With("request_id", "abc").
+		With("status", 201).
+		With("ok", true).
+		With("latency", 1500*time.Microsecond).
+		With("err", errors.New("boom"))
+	l.Info("request")
+	base.Info("base")
+
+	got := lines(t, &buf)
+	require.Len(t, got, 2)
+	assert.Equal(t, "abc", got[0]["request_id"])
+	assert.Equal(t, float64(201), got[0]["status"])
+	assert.Equal(t, true, got[0]["ok"])
+	assert.Equal(t, 1.5, got[0]["latency"])
+	assert.Equal(t, "boom", got[0]["err"])
+	assert.NotContains(t, got[1], "request_id", "With must not change the parent")
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	def := NewWriter(&buf, "info")
+
+	previous := Default()
+	SetDefault(def)
+	t.Cleanup(func() { SetDefault(previous) })
+
+	assert.Same(t, def, FromContext(context.Background()))
+
+	l := def.With("request_id", "abc")
+	assert.Same(t, l, FromContext(WithContext(context.Background(), l)))
+}
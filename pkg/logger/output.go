@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yervsil/toDo-microservice/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Форматы для log.format.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Выводы для log.outputs[].type.
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+)
+
+// openOutputs открывает writer'ы cfg.Outputs. Syslog получает JSON при любом
+// формате: его приоритет уже несет уровень.
+func openOutputs(cfg config.LogConfig) (zerolog.LevelWriter, []io.Closer, error) {
+	if cfg.Format != FormatJSON && cfg.Format != FormatConsole {
+		return nil, nil, fmt.Errorf("unknown log.format %q", cfg.Format)
+	}
+
+	var (
+		writers []io.Writer
+		closers []io.Closer
+	)
+	fail := func(err error) (zerolog.LevelWriter, []io.Closer, error) {
+		return nil, nil, errors.Join(err, closeAll(closers))
+	}
+
+	for i, out := range cfg.Outputs {
+		switch out.Type {
+		case OutputStdout:
+			writers = append(writers, format(cfg.Format, os.Stdout, false))
+
+		case OutputFile:
+			if out.Path == "" {
+				return fail(fmt.Errorf("log.outputs[%d]: file output needs a path", i))
+			}
+			file := &lumberjack.Logger{
+				Filename:   out.Path,
+				MaxSize:    out.MaxSizeMB,
+				MaxAge:     out.MaxAgeDays,
+				MaxBackups: out.MaxBackups,
+				Compress:   out.Compress,
+			}
+			writers = append(writers, format(cfg.Format, file, true))
+			closers = append(closers, file)
+
+		case OutputSyslog:
+			w, err := dialSyslog(out.Network, out.Address, out.Tag)
+			if err != nil {
+				return fail(fmt.Errorf("log.outputs[%d]: %w", i, err))
+			}
+			writers = append(writers, zerolog.SyslogLevelWriter(w))
+			closers = append(closers, w)
+
+		default:
+			return fail(fmt.Errorf("log.outputs[%d]: unknown type %q", i, out.Type))
+		}
+	}
+
+	if len(writers) == 0 {
+		writers = append(writers, format(cfg.Format, os.Stdout, false))
+	}
+
+	return zerolog.MultiLevelWriter(writers...), closers, nil
+}
+
+func format(name string, w io.Writer, noColor bool) io.Writer {
+	if name == FormatConsole {
+		return zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339, NoColor: noColor}
+	}
+
+	return w
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
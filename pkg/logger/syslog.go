@@ -0,0 +1,24 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/rs/zerolog"
+)
+
+type syslogWriter interface {
+	zerolog.SyslogWriter
+	Close() error
+}
+
+func dialSyslog(network, address, tag string) (syslogWriter, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %w", err)
+	}
+
+	return w, nil
+}
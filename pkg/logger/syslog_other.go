@@ -0,0 +1,18 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+type syslogWriter interface {
+	zerolog.SyslogWriter
+	Close() error
+}
+
+func dialSyslog(network, address, tag string) (syslogWriter, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+func TestNewFromConfig_syslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	l, err := NewFromConfig(config.LogConfig{
+		Level:   "info",
+		Format:  FormatJSON,
+		Outputs: []config.LogOutputConfig{{Type: OutputSyslog, Network: "udp", Address: conn.LocalAddr().String(), Tag: "todo"}},
+	})
+	require.NoError(t, err)
+	defer l.Close()
+
+	l.Warn("disk almost full")
+
+	buf := make([]byte, 2048)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	msg := string(buf[:n])
+	// daemon.warning: 3*8 + 4
+	assert.Contains(t, msg, "<28>")
+	assert.Contains(t, msg, "todo")
+	assert.Contains(t, msg, `"message":"disk almost full"`)
+}
@@ -0,0 +1,229 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Message — письмо с текстовым телом, HTML-телом или обоими.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender доставляет письма.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPClient отправляет письма через SMTP-релей. STARTTLS используется, когда
+// сервер его предлагает; учетные данные отправляются, только если заданы.
+type SMTPClient struct {
+	addr    string
+	host    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+var _ Sender = (*SMTPClient)(nil)
+
+// NewSMTPClient -.
+func NewSMTPClient(cfg config.SMTPConfig) *SMTPClient {
+	c := &SMTPClient{
+		addr:    net.JoinHostPort(cfg.Host, cfg.Port),
+		host:    cfg.Host,
+		from:    cfg.From,
+		timeout: cfg.Timeout,
+	}
+
+	if cfg.Username != "" {
+		c.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+
+	return c
+}
+
+// Send доставляет msg, используя настроенный адрес отправителя, когда msg.From
+// пуст.
+func (c *SMTPClient) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = c.from
+	}
+	if len(msg.To) == 0 {
+		return errors.New("mail: no recipients")
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("mail: dial %s: %w", c.addr, err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+			return fmt.Errorf("mail: starttls: %w", err)
+		}
+	}
+	if c.auth != nil {
+		if err := client.Auth(c.auth); err != nil {
+			return fmt.Errorf("mail: auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From); err != nil {
+		return fmt.Errorf("mail: from: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("mail: rcpt %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("mail: data: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("mail: data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mail: data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// Bytes выводит msg как сообщение RFC 5322. Если заданы оба тела, сообщение
+// — multipart/alternative с текстовой частью первой.
+func (m Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", m.From)
+	header.Set("To", strings.Join(m.To, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-Id", messageID(m.From))
+	header.Set("Mime-Version", "1.0")
+
+	switch {
+	case m.Text != "" && m.HTML != "":
+		mw := multipart.NewWriter(&buf)
+		header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+		writeHeader(&buf, header)
+
+		if err := writePart(mw, "text/plain", m.Text); err != nil {
+			return nil, err
+		}
+		if err := writePart(mw, "text/html", m.HTML); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	case m.HTML != "":
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, m.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, m.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-Id", "Mime-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if v := header.Get(key); v != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeQuotedPrintable(part, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
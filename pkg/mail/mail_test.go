@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/mail/mailtest"
+)
+
+func TestSMTPClient_Send(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	client := NewSMTPClient(config.SMTPConfig{Host: srv.Host(), Port: srv.Port(), From: "todo@example.com"})
+
+	err := client.Send(context.Background(), Message{
+		To:      []string{"anna@example.com", "boris@example.com"},
+		Subject: "Задачи на сегодня",
+		Text:    "Купить книгу",
+		HTML:    "<p>Купить книгу</p>",
+	})
+	require.NoError(t, err)
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "todo@example.com", messages[0].From)
+	assert.Equal(t, []string{"anna@example.com", "boris@example.com"}, messages[0].To)
+
+	msg, err := messages[0].Parse()
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Задачи на сегодня", subject)
+	assert.Equal(t, "anna@example.com, boris@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0]+": "+string(body))
+	}
+	assert.Equal(t, []string{"text/plain: Купить книгу", "text/html: <p>Купить книгу</p>"}, bodies)
+}
+
+func TestSMTPClient_SendErrors(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	client := NewSMTPClient(config.SMTPConfig{Host: srv.Host(), Port: srv.Port(), From: "todo@example.com"})
+
+	err := client.Send(context.Background(), Message{Subject: "no recipients", Text: "x"})
+	assert.EqualError(t, err, "mail: no recipients")
+
+	srv.FailNext(1)
+	err = client.Send(context.Background(), Message{To: []string{"anna@example.com"}, Text: "x"})
+	assert.Error(t, err)
+	assert.Empty(t, srv.Messages())
+}
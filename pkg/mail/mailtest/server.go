@@ -0,0 +1,177 @@
+// Package mailtest предоставляет SMTP-сервер внутри процесса для тестов, в
+// духе net/http/httptest.
+package mailtest
+
+import (
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message — письмо, принятое Server.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Parse разбирает исходное сообщение.
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(strings.NewReader(string(m.Data)))
+}
+
+// Server — минимальный SMTP-сервер на loopback-интерфейсе. Он принимает все
+// письма без аутентификации и TLS.
+type Server struct {
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	failures int
+}
+
+// NewServer запускает Server на случайном локальном порту.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mailtest: failed to listen: " + err.Error())
+	}
+
+	s := &Server{Addr: l.Addr().String(), listener: l}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s
+}
+
+// Host возвращает хост из Addr.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+// Port возвращает порт из Addr.
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	return port
+}
+
+// Messages возвращает письма, принятые к этому моменту.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// FailNext заставляет сервер отклонить следующие n писем временной ошибкой.
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+}
+
+// Close останавливает сервер и ждет завершения открытых сессий.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *Server) session(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) bool {
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+
+	if !reply(220, "mailtest ESMTP") {
+		return
+	}
+
+	var current Message
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply(250, "mailtest")
+		case "MAIL":
+			current = Message{From: address(arg)}
+			reply(250, "OK")
+		case "RCPT":
+			current.To = append(current.To, address(arg))
+			reply(250, "OK")
+		case "DATA":
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = data
+
+			if s.accept(current) {
+				reply(250, "OK")
+			} else {
+				reply(451, "Temporary failure")
+			}
+			current = Message{}
+		case "RSET", "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, "Command not implemented")
+		}
+	}
+}
+
+func (s *Server) accept(m Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return false
+	}
+
+	s.messages = append(s.messages, m)
+
+	return true
+}
+
+// address извлекает почтовый ящик из "FROM:<a@b>" или "TO:<a@b>".
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+
+	return strings.Trim(addr, "<>")
+}
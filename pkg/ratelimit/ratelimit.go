@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yervsil/toDo-microservice/config"
+)
+
+// Хранилища для rateLimit.store.
+const (
+	StoreMemory = "memory"
+)
+
+// Limit — корзина токенов: она вмещает до Burst токенов и получает Requests
+// токенов каждые Per. Каждый запрос забирает один.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// rate возвращает число токенов, получаемых за секунду.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result — состояние корзины после запроса.
+type Result struct {
+	Allowed bool
+	// Remaining — число оставшихся целых токенов.
+	Remaining int
+	// RetryAfter — сколько ждать следующего токена, если запрос не Allowed.
+	RetryAfter time.Duration
+	// Reset — сколько ждать, пока корзина снова заполнится.
+	Reset time.Duration
+}
+
+// Store хранит корзины. Хранилища, общие для нескольких реплик, заставляют их
+// соблюдать один предел вместе; тогда Take должен быть атомарным для всех.
+type Store interface {
+	// Take забирает токен из корзины key, при необходимости создавая ее полной.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule ограничивает запросы группы маршрутов.
+type Rule struct {
+	Name     string
+	Methods  []string
+	Prefixes []string
+	Limit    Limit
+}
+
+func (r Rule) matches(method, path string) bool {
+	if len(r.Methods) > 0 && !contains(r.Methods, method) {
+		return false
+	}
+	if len(r.Prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range r.Prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Limiter применяет к субъекту запроса первое подходящее правило.
+type Limiter struct {
+	mu    sync.RWMutex
+	rules []Rule
+	store Store
+}
+
+// New возвращает Limiter для групп из cfg.
+func New(cfg config.RateLimitConfig, store Store) (*Limiter, error) {
+	rules, err := newRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Limiter{rules: rules, store: store}, nil
+}
+
+// Update заменяет правила группами из cfg. Корзины групп, сохранивших имя,
+// сохраняют свои токены. При ошибке правила не меняются.
+func (l *Limiter) Update(cfg config.RateLimitConfig) error {
+	rules, err := newRules(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.rules = rules
+	l.mu.Unlock()
+
+	return nil
+}
+
+func newRules(cfg config.RateLimitConfig) ([]Rule, error) {
+	var (
+		rules []Rule
+		errs  []error
+	)
+	seen := make(map[string]bool)
+	for i, g := range cfg.Groups {
+		if g.Name == "" {
+			errs = append(errs, fmt.Errorf("rateLimit.groups[%d]: name is required", i))
+		} else if seen[g.Name] {
+			errs = append(errs, fmt.Errorf("rateLimit.groups[%d]: duplicate name %q", i, g.Name))
+		}
+		seen[g.Name] = true
+
+		if g.Requests <= 0 || g.Per <= 0 {
+			errs = append(errs, fmt.Errorf("rateLimit.groups[%d]: requests and per must be positive", i))
+		}
+
+		burst := g.Burst
+		if burst <= 0 {
+			burst = g.Requests
+		}
+
+		rules = append(rules, Rule{
+			Name:     g.Name,
+			Methods:  g.Methods,
+			Prefixes: g.Paths,
+			Limit:    Limit{Requests: g.Requests, Per: g.Per, Burst: burst},
+		})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Match возвращает правило для запроса; false значит, что он не ограничен.
+func (l *Limiter) Match(method, path string) (Rule, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, r := range l.rules {
+		if r.matches(method, path) {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Allow забирает токен для principal из корзины правила rule.
+func (l *Limiter) Allow(ctx context.Context, rule Rule, principal string) (Result, error) {
+	return l.store.Take(ctx, rule.Name+"|"+principal, rule.Limit)
+}
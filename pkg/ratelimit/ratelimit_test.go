@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"github.com/yervsil/toDo-microservice/pkg/clock"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	clk := clock.NewFake(time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore(clk)
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "writes|ip:1.2.3.4", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Take(ctx, "writes|ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	res, err = store.Take(ctx, "writes|ip:5.6.7.8", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "buckets are per key")
+
+	clk.Advance(1500 * time.Millisecond)
+	res, err = store.Take(ctx, "writes|ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "a token is back after a second")
+	assert.Equal(t, 0, res.Remaining)
+
+	clk.Advance(time.Hour)
+	res, err = store.Take(ctx, "writes|ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining, "the bucket holds no more than burst")
+}
+
+func TestMemoryStore_sweep(t *testing.T) {
+	clk := clock.NewFake(time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore(clk)
+	slow := Limit{Requests: 1, Per: time.Hour, Burst: 1}
+	fast := Limit{Requests: 60, Per: time.Minute, Burst: 1}
+
+	_, _ = store.Take(context.Background(), "slow", slow)
+	_, _ = store.Take(context.Background(), "fast", fast)
+	require.Equal(t, 2, store.Len())
+
+	clk.Advance(sweepInterval)
+	_, _ = store.Take(context.Background(), "other", fast)
+	assert.Equal(t, 2, store.Len(), "the refilled fast bucket is dropped, the slow one kept")
+}
+
+func TestLimiter(t *testing.T) {
+	limiter, err := New(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{
+		{Name: "writes", Methods: []string{"POST", "PUT"}, Paths: []string{"/api/"}, Requests: 1, Per: time.Minute},
+		{Name: "reads", Paths: []string{"/api/"}, Requests: 100, Per: time.Minute, Burst: 10},
+	}}, NewMemoryStore(clock.New()))
+	require.NoError(t, err)
+
+	rule, ok := limiter.Match("POST", "/api/todo-list/tasks")
+	require.True(t, ok)
+	assert.Equal(t, "writes", rule.Name)
+	assert.Equal(t, 1, rule.Limit.Burst, "burst defaults to requests")
+
+	rule, ok = limiter.Match("GET", "/api/todo-list/tasks")
+	require.True(t, ok)
+	assert.Equal(t, "reads", rule.Name)
+
+	_, ok = limiter.Match("GET", "/healthz")
+	assert.False(t, ok)
+
+	writes, _ := limiter.Match("POST", "/api/todo-list/tasks")
+	res, err := limiter.Allow(context.Background(), writes, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = limiter.Allow(context.Background(), writes, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	reads, _ := limiter.Match("GET", "/api/todo-list/tasks")
+	res, err = limiter.Allow(context.Background(), reads, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "groups have separate buckets")
+}
+
+func TestNew_invalid(t *testing.T) {
+	_, err := New(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{
+		{Name: "writes", Requests: 1, Per: time.Minute},
+		{Name: "writes", Requests: 1, Per: time.Minute},
+		{Requests: 0, Per: time.Minute},
+	}}, NewMemoryStore(clock.New()))
+
+	assert.EqualError(t, err, `rateLimit.groups[1]: duplicate name "writes"
+rateLimit.groups[2]: name is required
+rateLimit.groups[2]: requests and per must be positive`)
+}
+
+func TestLimiter_Update(t *testing.T) {
+	limiter, err := New(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{
+		{Name: "writes", Methods: []string{"POST"}, Requests: 1, Per: time.Minute},
+	}}, NewMemoryStore(clock.New()))
+	require.NoError(t, err)
+
+	writes, _ := limiter.Match("POST", "/api/todo-list/tasks")
+	res, err := limiter.Allow(context.Background(), writes, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	require.NoError(t, limiter.Update(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{
+		{Name: "writes", Methods: []string{"POST"}, Requests: 10, Per: time.Minute},
+		{Name: "reads", Methods: []string{"GET"}, Requests: 10, Per: time.Minute},
+	}}))
+
+	writes, ok := limiter.Match("POST", "/api/todo-list/tasks")
+	require.True(t, ok)
+	assert.Equal(t, 10, writes.Limit.Requests)
+	_, ok = limiter.Match("GET", "/api/todo-list/tasks")
+	assert.True(t, ok)
+
+	res, err = limiter.Allow(context.Background(), writes, "ip:1.2.3.4")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "the bucket keeps its tokens")
+
+	err = limiter.Update(config.RateLimitConfig{Groups: []config.RateLimitGroupConfig{{Name: "reads"}}})
+	assert.Error(t, err)
+	_, ok = limiter.Match("POST", "/api/todo-list/tasks")
+	assert.True(t, ok, "an invalid update keeps the rules")
+
+	require.NoError(t, limiter.Update(config.RateLimitConfig{}))
+	_, ok = limiter.Match("POST", "/api/todo-list/tasks")
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/yervsil/toDo-microservice/pkg/tracing"
+
+type commandKey struct {
+	connection string
+	request    int64
+}
+
+type mongoTracer struct {
+	mu    sync.Mutex
+	spans map[commandKey]trace.Span
+}
+
+// MongoMonitor начинает спан для каждой команды, отправленной в MongoDB,
+// дочерний к спану в контексте операции. Документы команд не записываются: в
+// них названия задач.
+func MongoMonitor() *event.CommandMonitor {
+	t := &mongoTracer{spans: make(map[commandKey]trace.Span)}
+
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *mongoTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBName(e.DatabaseName),
+		semconv.DBOperation(e.CommandName),
+	}
+	// Первый элемент большинства команд ({find: "task", ...}) называет коллекцию.
+	if first, err := e.Command.IndexErr(0); err == nil && first.Value().Type == bsontype.String {
+		attrs = append(attrs, semconv.DBMongoDBCollection(first.Value().StringValue()))
+	}
+
+	_, span := otel.Tracer(instrumentation).Start(ctx, "mongodb."+e.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	t.mu.Lock()
+	t.spans[commandKey{e.ConnectionID, e.RequestID}] = span
+	t.mu.Unlock()
+}
+
+func (t *mongoTracer) succeeded(_ context.Context, e *event.CommandSucceededEvent) {
+	if span := t.finish(e.CommandFinishedEvent); span != nil {
+		span.End()
+	}
+}
+
+func (t *mongoTracer) failed(_ context.Context, e *event.CommandFailedEvent) {
+	if span := t.finish(e.CommandFinishedEvent); span != nil {
+		span.RecordError(errors.New(e.Failure))
+		span.SetStatus(codes.Error, e.Failure)
+		span.End()
+	}
+}
+
+func (t *mongoTracer) finish(e event.CommandFinishedEvent) trace.Span {
+	key := commandKey{e.ConnectionID, e.RequestID}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := t.spans[key]
+	delete(t.spans, key)
+	return span
+}
@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yervsil/toDo-microservice/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// record устанавливает провайдер трассировки, хранящий завершенные спаны в памяти.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	_, err := Init(context.Background(), config.TracingConfig{Exporter: ExporterNone})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestInit(t *testing.T) {
+	shutdown, err := Init(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Init(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+	assert.EqualError(t, err, `unknown tracing.exporter "zipkin"`)
+}
+
+func TestInjectExtract(t *testing.T) {
+	record(t)
+
+	assert.Nil(t, Inject(context.Background()), "no trace, nothing to store")
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	_, child := otel.Tracer("test").Start(Extract(context.Background(), carrier), "delivery")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+
+	header := http.Header{}
+	InjectHTTP(ctx, header)
+	assert.Equal(t, carrier["traceparent"], header.Get("traceparent"))
+}
+
+func TestGinMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/tasks", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/tasks", "/healthz"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "/tasks", spans[0].Name())
+}
+
+func TestMongoMonitor(t *testing.T) {
+	recorder := record(t)
+	monitor := MongoMonitor()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "TaskService.GetTasks")
+
+	command, err := bson.Marshal(bson.D{{Key: "find", Value: "task"}})
+	require.NoError(t, err)
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: command, DatabaseName: "todo", CommandName: "find", RequestID: 1, ConnectionID: "c1",
+	})
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: command, DatabaseName: "todo", CommandName: "insert", RequestID: 2, ConnectionID: "c1",
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "c1"},
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 2, ConnectionID: "c1"},
+		Failure:              "duplicate key",
+	})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	find, insert := spans[0], spans[1]
+	assert.Equal(t, "mongodb.find", find.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), find.Parent().SpanID())
+	assert.Contains(t, find.Attributes(), semconv.DBMongoDBCollection("task"))
+	assert.Equal(t, codes.Unset, find.Status().Code)
+
+	assert.Equal(t, "mongodb.insert", insert.Name())
+	assert.Equal(t, codes.Error, insert.Status().Code)
+	assert.Equal(t, "duplicate key", insert.Status().Description)
+}